@@ -8,25 +8,44 @@ import (
 	"time"
 
 	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/blobstore"
 	"github.com/LeonardJouve/pass-secure/status"
 	"github.com/LeonardJouve/pass-secure/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/csrf"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/gofiber/storage/redis/v3"
 )
 
+const BYTES_PER_MB = 1024 * 1024
+
 func HealthCheck(c *fiber.Ctx) error {
 	return status.Ok(c, nil)
 }
 
+// Streamed bodies are not held to the body limit of the app, routes reading
+// their whole body in memory are refused larger and chunked ones.
+func LimitBody(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contentLength := c.Request().Header.ContentLength()
+		if contentLength == -1 || contentLength > limit {
+			return status.BadRequest(c, errors.New("request body is too large"))
+		}
+
+		return c.Next()
+	}
+}
+
 func Start(port uint16) (func() error, error) {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		StreamRequestBody: true,
+	})
 
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     os.Getenv("ALLOWED_ORIGINS"),
-		AllowHeaders:     "Origin, Content-Type, Accept, X-CSRF-Token, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, X-CSRF-Token, Authorization, X-Device-Name, X-Attachment-Name, X-Attachment-Key",
 		AllowMethods:     "GET, POST, PUT, PATCH, DELETE",
 		AllowCredentials: false, // used for dev purpose only TODO true,
 	}))
@@ -43,6 +62,14 @@ func Start(port uint16) (func() error, error) {
 		return nil, err
 	}
 
+	storage := redis.New(redis.Config{
+		Host:     os.Getenv("REDIS_HOST"),
+		Port:     int(redisPort),
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+
+	auth.InitSessionStorage(storage)
+
 	app.Use(csrf.New(csrf.Config{
 		ContextKey:     auth.CSRF_TOKEN,
 		CookieName:     auth.CSRF_TOKEN,
@@ -54,24 +81,59 @@ func Start(port uint16) (func() error, error) {
 		KeyLookup:      "header:X-CSRF-Token",
 		Expiration:     time.Duration(csrfTokenExpiration) * time.Minute,
 		KeyGenerator:   utils.UUIDv4,
-		Storage: redis.New(redis.Config{
-			Host:     os.Getenv("REDIS_HOST"),
-			Port:     int(redisPort),
-			Password: os.Getenv("REDIS_PASSWORD"),
-		}),
+		Storage:        storage,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			return status.Unauthorized(c, errors.New("invalid csrf token"))
 		},
 	}))
 
+	importMaxSizeString := os.Getenv("IMPORT_MAX_SIZE_IN_MB")
+	importMaxSize, err := strconv.ParseInt(importMaxSizeString, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attachments are streamed to the blob store and imports hold whole
+	// vaults, registered before the body limit applying to every other route
+	app.Post("/entries/:entry_id/attachments", Protect, CreateEntryAttachment)
+	app.Post("/import", Protect, LimitBody(int(importMaxSize)*BYTES_PER_MB), Import)
+
+	app.Use(LimitBody(fiber.DefaultBodyLimit))
+
+	// The breach range is public, requests are limited per address
+	breachRangeLimitString := os.Getenv("BREACH_RANGE_LIMIT_PER_MINUTE")
+	breachRangeLimit, err := strconv.ParseInt(breachRangeLimitString, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
 	app.Get("/healthcheck", HealthCheck)
 	app.Get("/csrf", GetCSRF)
 
+	app.Post("/prelogin", Prelogin)
 	app.Post("/login", Login)
+	app.Post("/login/mfa", LoginMFA)
+	app.Post("/login/webauthn/options", GetWebAuthnLoginOptions)
+	app.Post("/login/webauthn", LoginWebAuthn)
 	app.Post("/register", Register)
+	app.Post("/refresh", Refresh)
+	app.Get("/breach/range/:prefix", limiter.New(limiter.Config{
+		Max:        int(breachRangeLimit),
+		Expiration: time.Minute,
+		Storage:    storage,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"message": "too many requests",
+			})
+		},
+	}), GetBreachRange)
 
 	apiGroup := app.Group("", Protect)
 
+	apiGroup.Post("/logout", Logout)
+	apiGroup.Get("/export", GetExport)
+	apiGroup.Post("/generator", Generate)
+
 	websocketTimeoutString := os.Getenv("WEBSOCKET_TIMEOUT_IN_SECOND")
 	websocketTimeout, err := strconv.ParseInt(websocketTimeoutString, 10, 64)
 	if err != nil {
@@ -82,11 +144,57 @@ func Start(port uint16) (func() error, error) {
 	go hub.Process()
 	apiGroup.Get("/ws", hub.HandleUpgrade(), hub.HandleSocket())
 
+	trashRetentionString := os.Getenv("TRASH_RETENTION_IN_DAY")
+	trashRetention, err := strconv.ParseInt(trashRetentionString, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	entryRevisionRetentionString := os.Getenv("ENTRY_REVISION_RETENTION")
+	revisionRetention, err := strconv.ParseInt(entryRevisionRetentionString, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	if revisionRetention < 0 {
+		return nil, errors.New("ENTRY_REVISION_RETENTION must not be negative")
+	}
+	entryRevisionRetention = int32(revisionRetention)
+
+	attachmentStore, err = blobstore.New(os.Getenv("BLOB_STORE_DRIVER"), os.Getenv("BLOB_STORE_LOCATION"))
+	if err != nil {
+		return nil, err
+	}
+
+	passphraseWordlist, err = loadPassphraseWordlist(os.Getenv("GENERATOR_WORDLIST_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = openBreachIndex(os.Getenv("BREACH_DATASET_PATH"), os.Getenv("BREACH_INDEX_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	stopTrashPurge := startTrashPurge(int32(trashRetention))
+
 	folderGroup := apiGroup.Group("/folders")
 	folderGroup.Get("/", GetFolders)
+	folderGroup.Get("/tree", GetFolderTree)
 	folderGroup.Get("/:folder_id", GetFolder)
 	folderGroup.Post("/", CreateFolder)
-	folderGroup.Post("/:folder_id/users", AddFolderUser)
+	folderGroup.Put("/:folder_id/key", SetFolderKey)
+	folderGroup.Post("/:folder_id/move", MoveFolder)
+	folderGroup.Post("/:folder_id/transfer", TransferFolder)
+	folderGroup.Delete("/:folder_id/transfer", CancelFolderTransfer)
+	folderGroup.Get("/:folder_id/invitations", GetFolderInvitations)
+	folderGroup.Post("/:folder_id/invitations", CreateFolderInvitation)
+	folderGroup.Post("/:folder_id/invitations/:invitation_id/confirm", ConfirmFolderInvitation)
+	folderGroup.Delete("/:folder_id/invitations/:invitation_id", RevokeFolderInvitation)
+	folderGroup.Get("/:folder_id/groups", GetFolderGroups)
+	folderGroup.Put("/:folder_id/groups/:group_id", SetFolderGroup)
+	folderGroup.Delete("/:folder_id/groups/:group_id", RemoveFolderGroup)
+	folderGroup.Put("/:folder_id/users/:user_id", UpdateFolderUser)
 	folderGroup.Delete("/:folder_id/users/:user_id", RemoveFolderUser)
 	folderGroup.Put("/:folder_id", UpdateFolder)
 	folderGroup.Delete("/:folder_id", RemoveFolder)
@@ -96,19 +204,90 @@ func Start(port uint16) (func() error, error) {
 	entriesGroup.Get("/:entry_id", GetEntry)
 	entriesGroup.Post("/", CreateEntry)
 	entriesGroup.Put("/:entry_id", UpdateEntry)
+	entriesGroup.Get("/:entry_id/totp", GetEntryTOTP)
+	entriesGroup.Get("/:entry_id/history", GetEntryRevisions)
+	entriesGroup.Post("/:entry_id/history/:revision_id/restore", RestoreEntryRevision)
+	entriesGroup.Get("/:entry_id/attachments", GetEntryAttachments)
+	entriesGroup.Get("/:entry_id/attachments/:attachment_id", GetEntryAttachment)
+	entriesGroup.Delete("/:entry_id/attachments/:attachment_id", RemoveEntryAttachment)
 	entriesGroup.Delete("/:entry_id", RemoveEntry)
 
+	trashGroup := apiGroup.Group("/trash")
+	trashGroup.Get("/", GetTrash)
+	trashGroup.Post("/folders/:folder_id/restore", RestoreTrashedFolder)
+	trashGroup.Post("/entries/:entry_id/restore", RestoreTrashedEntry)
+	trashGroup.Delete("/folders/:folder_id", RemoveTrashedFolder)
+	trashGroup.Delete("/entries/:entry_id", RemoveTrashedEntry)
+
+	groupsGroup := apiGroup.Group("/groups")
+	groupsGroup.Get("/", GetGroups)
+	groupsGroup.Get("/invitations", GetGroupInvitations)
+	groupsGroup.Get("/:group_id", GetGroup)
+	groupsGroup.Post("/", CreateGroup)
+	groupsGroup.Put("/:group_id", UpdateGroup)
+	groupsGroup.Put("/:group_id/parent", SetGroupParent)
+	groupsGroup.Put("/:group_id/users/:user_id", AddGroupUser)
+	groupsGroup.Delete("/:group_id/users/:user_id", RemoveGroupUser)
+	groupsGroup.Post("/:group_id/accept", AcceptGroupInvitation)
+	groupsGroup.Post("/:group_id/decline", DeclineGroupInvitation)
+	groupsGroup.Delete("/:group_id", RemoveGroup)
+
+	organizationsGroup := apiGroup.Group("/organizations")
+	organizationsGroup.Get("/", GetOrganizations)
+	organizationsGroup.Get("/invitations", GetOrganizationInvitations)
+	organizationsGroup.Get("/:organization_id", GetOrganization)
+	organizationsGroup.Post("/", CreateOrganization)
+	organizationsGroup.Put("/:organization_id", UpdateOrganization)
+	organizationsGroup.Delete("/:organization_id", RemoveOrganization)
+	organizationsGroup.Get("/:organization_id/users", GetOrganizationUsers)
+	organizationsGroup.Post("/:organization_id/users", AddOrganizationUser)
+	organizationsGroup.Put("/:organization_id/users/:user_id", UpdateOrganizationUser)
+	organizationsGroup.Delete("/:organization_id/users/:user_id", RemoveOrganizationUser)
+	organizationsGroup.Post("/:organization_id/accept", AcceptOrganizationInvitation)
+	organizationsGroup.Post("/:organization_id/decline", DeclineOrganizationInvitation)
+	organizationsGroup.Get("/:organization_id/policies", GetOrganizationPolicies)
+	organizationsGroup.Put("/:organization_id/policies", UpdateOrganizationPolicies)
+	organizationsGroup.Get("/:organization_id/folders", GetOrganizationFolders)
+
+	invitationsGroup := apiGroup.Group("/invitations")
+	invitationsGroup.Get("/", GetInvitations)
+	invitationsGroup.Post("/:token/accept", AcceptInvitation)
+	invitationsGroup.Post("/:token/decline", DeclineInvitation)
+
+	transfersGroup := apiGroup.Group("/transfers")
+	transfersGroup.Get("/", GetFolderTransfers)
+	transfersGroup.Post("/:transfer_id/accept", AcceptFolderTransfer)
+	transfersGroup.Post("/:transfer_id/decline", DeclineFolderTransfer)
+
+	sessionsGroup := apiGroup.Group("/sessions")
+	sessionsGroup.Get("/", GetSessions)
+	sessionsGroup.Delete("/", RemoveOtherSessions)
+	sessionsGroup.Delete("/:session_id", RemoveSession)
+
 	usersGroup := apiGroup.Group("/users")
 	usersGroup.Get("/", GetUsers)
 	usersGroup.Get("/me", GetMe)
 	usersGroup.Delete("/me", RemoveMe)
 	usersGroup.Put("/me", UpdateMe)
+	usersGroup.Put("/me/keys", SetMyKeyPair)
+	usersGroup.Get("/me/policies", GetMyPolicies)
 	usersGroup.Get("/:user_id", GetUser)
+	usersGroup.Post("/me/2fa/totp", EnableTOTP)
+	usersGroup.Post("/me/2fa/totp/verify", VerifyTOTP)
+	usersGroup.Delete("/me/2fa/totp", DisableTOTP)
+	usersGroup.Post("/me/2fa/recovery-codes", RegenerateRecoveryCodes)
+	usersGroup.Get("/me/webauthn", GetWebAuthnCredentials)
+	usersGroup.Post("/me/webauthn/options", GetWebAuthnRegistrationOptions)
+	usersGroup.Post("/me/webauthn", CreateWebAuthnCredential)
+	usersGroup.Delete("/me/webauthn/:credential_id", RemoveWebAuthnCredential)
 
 	app.Listen(fmt.Sprintf(":%d", port))
 
 	return func() error {
+		stopTrashPurge()
+		closeBreachIndex()
 		hub.Close()
+		storage.Close()
 
 		return app.Shutdown()
 	}, nil
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/blobstore"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ATTACHMENT_BLOB_KEY_SIZE       = 32
+	ATTACHMENT_BLOB_DELETION_BATCH = 100
+	ATTACHMENT_CONTENT_TYPE        = "application/octet-stream"
+)
+
+var attachmentStore blobstore.Store
+
+func GetEntryAttachments(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid entry_id"))
+	}
+
+	entry, ok := getUserEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
+
+	if _, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_VIEWER); !ok {
+		return nil
+	}
+
+	attachments, err := qtx.GetEntryAttachments(ctx, entry.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeAttachments(c, &attachments))
+}
+
+// The size is reserved in the quota before the body is streamed to the blob
+// store, so that no transaction is held during the upload and concurrent
+// uploads of a user can not exceed the quota together.
+func CreateEntryAttachment(c *fiber.Ctx) error {
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid entry_id"))
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	entry, ok := getUserEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
+
+	if _, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_EDITOR); !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetCreateAttachmentInput(c)
+	if !ok {
+		return nil
+	}
+
+	maxSize, ok := getAttachmentSizeSetting(c, "ATTACHMENT_MAX_SIZE_IN_MB")
+	if !ok {
+		return nil
+	}
+
+	if input.Size > maxSize {
+		return status.BadRequest(c, errors.New("attachment is too large"))
+	}
+
+	reservationId, ok := reserveAttachmentSize(c, user.ID, input.Size)
+	if !ok {
+		return nil
+	}
+	defer releaseAttachmentReservation(reservationId)
+
+	blobKey, err := createAttachmentBlobKey()
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	// One more byte than announced is read to catch bodies longer than their content length
+	size, err := attachmentStore.Put(c.Context(), blobKey, io.LimitReader(body, input.Size+1))
+	if err != nil {
+		attachmentStore.Delete(c.Context(), blobKey)
+		return status.InternalServerError(c, nil)
+	}
+
+	if size != input.Size {
+		attachmentStore.Delete(c.Context(), blobKey)
+		return status.BadRequest(c, errors.New("attachment size does not match content length"))
+	}
+
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		attachmentStore.Delete(c.Context(), blobKey)
+		return nil
+	}
+	defer commit()
+
+	attachment, err := qtx.CreateAttachment(ctx, queries.CreateAttachmentParams{
+		EntryID: entry.ID,
+		UserID:  &user.ID,
+		Name:    input.Name,
+		Key:     input.Key,
+		Size:    size,
+		BlobKey: blobKey,
+	})
+	if err != nil {
+		attachmentStore.Delete(c.Context(), blobKey)
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizeAttachment(c, &attachment))
+}
+
+// Attachments may hold secrets such as recovery codes or keyfiles, members
+// whose access hides passwords can not download them.
+func GetEntryAttachment(c *fiber.Ctx) error {
+	entry, attachment, ok := getEntryAttachment(c, auth.FOLDER_ROLE_VIEWER)
+	if !ok {
+		return nil
+	}
+
+	access, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_VIEWER)
+	if !ok {
+		return nil
+	}
+
+	if access.HidePasswords {
+		return status.Unauthorized(c, errors.New("attachments are hidden"))
+	}
+
+	blob, err := attachmentStore.Get(c.Context(), attachment.BlobKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return status.NotFound(c, nil)
+		}
+
+		return status.InternalServerError(c, nil)
+	}
+
+	c.Set(fiber.HeaderContentType, ATTACHMENT_CONTENT_TYPE)
+
+	return c.Status(fiber.StatusOK).SendStream(blob, int(attachment.Size))
+}
+
+// The blob is removed in the background along with the ones of attachments
+// deleted through their entry.
+func RemoveEntryAttachment(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	_, attachment, ok := getEntryAttachment(c, auth.FOLDER_ROLE_EDITOR)
+	if !ok {
+		return nil
+	}
+
+	err := qtx.DeleteAttachment(ctx, attachment.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func purgeDeletedAttachmentBlobs() {
+	conn, release, ctx, err := database.Acquire()
+	if err != nil {
+		return
+	}
+	defer release()
+
+	q := queries.New(conn)
+	for {
+		blobKeys, err := q.GetDeletedAttachmentBlobs(ctx, ATTACHMENT_BLOB_DELETION_BATCH)
+		if err != nil {
+			return
+		}
+
+		for _, blobKey := range blobKeys {
+			if err := attachmentStore.Delete(ctx, blobKey); err != nil {
+				return
+			}
+
+			if err := q.DeleteDeletedAttachmentBlob(ctx, blobKey); err != nil {
+				return
+			}
+		}
+
+		if len(blobKeys) < ATTACHMENT_BLOB_DELETION_BATCH {
+			return
+		}
+	}
+}
+
+// Uploads of a user are serialized while their size is checked against the
+// quota, which counts the reservations of the uploads in progress.
+func reserveAttachmentSize(c *fiber.Ctx, userId int64, size int64) (int64, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return 0, false
+	}
+	defer commit()
+
+	quota, ok := getAttachmentSizeSetting(c, "ATTACHMENT_QUOTA_IN_MB")
+	if !ok {
+		return 0, false
+	}
+
+	if err := qtx.LockUserAttachments(ctx, userId); err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	if err := qtx.DeleteExpiredAttachmentReservations(ctx, userId); err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	usedSize, err := qtx.GetUserAttachmentsSize(ctx, userId)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	if usedSize+size > quota {
+		status.BadRequest(c, errors.New("attachment quota exceeded"))
+		return 0, false
+	}
+
+	reservationId, err := qtx.CreateAttachmentReservation(ctx, queries.CreateAttachmentReservationParams{
+		UserID: userId,
+		Size:   size,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	return reservationId, true
+}
+
+// Released outside of the request transaction, which is rolled back when the
+// upload fails.
+func releaseAttachmentReservation(reservationId int64) {
+	conn, release, ctx, err := database.Acquire()
+	if err != nil {
+		return
+	}
+	defer release()
+
+	queries.New(conn).DeleteAttachmentReservation(ctx, reservationId)
+}
+
+func createAttachmentBlobKey() (string, error) {
+	blobKeyBytes := make([]byte, ATTACHMENT_BLOB_KEY_SIZE)
+	if _, err := rand.Read(blobKeyBytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(blobKeyBytes), nil
+}
+
+func getAttachmentSizeSetting(c *fiber.Ctx, key string) (int64, bool) {
+	sizeString := os.Getenv(key)
+	size, err := strconv.ParseInt(sizeString, 10, 64)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	return size * BYTES_PER_MB, true
+}
+
+func getEntryAttachment(c *fiber.Ctx, role string) (queries.Entry, queries.Attachment, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.Entry{}, queries.Attachment{}, false
+	}
+	defer commit()
+
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid entry_id"))
+		return queries.Entry{}, queries.Attachment{}, false
+	}
+
+	attachmentId, err := c.ParamsInt("attachment_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid attachment_id"))
+		return queries.Entry{}, queries.Attachment{}, false
+	}
+
+	entry, ok := getUserEntry(c, int64(entryId))
+	if !ok {
+		return queries.Entry{}, queries.Attachment{}, false
+	}
+
+	if _, ok := requireFolderRole(c, entry.FolderID, role); !ok {
+		return queries.Entry{}, queries.Attachment{}, false
+	}
+
+	attachment, err := qtx.GetEntryAttachment(ctx, queries.GetEntryAttachmentParams{
+		EntryID:      entry.ID,
+		AttachmentID: int64(attachmentId),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.Entry{}, queries.Attachment{}, false
+	}
+
+	return entry, attachment, true
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"strconv"
 	"strings"
@@ -39,6 +40,11 @@ func Protect(c *fiber.Ctx) error {
 		return nil
 	}
 
+	revoked := auth.IsRevoked(c, accessTokenClaims)
+	if revoked {
+		return nil
+	}
+
 	userId, err := strconv.ParseInt(accessTokenClaims.Subject, 10, 64)
 	if err != nil {
 		return status.InternalServerError(c, nil)
@@ -50,6 +56,7 @@ func Protect(c *fiber.Ctx) error {
 	}
 
 	c.Locals("user", user)
+	c.Locals("session_id", accessTokenClaims.ID)
 
 	return c.Next()
 }
@@ -83,7 +90,52 @@ func Register(c *fiber.Ctx) error {
 		return status.InternalServerError(c, nil)
 	}
 
-	return status.Created(c, models.SanitizeUser(c, &user))
+	err = qtx.ClaimUserInvitations(ctx, queries.ClaimUserInvitationsParams{
+		InviteeEmail: user.Email,
+		InviteeID:    &user.ID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	err = qtx.ClaimUserOrganizationInvitations(ctx, queries.ClaimUserOrganizationInvitationsParams{
+		InviteeEmail: user.Email,
+		UserID:       &user.ID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizeMe(c, &user))
+}
+
+// Unknown emails get the default parameters so that prelogin cannot be used
+// to enumerate accounts.
+func Prelogin(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	input, ok := schemas.GetPreloginInput(c)
+	if !ok {
+		return nil
+	}
+
+	user, err := qtx.GetUserByEmail(ctx, input.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return status.InternalServerError(c, nil)
+		}
+
+		user = queries.User{
+			Kdf:           auth.DEFAULT_KDF,
+			KdfIterations: auth.DEFAULT_KDF_ITERATIONS,
+		}
+	}
+
+	return status.Ok(c, models.SanitizeKdf(c, &user))
 }
 
 func Login(c *fiber.Ctx) error {
@@ -92,16 +144,114 @@ func Login(c *fiber.Ctx) error {
 		return nil
 	}
 
-	accessToken, ok := auth.CreateToken(c, user.ID)
+	mfaMethods, ok := auth.GetMFAMethods(c, user)
+	if !ok {
+		return nil
+	}
+
+	if len(mfaMethods) != 0 {
+		mfaToken, ok := auth.CreateMFAToken(c, user.ID)
+		if !ok {
+			return nil
+		}
+
+		return status.Ok(c, fiber.Map{
+			"mfa_token":   mfaToken,
+			"mfa_methods": mfaMethods,
+		})
+	}
+
+	return startSession(c, user.ID)
+}
+
+func LoginMFA(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	input, ok := schemas.GetLoginMFAInput(c)
+	if !ok {
+		return nil
+	}
+
+	_, userId, ok := auth.ValidateMFAToken(c, input.MFAToken)
+	if !ok {
+		return nil
+	}
+
+	user, err := qtx.GetUser(ctx, userId)
+	if err != nil {
+		return status.Unauthorized(c, nil)
+	}
+
+	if ok := verifySecondFactor(c, user, input.SecondFactorInput); !ok {
+		return nil
+	}
+
+	return startSession(c, user.ID)
+}
+
+func startSession(c *fiber.Ctx, userId int64) error {
+	session, ok := auth.CreateSession(c, userId)
+	if !ok {
+		return nil
+	}
+
+	accessToken, ok := auth.CreateToken(c, userId, session.ID)
+	if !ok {
+		return nil
+	}
+
+	refreshToken, ok := auth.CreateRefreshToken(c, userId, session.ID)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, fiber.Map{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+func Refresh(c *fiber.Ctx) error {
+	input, ok := schemas.GetRefreshInput(c)
+	if !ok {
+		return nil
+	}
+
+	oldRefreshToken, refreshToken, ok := auth.RotateRefreshToken(c, input.RefreshToken)
+	if !ok {
+		return nil
+	}
+
+	accessToken, ok := auth.CreateToken(c, oldRefreshToken.UserID, oldRefreshToken.Family)
 	if !ok {
 		return nil
 	}
 
 	return status.Ok(c, fiber.Map{
-		"access_token": accessToken,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
 	})
 }
 
+func Logout(c *fiber.Ctx) error {
+	sessionId, ok := getSessionId(c)
+	if !ok {
+		return nil
+	}
+
+	if ok := auth.RevokeSession(c, sessionId); !ok {
+		return nil
+	}
+
+	auth.ClearTokens(c)
+
+	return status.Ok(c, nil)
+}
+
 func getUser(c *fiber.Ctx) (queries.User, bool) {
 	user, ok := c.Locals("user").(queries.User)
 	if !ok {
@@ -112,6 +262,16 @@ func getUser(c *fiber.Ctx) (queries.User, bool) {
 	return user, true
 }
 
+func getSessionId(c *fiber.Ctx) (string, bool) {
+	sessionId, ok := c.Locals("session_id").(string)
+	if !ok {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+
+	return sessionId, true
+}
+
 func GetCSRF(c *fiber.Ctx) error {
 	csrfToken, ok := c.Locals(auth.CSRF_TOKEN).(string)
 	if !ok || len(csrfToken) == 0 {
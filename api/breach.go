@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/LeonardJouve/pass-secure/breach"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Nil while breach checks are disabled or the index is being built.
+var breachIndex atomic.Pointer[breach.Index]
+
+// Returns the suffixes of the breached SHA-1 hashes starting with the prefix
+// in the format of the Have I Been Pwned range API, so that clients check
+// entries and master passwords without sending them. It is public since
+// master passwords are checked before registering.
+func GetBreachRange(c *fiber.Ctx) error {
+	index := breachIndex.Load()
+	if index == nil {
+		return status.NotFound(c, errors.New("breach checks are disabled"))
+	}
+
+	entries, err := index.Range(c.Params("prefix"))
+	if errors.Is(err, breach.ErrInvalidPrefix) {
+		return status.BadRequest(c, err)
+	} else if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	var body strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&body, "%s:%d\r\n", entry.Suffix, entry.Count)
+	}
+
+	c.Status(fiber.StatusOK)
+
+	return c.SendString(body.String())
+}
+
+// Breach checks are disabled without an index path. A missing index is built
+// from the dataset in the background, which takes a while for the full
+// dataset, breach checks are disabled until it is opened.
+func openBreachIndex(datasetPath string, indexPath string) error {
+	if len(indexPath) == 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(indexPath); errors.Is(err, os.ErrNotExist) && len(datasetPath) != 0 {
+		go func() {
+			if err := breach.BuildIndex(datasetPath, indexPath); err != nil {
+				return
+			}
+
+			if index, err := breach.Open(indexPath); err == nil {
+				breachIndex.Store(index)
+			}
+		}()
+
+		return nil
+	}
+
+	index, err := breach.Open(indexPath)
+	if err != nil {
+		return err
+	}
+	breachIndex.Store(index)
+
+	return nil
+}
+
+func closeBreachIndex() {
+	if index := breachIndex.Swap(nil); index != nil {
+		index.Close()
+	}
+}
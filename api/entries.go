@@ -2,8 +2,11 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"time"
 
+	"github.com/LeonardJouve/pass-secure/auth"
 	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/database/models"
 	"github.com/LeonardJouve/pass-secure/database/queries"
@@ -24,38 +27,62 @@ func CreateEntry(c *fiber.Ctx) error {
 		return nil
 	}
 
-	user, ok := getUser(c)
+	if _, ok := requireFolderRole(c, input.FolderID, auth.FOLDER_ROLE_EDITOR); !ok {
+		return nil
+	}
+
+	entry, err := qtx.CreateEntry(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedEntry, ok := models.SanitizeEntry(c, &entry)
 	if !ok {
 		return nil
 	}
 
-	parentFolder, ok := getUserFolder(c, input.FolderID)
+	return status.Created(c, sanitizedEntry)
+}
+
+func GetEntries(c *fiber.Ctx) error {
+	entries, ok := getUserEntries(c)
 	if !ok {
 		return nil
 	}
 
-	if parentFolder.OwnerID != user.ID {
-		return status.Unauthorized(c, nil)
+	sanitizedEntries, ok := models.SanitizeEntries(c, &entries)
+	if !ok {
+		return nil
 	}
 
-	entry, err := qtx.CreateEntry(ctx, input)
+	return status.Ok(c, sanitizedEntries)
+}
+
+func GetEntry(c *fiber.Ctx) error {
+	entryId, err := c.ParamsInt("entry_id")
 	if err != nil {
-		return status.InternalServerError(c, nil)
+		return status.BadRequest(c, errors.New("invalid entry_id"))
 	}
 
-	return status.Created(c, models.SanitizeEntry(c, &entry))
-}
+	entry, ok := getUserEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
 
-func GetEntries(c *fiber.Ctx) error {
-	entries, ok := getUserEntries(c)
+	sanitizedEntry, ok := models.SanitizeEntry(c, &entry)
 	if !ok {
 		return nil
 	}
 
-	return status.Ok(c, models.SanitizeEntries(c, &entries))
+	return status.Ok(c, sanitizedEntry)
 }
 
-func GetEntry(c *fiber.Ctx) error {
+// Unlike a server computed code, the secret is end-to-end encrypted so the
+// server can not generate the current code, it only validates the parameters
+// of the otpauth:// URI on create and update. Clients generate the code from
+// the decrypted secret and the time step returned here, so that every member
+// shows the same code along with the seconds remaining.
+func GetEntryTOTP(c *fiber.Ctx) error {
 	entryId, err := c.ParamsInt("entry_id")
 	if err != nil {
 		return status.BadRequest(c, errors.New("invalid entry_id"))
@@ -66,7 +93,28 @@ func GetEntry(c *fiber.Ctx) error {
 		return nil
 	}
 
-	return status.Ok(c, models.SanitizeEntry(c, &entry))
+	access, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_VIEWER)
+	if !ok {
+		return nil
+	}
+
+	if access.HidePasswords {
+		return status.Unauthorized(c, errors.New("totp secret is hidden"))
+	}
+
+	totp := models.SanitizeEntryTOTP(entry.Totp, false)
+	if totp == nil {
+		return status.NotFound(c, nil)
+	}
+
+	now := time.Now().Unix()
+	period := int64(totp.Period)
+
+	return status.Ok(c, fiber.Map{
+		"totp":             totp,
+		"step":             now / period,
+		"secondsRemaining": period - now%period,
+	})
 }
 
 func UpdateEntry(c *fiber.Ctx) error {
@@ -86,26 +134,46 @@ func UpdateEntry(c *fiber.Ctx) error {
 		return nil
 	}
 
-	user, ok := getUser(c)
+	access, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_EDITOR)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetUpdateEntryInput(c, entry.Type)
 	if !ok {
 		return nil
 	}
+	input.ID = entry.ID
 
-	parentFolder, err := qtx.GetFolder(ctx, entry.FolderID)
+	// Members with hidden passwords can edit the other fields but never the
+	// password, the TOTP nor the custom fields, which may hold hidden values
+	if input.Password == nil || access.HidePasswords {
+		input.Password = entry.Password
+	}
+
+	if access.HidePasswords {
+		input.Totp = entry.Totp
+		input.CustomFields = entry.CustomFields
+	}
+
+	input.Fields, err = keepEntrySecretFields(entry.Fields, input.Fields, access.HidePasswords)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return status.BadRequest(c, errors.New("invalid folder_id"))
-		} else {
-			return status.InternalServerError(c, nil)
-		}
+		return status.InternalServerError(c, nil)
 	}
 
-	if parentFolder.OwnerID != user.ID {
-		return status.Unauthorized(c, nil)
+	// Passwords hidden from the member must stay hidden in the target folder
+	if input.FolderID != entry.FolderID {
+		targetAccess, ok := requireFolderRole(c, input.FolderID, auth.FOLDER_ROLE_EDITOR)
+		if !ok {
+			return nil
+		}
+
+		if access.HidePasswords && !targetAccess.HidePasswords {
+			return status.Unauthorized(c, errors.New("can not move an entry with hidden passwords to a folder showing them"))
+		}
 	}
 
-	input, ok := schemas.GetUpdateEntryInput(c)
-	if !ok {
+	if ok := createEntryRevision(c, qtx, ctx, entry, input); !ok {
 		return nil
 	}
 
@@ -114,7 +182,12 @@ func UpdateEntry(c *fiber.Ctx) error {
 		return status.InternalServerError(c, nil)
 	}
 
-	return status.Ok(c, models.SanitizeEntry(c, &newEntry))
+	sanitizedEntry, ok := models.SanitizeEntry(c, &newEntry)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedEntry)
 }
 
 func RemoveEntry(c *fiber.Ctx) error {
@@ -134,30 +207,54 @@ func RemoveEntry(c *fiber.Ctx) error {
 		return nil
 	}
 
-	user, ok := getUser(c)
-	if !ok {
+	if _, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_EDITOR); !ok {
 		return nil
 	}
 
-	parentFolder, err := qtx.GetFolder(ctx, entry.FolderID)
+	err = qtx.TrashEntry(ctx, entry.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return status.BadRequest(c, errors.New("invalid folder_id"))
-		} else {
-			return status.InternalServerError(c, nil)
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+// Secret fields missing from the new fields keep their current value, all of
+// them do when the access hides passwords.
+func keepEntrySecretFields(currentFields []byte, newFields []byte, keepAll bool) ([]byte, error) {
+	current := make(map[string]string)
+	if currentFields != nil {
+		if err := json.Unmarshal(currentFields, &current); err != nil {
+			return nil, err
 		}
 	}
 
-	if parentFolder.OwnerID != user.ID {
-		return status.Unauthorized(c, nil)
+	fields := make(map[string]string)
+	if newFields != nil {
+		if err := json.Unmarshal(newFields, &fields); err != nil {
+			return nil, err
+		}
 	}
 
-	err = qtx.DeleteEntry(ctx, entry.ID)
-	if err != nil {
-		return status.InternalServerError(c, nil)
+	for _, secretField := range models.EntrySecretFields {
+		value, ok := current[secretField]
+		if !ok {
+			if keepAll {
+				delete(fields, secretField)
+			}
+			continue
+		}
+
+		if _, ok := fields[secretField]; !ok || keepAll {
+			fields[secretField] = value
+		}
 	}
 
-	return status.Ok(c, nil)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(fields)
 }
 
 func getUserEntries(c *fiber.Ctx) ([]queries.Entry, bool) {
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const EXPORT_FILE_NAME = "pass-secure-export.json"
+
+// Dumps the folders and entries of the user as stored, encrypted with their
+// account keys. Clients decrypt it and write the JSON, passphrase encrypted
+// JSON or KDBX file with the exporter package, so that the vault never reaches
+// the server in plaintext. Folders of organizations forbidding shared exports
+// are left out.
+func GetExport(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if ok := checkExportPolicy(c, qtx, ctx, user.ID); !ok {
+		return nil
+	}
+
+	folders, err := qtx.GetUserExportFolders(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	entries, err := qtx.GetUserExportEntries(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedExport, ok := models.SanitizeExport(c, &user, &folders, &entries)
+	if !ok {
+		return nil
+	}
+
+	c.Attachment(EXPORT_FILE_NAME)
+
+	return status.Ok(c, sanitizedExport)
+}
+
+// Members of an organization forbidding personal exports can not export
+// anything, including their own folders.
+func checkExportPolicy(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, userId int64) bool {
+	policies, err := qtx.GetUserPolicies(ctx, userId)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if policies.DisablePersonalExport {
+		status.Unauthorized(c, errors.New("exports are disabled by your organization"))
+		return false
+	}
+
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/LeonardJouve/pass-secure/auth"
 	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/database/models"
 	"github.com/LeonardJouve/pass-secure/database/queries"
@@ -29,13 +30,27 @@ func CreateFolder(c *fiber.Ctx) error {
 		return nil
 	}
 
+	if _, ok := requireFolderRole(c, *input.ParentID, auth.FOLDER_ROLE_MANAGER); !ok {
+		return nil
+	}
+
 	parentFolder, ok := getUserFolder(c, *input.ParentID)
 	if !ok {
 		return nil
 	}
 
-	if parentFolder.OwnerID != user.ID {
-		return status.Unauthorized(c, nil)
+	// Subfolders belong to the organization of their parent, only organization
+	// admins can create new organization folders elsewhere
+	if parentFolder.OrganizationID != nil {
+		if input.OrganizationID != nil && *input.OrganizationID != *parentFolder.OrganizationID {
+			return status.BadRequest(c, errors.New("folder must belong to the organization of its parent"))
+		}
+
+		input.OrganizationID = parentFolder.OrganizationID
+	} else if input.OrganizationID != nil {
+		if _, ok := requireOrganizationRole(c, *input.OrganizationID, auth.ORGANIZATION_ROLE_ADMIN); !ok {
+			return nil
+		}
 	}
 
 	folder, err := qtx.CreateFolder(ctx, input)
@@ -68,19 +83,15 @@ func UpdateFolder(c *fiber.Ctx) error {
 		return nil
 	}
 
-	user, ok := getUser(c)
-	if !ok {
+	if _, ok := requireFolderRole(c, folder.ID, auth.FOLDER_ROLE_MANAGER); !ok {
 		return nil
 	}
 
-	if folder.OwnerID != user.ID {
-		return status.Unauthorized(c, nil)
-	}
-
 	input, ok := schemas.GetUpdateFolderInput(c)
 	if !ok {
 		return nil
 	}
+	input.ID = folder.ID
 
 	newFolder, err := qtx.UpdateFolder(ctx, input)
 	if err != nil {
@@ -109,6 +120,120 @@ func GetFolders(c *fiber.Ctx) error {
 	return status.Ok(c, sanitizedFolders)
 }
 
+// Every check runs in the move transaction, under a lock serializing moves so
+// that two concurrent moves can not build a cycle together.
+func MoveFolder(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetMoveFolderInput(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	if err := qtx.LockFolderTree(ctx); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if _, ok := checkFolderRole(c, qtx, ctx, input.ID, auth.FOLDER_ROLE_OWNER); !ok {
+		return nil
+	}
+
+	if _, ok := checkFolderRole(c, qtx, ctx, *input.ParentID, auth.FOLDER_ROLE_MANAGER); !ok {
+		return nil
+	}
+
+	folder, err := qtx.GetFolder(ctx, input.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if folder.ParentID == nil {
+		return status.BadRequest(c, errors.New("root folder can not be moved"))
+	}
+
+	parentFolder, err := qtx.GetFolder(ctx, *input.ParentID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if parentFolder.ParentID == nil && parentFolder.OwnerID != user.ID {
+		return status.BadRequest(c, errors.New("folder can not be moved into another user's root folder"))
+	}
+
+	// Folders stay within their organization, or outside of any
+	if (folder.OrganizationID == nil) != (parentFolder.OrganizationID == nil) || (folder.OrganizationID != nil && *folder.OrganizationID != *parentFolder.OrganizationID) {
+		return status.BadRequest(c, errors.New("folder can not be moved out of or into an organization"))
+	}
+
+	isDescendant, err := qtx.IsFolderDescendant(ctx, queries.IsFolderDescendantParams{
+		FolderID:   parentFolder.ID,
+		AncestorID: folder.ID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if isDescendant {
+		return status.BadRequest(c, errors.New("folder can not be moved into itself or one of its subfolders"))
+	}
+
+	newFolder, err := qtx.MoveFolder(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedFolder, ok := models.SanitizeFolder(c, &newFolder)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedFolder)
+}
+
+func GetFolderTree(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	folders, ok := getUserFolders(c)
+	if !ok {
+		return nil
+	}
+
+	entryCounts, err := qtx.GetUserFolderEntryCounts(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	folderTree, ok := models.SanitizeFolderTree(c, &folders, &entryCounts)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, folderTree)
+}
+
 func GetFolder(c *fiber.Ctx) error {
 	folderId, err := c.ParamsInt("folder_id")
 	if err != nil {
@@ -145,20 +270,19 @@ func RemoveFolder(c *fiber.Ctx) error {
 		return nil
 	}
 
-	user, ok := getUser(c)
-	if !ok {
+	if _, ok := requireFolderRole(c, folder.ID, auth.FOLDER_ROLE_OWNER); !ok {
 		return nil
 	}
 
-	if folder.OwnerID != user.ID {
+	if folder.ParentID == nil {
 		return status.Unauthorized(c, nil)
 	}
 
-	if folder.ParentID == nil {
-		return status.Unauthorized(c, nil)
+	if err := qtx.LockFolderTree(ctx); err != nil {
+		return status.InternalServerError(c, nil)
 	}
 
-	err = qtx.DeleteFolder(ctx, folder.ID)
+	err = qtx.TrashFolder(ctx, folder.ID)
 	if err != nil {
 		return status.InternalServerError(c, nil)
 	}
@@ -193,26 +317,53 @@ func RemoveFolderUser(c *fiber.Ctx) error {
 		return nil
 	}
 
-	if folder.OwnerID != user.ID {
-		return status.Unauthorized(c, errors.New("only folder owner can remove users"))
+	if folder.OwnerID == int64(userId) {
+		return status.Unauthorized(c, errors.New("folder owner can not be removed"))
 	}
 
-	if user.ID == int64(userId) {
-		return status.Unauthorized(c, errors.New("folder owner can not be removed"))
+	// Any member can leave the folder, removing others requires the manager
+	// role and a rank above theirs
+	if user.ID != int64(userId) {
+		access, ok := checkFolderRole(c, qtx, ctx, folder.ID, auth.FOLDER_ROLE_MANAGER)
+		if !ok {
+			return nil
+		}
+
+		if _, ok := checkManagedFolderUser(c, qtx, ctx, access, int64(userId)); !ok {
+			return nil
+		}
 	}
 
-	qtx.DeleteFolderUser(ctx, queries.DeleteFolderUserParams{
+	deleted, err := qtx.DeleteFolderUser(ctx, queries.DeleteFolderUserParams{
 		UserID:   int64(userId),
 		FolderID: folder.ID,
 	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	// Access inherited from a parent folder or a group has no row to delete
+	if deleted == 0 {
+		_, err := qtx.GetFolderAccess(ctx, queries.GetFolderAccessParams{
+			UserID:   int64(userId),
+			FolderID: folder.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.NotFound(c, nil)
+		} else if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+
+		return status.BadRequest(c, errors.New("access is inherited from a parent folder or a group"))
+	}
 
 	return status.Ok(c, nil)
 }
 
-func AddFolderUser(c *fiber.Ctx) error {
+func UpdateFolderUser(c *fiber.Ctx) error {
 	qtx, ctx, commit, ok := database.BeginTransaction(c)
 	if !ok {
-		return status.InternalServerError(c, nil)
+		return nil
 	}
 	defer commit()
 
@@ -221,6 +372,11 @@ func AddFolderUser(c *fiber.Ctx) error {
 		return status.BadRequest(c, errors.New("invalid folder_id"))
 	}
 
+	userId, err := c.ParamsInt("user_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid user_id"))
+	}
+
 	folder, ok := getUserFolder(c, int64(folderId))
 	if !ok {
 		return nil
@@ -231,29 +387,113 @@ func AddFolderUser(c *fiber.Ctx) error {
 		return nil
 	}
 
-	if folder.OwnerID != user.ID {
-		return status.Unauthorized(c, errors.New("only folder owner can add users"))
+	access, ok := checkFolderRole(c, qtx, ctx, folder.ID, auth.FOLDER_ROLE_MANAGER)
+	if !ok {
+		return nil
+	}
+
+	if user.ID == int64(userId) {
+		return status.Unauthorized(c, errors.New("can not change your own access"))
+	}
+
+	if folder.OwnerID == int64(userId) {
+		return status.Unauthorized(c, errors.New("folder owner can not be changed"))
 	}
 
-	input, ok := schemas.GetAddFolderUserInput(c)
+	if _, ok := checkManagedFolderUser(c, qtx, ctx, access, int64(userId)); !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetUpdateFolderUserInput(c, int64(userId), folder.ID)
 	if !ok {
 		return nil
 	}
 
-	addUser, err := qtx.GetUserByEmail(ctx, input.Email)
+	if ok := checkGrantedFolderAccess(c, access, input.Role, input.HidePasswords); !ok {
+		return nil
+	}
+
+	// Members inheriting their access from a parent folder get an explicit
+	// grant on this folder which overrides the inherited one
+	_, err = qtx.UpdateFolderUser(ctx, input)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = qtx.OverrideFolderUser(ctx, queries.OverrideFolderUserParams{
+			Role:          input.Role,
+			HidePasswords: input.HidePasswords,
+			UserID:        input.UserID,
+			FolderID:      input.FolderID,
+		})
+	}
 	if err != nil {
-		return status.BadRequest(c, errors.New("invalid email"))
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.NotFound(c, nil)
+		}
+
+		return status.InternalServerError(c, nil)
 	}
 
-	err = qtx.AddFolderUser(ctx, queries.AddFolderUserParams{
-		UserID:   addUser.ID,
-		FolderID: folder.ID,
-	})
+	sanitizedFolder, ok := models.SanitizeFolder(c, &folder)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedFolder)
+}
+
+// The folder key can only be set while the folder is not shared, changing it
+// afterwards would lock the other members out.
+func SetFolderKey(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	folder, ok := getUserFolder(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	access, ok := requireFolderRole(c, folder.ID, auth.FOLDER_ROLE_OWNER)
+	if !ok {
+		return nil
+	}
+
+	userIds, err := qtx.GetFolderUsers(ctx, folder.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	folderGroups, err := qtx.GetFolderGroups(ctx, folder.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if len(userIds) > 1 || len(folderGroups) != 0 {
+		return status.BadRequest(c, errors.New("folder is already shared"))
+	}
+
+	input, ok := schemas.GetSetFolderKeyInput(c, access.UserID, folder.ID)
+	if !ok {
+		return nil
+	}
+
+	err = qtx.SetFolderUserKey(ctx, input)
 	if err != nil {
 		return status.InternalServerError(c, nil)
 	}
 
-	return status.Created(c, models.SanitizeUser(c, &addUser))
+	sanitizedFolder, ok := models.SanitizeFolder(c, &folder)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedFolder)
 }
 
 func getUserFolders(c *fiber.Ctx) ([]queries.Folder, bool) {
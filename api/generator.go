@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"os"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/generator"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+var passphraseWordlist generator.Wordlist
+
+// Generates a password or passphrase with the requested options, clamped by
+// the generator policies of the organizations of the user. The options
+// actually used are returned along with the value.
+func Generate(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetGeneratorInput(c)
+	if !ok {
+		return nil
+	}
+
+	policies, err := qtx.GetUserPolicies(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+	policy := getGeneratorPolicy(&policies)
+
+	if input.Type == schemas.GENERATOR_TYPE_PASSPHRASE {
+		if passphraseWordlist == nil {
+			return status.NotFound(c, errors.New("passphrases are disabled"))
+		}
+
+		options := policy.ClampPassphrase(input.PassphraseOptions())
+		passphrase, err := passphraseWordlist.Passphrase(options)
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+
+		return status.Ok(c, fiber.Map{
+			"value":   passphrase,
+			"options": options,
+		})
+	}
+
+	options := policy.ClampPassword(input.PasswordOptions())
+	password, err := generator.Password(options)
+	if errors.Is(err, generator.ErrInvalidOptions) {
+		return status.BadRequest(c, errors.New("at least one character class is required"))
+	} else if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, fiber.Map{
+		"value":   password,
+		"options": options,
+	})
+}
+
+// Passphrases are disabled without a wordlist, either when no path is set or
+// when the file is missing.
+func loadPassphraseWordlist(path string) (generator.Wordlist, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	wordlist, err := generator.LoadWordlist(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return wordlist, err
+}
+
+func getGeneratorPolicy(policies *queries.GetUserPoliciesRow) generator.Policy {
+	return generator.Policy{
+		MinLength:        int(policies.GeneratorMinLength),
+		RequireUppercase: policies.GeneratorRequireUppercase,
+		RequireLowercase: policies.GeneratorRequireLowercase,
+		RequireDigits:    policies.GeneratorRequireDigits,
+		RequireSymbols:   policies.GeneratorRequireSymbols,
+		MinDigits:        int(policies.GeneratorMinDigits),
+		MinSymbols:       int(policies.GeneratorMinSymbols),
+		MinWordCount:     int(policies.GeneratorMinWordCount),
+		Capitalize:       policies.GeneratorCapitalize,
+		IncludeNumber:    policies.GeneratorIncludeNumber,
+	}
+}
@@ -0,0 +1,623 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateGroup(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetCreateGroupInput(c)
+	if !ok {
+		return nil
+	}
+
+	group, err := qtx.CreateGroup(ctx, queries.CreateGroupParams{
+		Name:    input.Name,
+		OwnerID: user.ID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	err = qtx.AddGroupUser(ctx, queries.AddGroupUserParams{
+		GroupID: group.ID,
+		UserID:  user.ID,
+		Key:     input.Key,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizedGroup{
+		ID:      group.ID,
+		Name:    group.Name,
+		OwnerID: group.OwnerID,
+		UserIds: []int64{user.ID},
+		Key:     &input.Key,
+	})
+}
+
+func GetGroups(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	groups, err := qtx.GetUserGroups(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedGroups, ok := models.SanitizeGroups(c, &groups)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedGroups)
+}
+
+func GetGroup(c *fiber.Ctx) error {
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	group, ok := getUserGroup(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	sanitizedGroup, ok := models.SanitizeGroup(c, &group)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedGroup)
+}
+
+func UpdateGroup(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	group, ok := getOwnedGroup(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetUpdateGroupInput(c, group.ID)
+	if !ok {
+		return nil
+	}
+
+	newGroup, err := qtx.UpdateGroup(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedGroup, ok := models.SanitizeGroup(c, &newGroup)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedGroup)
+}
+
+func RemoveGroup(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	group, ok := getOwnedGroup(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	err = qtx.DeleteGroup(ctx, group.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+// Nesting a group makes its members members of the parent group, so the owner
+// of both groups has to agree. Checks run under a lock serializing nesting so
+// that two concurrent changes can not build a cycle together.
+func SetGroupParent(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetSetGroupParentInput(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	if err := qtx.LockGroupTree(ctx); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	group, err := qtx.GetGroup(ctx, input.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.NotFound(c, nil)
+		}
+
+		return status.InternalServerError(c, nil)
+	}
+
+	if group.OwnerID != user.ID {
+		return status.Unauthorized(c, errors.New("only group owner can nest the group"))
+	}
+
+	if input.ParentID != nil {
+		parentGroup, err := qtx.GetGroup(ctx, *input.ParentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return status.NotFound(c, nil)
+			}
+
+			return status.InternalServerError(c, nil)
+		}
+
+		if parentGroup.OwnerID != user.ID {
+			return status.Unauthorized(c, errors.New("only parent group owner can nest groups into it"))
+		}
+
+		isDescendant, err := qtx.IsGroupDescendant(ctx, queries.IsGroupDescendantParams{
+			GroupID:    parentGroup.ID,
+			AncestorID: group.ID,
+		})
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+
+		if isDescendant {
+			return status.BadRequest(c, errors.New("group can not be nested into itself or one of its subgroups"))
+		}
+	}
+
+	newGroup, err := qtx.SetGroupParent(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedGroup, ok := models.SanitizeGroup(c, &newGroup)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedGroup)
+}
+
+// Users join the group once they accept the invitation. Unknown users and
+// users without a public key get the same answer so that ids can not be
+// probed.
+func AddGroupUser(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	userId, err := c.ParamsInt("user_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid user_id"))
+	}
+
+	group, ok := getOwnedGroup(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetAddGroupUserInput(c, group.ID, int64(userId), user.ID)
+	if !ok {
+		return nil
+	}
+
+	member, err := qtx.GetUser(ctx, input.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return status.InternalServerError(c, nil)
+	}
+
+	if err != nil || member.PublicKey == nil {
+		return status.NotFound(c, nil)
+	}
+
+	err = qtx.CreateGroupInvitation(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedGroup, ok := models.SanitizeGroup(c, &group)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedGroup)
+}
+
+func GetGroupInvitations(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	invitations, err := qtx.GetUserGroupInvitations(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeGroupInvitations(c, &invitations))
+}
+
+func AcceptGroupInvitation(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	invitation, ok := takeGroupInvitation(c, user.ID)
+	if !ok {
+		return nil
+	}
+
+	err := qtx.AddGroupUser(ctx, queries.AddGroupUserParams{
+		GroupID: invitation.GroupID,
+		UserID:  user.ID,
+		Key:     invitation.Key,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	group, err := qtx.GetGroup(ctx, invitation.GroupID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedGroup, ok := models.SanitizeGroup(c, &group)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedGroup)
+}
+
+func DeclineGroupInvitation(c *fiber.Ctx) error {
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if _, ok := takeGroupInvitation(c, user.ID); !ok {
+		return nil
+	}
+
+	return status.Ok(c, nil)
+}
+
+// Removes the invitation of the user to the group of the request.
+func takeGroupInvitation(c *fiber.Ctx, userId int64) (queries.GroupInvitation, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.GroupInvitation{}, false
+	}
+	defer commit()
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid group_id"))
+		return queries.GroupInvitation{}, false
+	}
+
+	invitation, err := qtx.DeleteGroupInvitation(ctx, queries.DeleteGroupInvitationParams{
+		GroupID: int64(groupId),
+		UserID:  userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.GroupInvitation{}, false
+	}
+
+	return invitation, true
+}
+
+func RemoveGroupUser(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	userId, err := c.ParamsInt("user_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid user_id"))
+	}
+
+	group, ok := getUserGroup(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	// Any member can leave the group, removing others requires owning it
+	if user.ID != int64(userId) && group.OwnerID != user.ID {
+		return status.Unauthorized(c, errors.New("only group owner can remove members"))
+	}
+
+	if group.OwnerID == int64(userId) {
+		return status.Unauthorized(c, errors.New("group owner can not be removed"))
+	}
+
+	deleted, err := qtx.DeleteGroupUser(ctx, queries.DeleteGroupUserParams{
+		GroupID: group.ID,
+		UserID:  int64(userId),
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if deleted == 0 {
+		return status.NotFound(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func GetFolderGroups(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	folder, ok := getUserFolder(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	folderGroups, err := qtx.GetFolderGroups(ctx, folder.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeFolderGroups(c, &folderGroups))
+}
+
+// Granting a group requires being one of its members, the folder key has to be
+// encrypted with the group key.
+func SetFolderGroup(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	access, ok := checkFolderRole(c, qtx, ctx, int64(folderId), auth.FOLDER_ROLE_MANAGER)
+	if !ok {
+		return nil
+	}
+
+	if access.Key == nil {
+		return status.BadRequest(c, errors.New("folder key must be set before sharing"))
+	}
+
+	// Granting a folder hands it to every member, which is up to the group owner
+	group, ok := getOwnedGroup(c, int64(groupId))
+	if !ok {
+		return nil
+	}
+
+	if ok := checkManagedFolderGroup(c, qtx, ctx, access, group.ID); !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetSetFolderGroupInput(c, group.ID, access.FolderID)
+	if !ok {
+		return nil
+	}
+
+	if ok := checkGrantedFolderAccess(c, access, input.Role, input.HidePasswords); !ok {
+		return nil
+	}
+
+	folderGroup, err := qtx.SetFolderGroup(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeFolderGroup(c, &folderGroup))
+}
+
+func RemoveFolderGroup(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	groupId, err := c.ParamsInt("group_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid group_id"))
+	}
+
+	access, ok := checkFolderRole(c, qtx, ctx, int64(folderId), auth.FOLDER_ROLE_MANAGER)
+	if !ok {
+		return nil
+	}
+
+	if ok := checkManagedFolderGroup(c, qtx, ctx, access, int64(groupId)); !ok {
+		return nil
+	}
+
+	deleted, err := qtx.DeleteFolderGroup(ctx, queries.DeleteFolderGroupParams{
+		GroupID:  int64(groupId),
+		FolderID: int64(folderId),
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if deleted == 0 {
+		return status.NotFound(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func getUserGroup(c *fiber.Ctx, groupId int64) (queries.Group, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.Group{}, false
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.Group{}, false
+	}
+
+	group, err := qtx.GetUserGroup(ctx, queries.GetUserGroupParams{
+		UserID:  user.ID,
+		GroupID: groupId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.Group{}, false
+	}
+
+	return group, true
+}
+
+func getOwnedGroup(c *fiber.Ctx, groupId int64) (queries.Group, bool) {
+	group, ok := getUserGroup(c, groupId)
+	if !ok {
+		return queries.Group{}, false
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.Group{}, false
+	}
+
+	if group.OwnerID != user.ID {
+		status.Unauthorized(c, errors.New("only group owner can manage the group"))
+		return queries.Group{}, false
+	}
+
+	return group, true
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Creates the folders and entries of an import under the chosen parent in a
+// single transaction. Clients parse the export of the other password manager
+// with the importer package and send the items encrypted, the file never
+// reaches the server. Entries failing validation are reported by row and left
+// out without failing the others.
+func Import(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetImportInput(c)
+	if !ok {
+		return nil
+	}
+
+	role := auth.FOLDER_ROLE_EDITOR
+	if len(input.Folders) != 0 {
+		role = auth.FOLDER_ROLE_MANAGER
+	}
+
+	if _, ok := requireFolderRole(c, input.ParentID, role); !ok {
+		return nil
+	}
+
+	parentFolder, ok := getUserFolder(c, input.ParentID)
+	if !ok {
+		return nil
+	}
+
+	// Imported folders belong to the organization of their parent like other
+	// subfolders
+	folderIds := map[string]int64{
+		"": parentFolder.ID,
+	}
+	folders := make([]queries.Folder, len(input.Folders))
+	for i, folderInput := range input.Folders {
+		parentId := folderIds[folderInput.ParentRef]
+		folder, err := qtx.CreateFolder(ctx, queries.CreateFolderParams{
+			Name:           folderInput.Name,
+			OwnerID:        user.ID,
+			ParentID:       &parentId,
+			OrganizationID: parentFolder.OrganizationID,
+		})
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+
+		folderIds[folderInput.Ref] = folder.ID
+		folders[i] = folder
+	}
+
+	report := []models.ImportReportRow{}
+	for _, failure := range input.Failures {
+		report = append(report, models.ImportReportRow{
+			Row:    failure.Row,
+			Status: models.IMPORT_STATUS_FAILED,
+			Reason: failure.Reason,
+		})
+	}
+
+	entries := make([]queries.Entry, len(input.Entries))
+	for i, entryInput := range input.Entries {
+		entryInput.Entry.FolderID = folderIds[entryInput.FolderRef]
+		entry, err := qtx.CreateEntry(ctx, entryInput.Entry)
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+
+		entries[i] = entry
+		report = append(report, models.ImportReportRow{
+			Row:    entryInput.Row,
+			Status: models.IMPORT_STATUS_IMPORTED,
+		})
+	}
+
+	sanitizedImport, ok := models.SanitizeImport(c, &folders, &entries, report)
+	if !ok {
+		return nil
+	}
+
+	return status.Created(c, sanitizedImport)
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Inviting answers the same way whether or not the email is registered, the
+// invitation is claimed when the invitee registers.
+func CreateFolderInvitation(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	access, ok := requireFolderRole(c, int64(folderId), auth.FOLDER_ROLE_MANAGER)
+	if !ok {
+		return nil
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetCreateInvitationInput(c, int64(folderId), user.ID)
+	if !ok {
+		return nil
+	}
+
+	if ok := checkGrantedFolderAccess(c, access, input.Role, input.HidePasswords); !ok {
+		return nil
+	}
+
+	exists, err := qtx.HasOpenFolderInvitation(ctx, queries.HasOpenFolderInvitationParams{
+		FolderID:     input.FolderID,
+		InviteeEmail: input.InviteeEmail,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if exists {
+		return status.BadRequest(c, errors.New("email already invited"))
+	}
+
+	invitation, err := qtx.CreateFolderInvitation(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizeInvitation(c, &invitation))
+}
+
+func GetFolderInvitations(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	if _, ok := requireFolderRole(c, int64(folderId), auth.FOLDER_ROLE_MANAGER); !ok {
+		return nil
+	}
+
+	invitations, err := qtx.GetFolderInvitations(ctx, int64(folderId))
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeInvitations(c, &invitations))
+}
+
+func RevokeFolderInvitation(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	invitation, _, ok := getFolderInvitation(c)
+	if !ok {
+		return nil
+	}
+
+	err := qtx.DeleteFolderInvitation(ctx, invitation.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+// Accepting only proves the invitee wants the folder, access is granted once a
+// manager wraps the folder key with the invitee's public key.
+func ConfirmFolderInvitation(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	invitation, access, ok := getFolderInvitation(c)
+	if !ok {
+		return nil
+	}
+
+	if access.Key == nil {
+		return status.BadRequest(c, errors.New("folder key must be set before sharing"))
+	}
+
+	if invitation.Status != auth.INVITATION_STATUS_ACCEPTED || invitation.InviteeID == nil {
+		return status.BadRequest(c, errors.New("invitation is not accepted"))
+	}
+
+	if invitation.ExpiresAt.Time.Before(time.Now()) {
+		return status.BadRequest(c, errors.New("invitation has expired"))
+	}
+
+	// The confirming manager may not be the inviter
+	if ok := checkGrantedFolderAccess(c, access, invitation.Role, invitation.HidePasswords); !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetConfirmInvitationInput(c)
+	if !ok {
+		return nil
+	}
+
+	invitee, err := qtx.GetUser(ctx, *invitation.InviteeID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if invitee.PublicKey == nil {
+		return status.BadRequest(c, errors.New("user has no public key"))
+	}
+
+	folder, err := qtx.GetFolder(ctx, invitation.FolderID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if invitee.ID == folder.OwnerID {
+		return status.BadRequest(c, errors.New("folder owner can not be changed"))
+	}
+
+	// Members granted a role since the invitation keep it, the invitation is
+	// left for a manager to revoke
+	added, err := qtx.AddFolderUser(ctx, queries.AddFolderUserParams{
+		UserID:        invitee.ID,
+		FolderID:      invitation.FolderID,
+		Key:           &input.Key,
+		Role:          invitation.Role,
+		HidePasswords: invitation.HidePasswords,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if added == 0 {
+		return status.BadRequest(c, errors.New("user is already a folder member"))
+	}
+
+	newInvitation, err := qtx.SetFolderInvitationStatus(ctx, queries.SetFolderInvitationStatusParams{
+		ID:     invitation.ID,
+		Status: auth.INVITATION_STATUS_CONFIRMED,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeInvitation(c, &newInvitation))
+}
+
+func GetInvitations(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	invitations, err := qtx.GetUserInvitations(ctx, &user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeUserInvitations(c, &invitations))
+}
+
+func AcceptInvitation(c *fiber.Ctx) error {
+	return answerInvitation(c, auth.INVITATION_STATUS_ACCEPTED)
+}
+
+func DeclineInvitation(c *fiber.Ctx) error {
+	return answerInvitation(c, auth.INVITATION_STATUS_DECLINED)
+}
+
+func answerInvitation(c *fiber.Ctx, invitationStatus string) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	invitation, err := qtx.GetUserInvitation(ctx, queries.GetUserInvitationParams{
+		InviteeID: &user.ID,
+		Token:     c.Params("token"),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.NotFound(c, nil)
+		}
+
+		return status.InternalServerError(c, nil)
+	}
+
+	if invitation.Status != auth.INVITATION_STATUS_PENDING || invitation.ExpiresAt.Time.Before(time.Now()) {
+		return status.BadRequest(c, errors.New("invitation is no longer pending"))
+	}
+
+	newInvitation, err := qtx.SetFolderInvitationStatus(ctx, queries.SetFolderInvitationStatusParams{
+		ID:     invitation.ID,
+		Status: invitationStatus,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeUserInvitation(c, &newInvitation))
+}
+
+func getFolderInvitation(c *fiber.Ctx) (queries.FolderInvitation, queries.FolderAccess, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.FolderInvitation{}, queries.FolderAccess{}, false
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid folder_id"))
+		return queries.FolderInvitation{}, queries.FolderAccess{}, false
+	}
+
+	invitationId, err := c.ParamsInt("invitation_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid invitation_id"))
+		return queries.FolderInvitation{}, queries.FolderAccess{}, false
+	}
+
+	access, ok := requireFolderRole(c, int64(folderId), auth.FOLDER_ROLE_MANAGER)
+	if !ok {
+		return queries.FolderInvitation{}, queries.FolderAccess{}, false
+	}
+
+	invitation, err := qtx.GetFolderInvitation(ctx, queries.GetFolderInvitationParams{
+		FolderID:     int64(folderId),
+		InvitationID: int64(invitationId),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.FolderInvitation{}, queries.FolderAccess{}, false
+	}
+
+	return invitation, access, true
+}
@@ -0,0 +1,640 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateOrganization(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetOrganizationInput(c)
+	if !ok {
+		return nil
+	}
+
+	organization, err := qtx.CreateOrganization(ctx, input.Name)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	_, err = qtx.AddOrganizationUser(ctx, queries.AddOrganizationUserParams{
+		OrganizationID: organization.ID,
+		UserID:         user.ID,
+		Role:           auth.ORGANIZATION_ROLE_OWNER,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if err := qtx.CreateOrganizationPolicies(ctx, organization.ID); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizedOrganization{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Role:      auth.ORGANIZATION_ROLE_OWNER,
+		CreatedAt: organization.CreatedAt.Time,
+	})
+}
+
+func GetOrganizations(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	organizations, err := qtx.GetUserOrganizations(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedOrganizations, ok := models.SanitizeOrganizations(c, &organizations)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedOrganizations)
+}
+
+func GetOrganization(c *fiber.Ctx) error {
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	organization, ok := getUserOrganization(c, int64(organizationId))
+	if !ok {
+		return nil
+	}
+
+	sanitizedOrganization, ok := models.SanitizeOrganization(c, &organization)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedOrganization)
+}
+
+func UpdateOrganization(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	if _, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_ADMIN); !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetOrganizationInput(c)
+	if !ok {
+		return nil
+	}
+
+	organization, err := qtx.UpdateOrganization(ctx, queries.UpdateOrganizationParams{
+		ID:   int64(organizationId),
+		Name: input.Name,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedOrganization, ok := models.SanitizeOrganization(c, &organization)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedOrganization)
+}
+
+// Deleting an organization deletes its folders along with their entries.
+func RemoveOrganization(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	if _, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_OWNER); !ok {
+		return nil
+	}
+
+	if err := qtx.DeleteOrganization(ctx, int64(organizationId)); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func GetOrganizationUsers(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	if _, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_MEMBER); !ok {
+		return nil
+	}
+
+	organizationUsers, err := qtx.GetOrganizationUsers(ctx, int64(organizationId))
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeOrganizationUsers(c, &organizationUsers))
+}
+
+// Users are invited and only join the organization, falling under its
+// policies, once they accept. Inviting answers the same way whether or not the
+// email is registered, the invitation is claimed when the invitee registers.
+func AddOrganizationUser(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	organizationUser, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_ADMIN)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetAddOrganizationUserInput(c)
+	if !ok {
+		return nil
+	}
+
+	if input.Role == auth.ORGANIZATION_ROLE_OWNER && organizationUser.Role != auth.ORGANIZATION_ROLE_OWNER {
+		return status.Unauthorized(c, errors.New("only organization owners can add owners"))
+	}
+
+	member, err := qtx.GetUserByEmail(ctx, input.Email)
+	if err == nil {
+		_, err = qtx.GetOrganizationUser(ctx, queries.GetOrganizationUserParams{
+			OrganizationID: int64(organizationId),
+			UserID:         member.ID,
+		})
+		if err == nil {
+			return status.BadRequest(c, errors.New("user is already a member"))
+		}
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return status.InternalServerError(c, nil)
+	}
+
+	invitation, err := qtx.CreateOrganizationInvitation(ctx, queries.CreateOrganizationInvitationParams{
+		OrganizationID: int64(organizationId),
+		InviteeEmail:   input.Email,
+		InviterID:      organizationUser.UserID,
+		Role:           input.Role,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizeOrganizationInvitation(c, &invitation))
+}
+
+func GetOrganizationInvitations(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	invitations, err := qtx.GetUserOrganizationInvitations(ctx, &user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeOrganizationInvitations(c, &invitations))
+}
+
+// Organizations requiring two-factor authentication can only be joined with
+// it enabled.
+func AcceptOrganizationInvitation(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	invitation, ok := takeOrganizationInvitation(c, user.ID)
+	if !ok {
+		return nil
+	}
+
+	policies, err := qtx.GetOrganizationPolicies(ctx, invitation.OrganizationID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if policies.RequireTwoFactor {
+		methods, ok := auth.GetMFAMethods(c, user)
+		if !ok {
+			return nil
+		}
+
+		if len(methods) == 0 {
+			return status.BadRequest(c, errors.New("organization requires two-factor authentication"))
+		}
+	}
+
+	newOrganizationUser, err := qtx.AddOrganizationUser(ctx, queries.AddOrganizationUserParams{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         user.ID,
+		Role:           invitation.Role,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeOrganizationUser(c, &newOrganizationUser))
+}
+
+func DeclineOrganizationInvitation(c *fiber.Ctx) error {
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if _, ok := takeOrganizationInvitation(c, user.ID); !ok {
+		return nil
+	}
+
+	return status.Ok(c, nil)
+}
+
+func UpdateOrganizationUser(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	userId, err := c.ParamsInt("user_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid user_id"))
+	}
+
+	organizationUser, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_ADMIN)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetUpdateOrganizationUserInput(c, int64(organizationId), int64(userId))
+	if !ok {
+		return nil
+	}
+
+	member, ok := getOrganizationMember(c, qtx, ctx, input.OrganizationID, input.UserID)
+	if !ok {
+		return nil
+	}
+
+	if (member.Role == auth.ORGANIZATION_ROLE_OWNER || input.Role == auth.ORGANIZATION_ROLE_OWNER) && organizationUser.Role != auth.ORGANIZATION_ROLE_OWNER {
+		return status.Unauthorized(c, errors.New("only organization owners can manage owners"))
+	}
+
+	if member.Role == auth.ORGANIZATION_ROLE_OWNER && input.Role != auth.ORGANIZATION_ROLE_OWNER {
+		if ok := checkOtherOrganizationOwner(c, qtx, ctx, member.OrganizationID); !ok {
+			return nil
+		}
+	}
+
+	newOrganizationUser, err := qtx.UpdateOrganizationUser(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeOrganizationUser(c, &newOrganizationUser))
+}
+
+// Any member can leave the organization. The organization folders they own
+// are handed over to another owner and they lose access to every organization
+// folder.
+func RemoveOrganizationUser(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	userId, err := c.ParamsInt("user_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid user_id"))
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	requiredRole := auth.ORGANIZATION_ROLE_ADMIN
+	if user.ID == int64(userId) {
+		requiredRole = auth.ORGANIZATION_ROLE_MEMBER
+	}
+
+	organizationUser, ok := requireOrganizationRole(c, int64(organizationId), requiredRole)
+	if !ok {
+		return nil
+	}
+
+	member, ok := getOrganizationMember(c, qtx, ctx, int64(organizationId), int64(userId))
+	if !ok {
+		return nil
+	}
+
+	if member.Role == auth.ORGANIZATION_ROLE_OWNER {
+		if organizationUser.Role != auth.ORGANIZATION_ROLE_OWNER {
+			return status.Unauthorized(c, errors.New("only organization owners can manage owners"))
+		}
+
+		if ok := checkOtherOrganizationOwner(c, qtx, ctx, member.OrganizationID); !ok {
+			return nil
+		}
+	}
+
+	err = qtx.ReassignOrganizationFolders(ctx, queries.ReassignOrganizationFoldersParams{
+		UserID:         member.UserID,
+		OrganizationID: member.OrganizationID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	err = qtx.DeleteOrganizationFolderUser(ctx, queries.DeleteOrganizationFolderUserParams{
+		UserID:         member.UserID,
+		OrganizationID: member.OrganizationID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	err = qtx.DeleteOrganizationUser(ctx, queries.DeleteOrganizationUserParams{
+		OrganizationID: member.OrganizationID,
+		UserID:         member.UserID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func GetOrganizationPolicies(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	if _, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_MEMBER); !ok {
+		return nil
+	}
+
+	policies, err := qtx.GetOrganizationPolicies(ctx, int64(organizationId))
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeOrganizationPolicies(c, &policies))
+}
+
+// Two-factor authentication can only be required once every member has
+// enabled it.
+func UpdateOrganizationPolicies(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	if _, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_ADMIN); !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetUpdateOrganizationPoliciesInput(c, int64(organizationId))
+	if !ok {
+		return nil
+	}
+
+	if input.RequireTwoFactor {
+		userIds, err := qtx.GetOrganizationUsersWithoutTwoFactor(ctx, input.OrganizationID)
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+
+		if len(userIds) != 0 {
+			return status.BadRequest(c, fmt.Errorf("members without two-factor authentication: %v", userIds))
+		}
+	}
+
+	policies, err := qtx.UpdateOrganizationPolicies(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeOrganizationPolicies(c, &policies))
+}
+
+func GetOrganizationFolders(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid organization_id"))
+	}
+
+	if _, ok := requireOrganizationRole(c, int64(organizationId), auth.ORGANIZATION_ROLE_ADMIN); !ok {
+		return nil
+	}
+
+	folders, err := qtx.GetOrganizationFolders(ctx, int64(organizationId))
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedFolders, ok := models.SanitizeFolders(c, &folders)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedFolders)
+}
+
+// Removes the invitation of the user to the organization of the request.
+func takeOrganizationInvitation(c *fiber.Ctx, userId int64) (queries.OrganizationInvitation, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.OrganizationInvitation{}, false
+	}
+	defer commit()
+
+	organizationId, err := c.ParamsInt("organization_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid organization_id"))
+		return queries.OrganizationInvitation{}, false
+	}
+
+	invitation, err := qtx.DeleteOrganizationInvitation(ctx, queries.DeleteOrganizationInvitationParams{
+		OrganizationID: int64(organizationId),
+		UserID:         &userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.OrganizationInvitation{}, false
+	}
+
+	return invitation, true
+}
+
+func getUserOrganization(c *fiber.Ctx, organizationId int64) (queries.Organization, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.Organization{}, false
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.Organization{}, false
+	}
+
+	organization, err := qtx.GetUserOrganization(ctx, queries.GetUserOrganizationParams{
+		UserID:         user.ID,
+		OrganizationID: organizationId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.Organization{}, false
+	}
+
+	return organization, true
+}
+
+func getOrganizationMember(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, organizationId int64, userId int64) (queries.OrganizationUser, bool) {
+	member, err := qtx.GetOrganizationUser(ctx, queries.GetOrganizationUserParams{
+		OrganizationID: organizationId,
+		UserID:         userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.OrganizationUser{}, false
+	}
+
+	return member, true
+}
+
+// Organizations always keep an owner to hand their folders over to.
+func checkOtherOrganizationOwner(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, organizationId int64) bool {
+	owners, err := qtx.CountOrganizationOwners(ctx, organizationId)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if owners <= 1 {
+		status.BadRequest(c, errors.New("organization must keep an owner"))
+		return false
+	}
+
+	return true
+}
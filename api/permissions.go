@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func requireFolderRole(c *fiber.Ctx, folderId int64, role string) (queries.FolderAccess, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.FolderAccess{}, false
+	}
+	defer commit()
+
+	return checkFolderRole(c, qtx, ctx, folderId, role)
+}
+
+// Same as requireFolderRole within a transaction the caller already holds.
+func checkFolderRole(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, folderId int64, role string) (queries.FolderAccess, bool) {
+	user, ok := getUser(c)
+	if !ok {
+		return queries.FolderAccess{}, false
+	}
+
+	access, err := qtx.GetFolderAccess(ctx, queries.GetFolderAccessParams{
+		UserID:   user.ID,
+		FolderID: folderId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.FolderAccess{}, false
+	}
+
+	if !auth.HasFolderRole(access.Role, role) {
+		status.Unauthorized(c, errors.New("insufficient folder permissions"))
+		return queries.FolderAccess{}, false
+	}
+
+	return access, true
+}
+
+// Members can only grant the access they hold, a role up to their own and
+// passwords only when they see them.
+func checkGrantedFolderAccess(c *fiber.Ctx, access queries.FolderAccess, role string, hidePasswords bool) bool {
+	if !auth.HasFolderRole(access.Role, role) {
+		status.Unauthorized(c, errors.New("can not grant a role above your own"))
+		return false
+	}
+
+	if access.HidePasswords && !hidePasswords {
+		status.Unauthorized(c, errors.New("can not grant access to passwords hidden from you"))
+		return false
+	}
+
+	return true
+}
+
+// Members can only change the access of members ranked below them, owners can
+// change every member but other owners, including the ones inheriting the
+// ownership from a parent folder.
+func checkManagedFolderUser(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, access queries.FolderAccess, userId int64) (queries.FolderAccess, bool) {
+	targetAccess, err := qtx.GetFolderAccess(ctx, queries.GetFolderAccessParams{
+		UserID:   userId,
+		FolderID: access.FolderID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.FolderAccess{}, false
+	}
+
+	if targetAccess.Role == auth.FOLDER_ROLE_OWNER {
+		status.Unauthorized(c, errors.New("folder owner can not be changed"))
+		return queries.FolderAccess{}, false
+	}
+
+	if access.Role != auth.FOLDER_ROLE_OWNER && !auth.IsAboveFolderRole(access.Role, targetAccess.Role) {
+		status.Unauthorized(c, errors.New("can not change the access of a member ranked as high as you"))
+		return queries.FolderAccess{}, false
+	}
+
+	return targetAccess, true
+}
+
+// Same as checkManagedFolderUser for the grant of a group, a group without a
+// grant on the folder can be granted by any manager.
+func checkManagedFolderGroup(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, access queries.FolderAccess, groupId int64) bool {
+	folderGroup, err := qtx.GetFolderGroup(ctx, queries.GetFolderGroupParams{
+		GroupID:  groupId,
+		FolderID: access.FolderID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return true
+		}
+
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if access.Role != auth.FOLDER_ROLE_OWNER && !auth.IsAboveFolderRole(access.Role, folderGroup.Role) {
+		status.Unauthorized(c, errors.New("can not change the access of a group ranked as high as you"))
+		return false
+	}
+
+	return true
+}
+
+func requireOrganizationRole(c *fiber.Ctx, organizationId int64, role string) (queries.OrganizationUser, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.OrganizationUser{}, false
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.OrganizationUser{}, false
+	}
+
+	organizationUser, err := qtx.GetOrganizationUser(ctx, queries.GetOrganizationUserParams{
+		OrganizationID: organizationId,
+		UserID:         user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.OrganizationUser{}, false
+	}
+
+	if !auth.HasOrganizationRole(organizationUser.Role, role) {
+		status.Unauthorized(c, errors.New("insufficient organization permissions"))
+		return queries.OrganizationUser{}, false
+	}
+
+	return organizationUser, true
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ENTRY_FIELD_NAME          = "name"
+	ENTRY_FIELD_USERNAME      = "username"
+	ENTRY_FIELD_PASSWORD      = "password"
+	ENTRY_FIELD_TOTP          = "totp"
+	ENTRY_FIELD_NOTES         = "notes"
+	ENTRY_FIELD_URIS          = "uris"
+	ENTRY_FIELD_CUSTOM_FIELDS = "customFields"
+)
+
+// Number of revisions kept per entry, set on start.
+var entryRevisionRetention int32
+
+func GetEntryRevisions(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid entry_id"))
+	}
+
+	entry, ok := getUserEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
+
+	access, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_VIEWER)
+	if !ok {
+		return nil
+	}
+
+	revisions, err := qtx.GetEntryRevisions(ctx, entry.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeEntryRevisions(c, &revisions, access.HidePasswords))
+}
+
+// Restoring is an update like any other, the values it replaces are kept as a
+// new revision. Revisions recorded before the entry moved are encrypted with
+// the key of its previous folder and can not be restored.
+func RestoreEntryRevision(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid entry_id"))
+	}
+
+	revisionId, err := c.ParamsInt("revision_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid revision_id"))
+	}
+
+	entry, ok := getUserEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
+
+	access, ok := requireFolderRole(c, entry.FolderID, auth.FOLDER_ROLE_EDITOR)
+	if !ok {
+		return nil
+	}
+
+	revision, err := qtx.GetEntryRevision(ctx, queries.GetEntryRevisionParams{
+		EntryID:    entry.ID,
+		RevisionID: int64(revisionId),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.NotFound(c, nil)
+		}
+
+		return status.InternalServerError(c, nil)
+	}
+
+	if revision.FolderID != entry.FolderID {
+		return status.BadRequest(c, errors.New("revision is encrypted for another folder"))
+	}
+
+	input := queries.UpdateEntryParams{
+		ID:           entry.ID,
+		Name:         revision.Name,
+		Username:     revision.Username,
+		Password:     revision.Password,
+		Totp:         revision.Totp,
+		Notes:        revision.Notes,
+		Uris:         revision.Uris,
+		CustomFields: revision.CustomFields,
+		Fields:       revision.Fields,
+		FolderID:     entry.FolderID,
+	}
+
+	if access.HidePasswords {
+		input.Password = entry.Password
+		input.Totp = entry.Totp
+		input.CustomFields = entry.CustomFields
+
+		input.Fields, err = keepEntrySecretFields(entry.Fields, input.Fields, true)
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+	}
+
+	if ok := createEntryRevision(c, qtx, ctx, entry, input); !ok {
+		return nil
+	}
+
+	newEntry, err := qtx.UpdateEntry(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedEntry, ok := models.SanitizeEntry(c, &newEntry)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedEntry)
+}
+
+// Keeps the values about to be replaced, unless the update only moves the
+// entry, and drops the revisions past the retention count.
+func createEntryRevision(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, entry queries.Entry, input queries.UpdateEntryParams) bool {
+	var changedFields []string
+	if input.Name != entry.Name {
+		changedFields = append(changedFields, ENTRY_FIELD_NAME)
+	}
+	if !equalOptionalStrings(input.Username, entry.Username) {
+		changedFields = append(changedFields, ENTRY_FIELD_USERNAME)
+	}
+	if !equalOptionalStrings(input.Password, entry.Password) {
+		changedFields = append(changedFields, ENTRY_FIELD_PASSWORD)
+	}
+	if !equalOptionalStrings(input.Notes, entry.Notes) {
+		changedFields = append(changedFields, ENTRY_FIELD_NOTES)
+	}
+
+	equalTOTP, err := equalJSON(input.Totp, entry.Totp)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+	if !equalTOTP {
+		changedFields = append(changedFields, ENTRY_FIELD_TOTP)
+	}
+
+	equalURIs, err := equalJSON(input.Uris, entry.Uris)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+	if !equalURIs {
+		changedFields = append(changedFields, ENTRY_FIELD_URIS)
+	}
+
+	equalCustomFields, err := equalJSON(input.CustomFields, entry.CustomFields)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+	if !equalCustomFields {
+		changedFields = append(changedFields, ENTRY_FIELD_CUSTOM_FIELDS)
+	}
+
+	changedTypeFields, err := getChangedEntryFields(entry.Fields, input.Fields)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+	changedFields = append(changedFields, changedTypeFields...)
+
+	if len(changedFields) == 0 {
+		return true
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return false
+	}
+
+	err = qtx.CreateEntryRevision(ctx, queries.CreateEntryRevisionParams{
+		EntryID:       entry.ID,
+		UserID:        &user.ID,
+		ChangedFields: changedFields,
+		Name:          entry.Name,
+		Username:      entry.Username,
+		Password:      entry.Password,
+		Totp:          entry.Totp,
+		Notes:         entry.Notes,
+		Uris:          entry.Uris,
+		CustomFields:  entry.CustomFields,
+		Fields:        entry.Fields,
+		FolderID:      entry.FolderID,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	err = qtx.PruneEntryRevisions(ctx, queries.PruneEntryRevisionsParams{
+		EntryID:   entry.ID,
+		Retention: entryRevisionRetention,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	return true
+}
+
+// Type specific fields are reported under their own name.
+func getChangedEntryFields(currentFields []byte, newFields []byte) ([]string, error) {
+	current := make(map[string]string)
+	if currentFields != nil {
+		if err := json.Unmarshal(currentFields, &current); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make(map[string]string)
+	if newFields != nil {
+		if err := json.Unmarshal(newFields, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var changedFields []string
+	for field, value := range current {
+		if newValue, ok := fields[field]; !ok || newValue != value {
+			changedFields = append(changedFields, field)
+		}
+	}
+	for field := range fields {
+		if _, ok := current[field]; !ok {
+			changedFields = append(changedFields, field)
+		}
+	}
+	sort.Strings(changedFields)
+
+	return changedFields, nil
+}
+
+// Stored JSON is reformatted by the database, values are compared once decoded.
+func equalJSON(a []byte, b []byte) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+
+	var decodedA, decodedB any
+	if err := json.Unmarshal(a, &decodedA); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, &decodedB); err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(decodedA, decodedB), nil
+}
+
+func equalOptionalStrings(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetSessions(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	sessions, err := qtx.GetUserSessions(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeSessions(c, &sessions))
+}
+
+func RemoveSession(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	session, err := qtx.GetUserSession(ctx, queries.GetUserSessionParams{
+		UserID:    user.ID,
+		SessionID: c.Params("session_id"),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.NotFound(c, nil)
+		} else {
+			return status.InternalServerError(c, nil)
+		}
+	}
+
+	if ok := auth.RevokeSession(c, session.ID); !ok {
+		return nil
+	}
+
+	return status.Ok(c, nil)
+}
+
+func RemoveOtherSessions(c *fiber.Ctx) error {
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	sessionId, ok := getSessionId(c)
+	if !ok {
+		return nil
+	}
+
+	if ok := auth.RevokeOtherSessions(c, user.ID, sessionId); !ok {
+		return nil
+	}
+
+	return status.Ok(c, nil)
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TransferFolder(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	folder, ok := getOwnedFolder(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetTransferFolderInput(c)
+	if !ok {
+		return nil
+	}
+
+	if input.UserID == folder.OwnerID {
+		return status.BadRequest(c, errors.New("user already owns the folder"))
+	}
+
+	if !input.RequireAcceptance {
+		folders, ok := transferFolder(c, qtx, ctx, folder, input.UserID, input.Subtree, input.Key)
+		if !ok {
+			return nil
+		}
+
+		sanitizedFolders, ok := models.SanitizeFolders(c, &folders)
+		if !ok {
+			return nil
+		}
+
+		return status.Ok(c, sanitizedFolders)
+	}
+
+	if _, ok := checkFolderMember(c, qtx, ctx, folder.ID, input.UserID); !ok {
+		return nil
+	}
+
+	transfer, err := qtx.CreateFolderTransfer(ctx, queries.CreateFolderTransferParams{
+		FolderID:   folder.ID,
+		FromUserID: folder.OwnerID,
+		ToUserID:   input.UserID,
+		Subtree:    input.Subtree,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, models.SanitizeFolderTransfer(c, &transfer))
+}
+
+func CancelFolderTransfer(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	folder, ok := getOwnedFolder(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	deleted, err := qtx.DeleteFolderTransfer(ctx, folder.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if deleted == 0 {
+		return status.NotFound(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func GetFolderTransfers(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	transfers, err := qtx.GetUserFolderTransfers(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeFolderTransfers(c, &transfers))
+}
+
+func AcceptFolderTransfer(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	transfer, ok := getUserFolderTransfer(c)
+	if !ok {
+		return nil
+	}
+
+	folder, err := qtx.GetFolder(ctx, transfer.FolderID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if folder.OwnerID != transfer.FromUserID {
+		return status.BadRequest(c, errors.New("folder owner changed since the transfer was requested"))
+	}
+
+	input, ok := schemas.GetAcceptFolderTransferInput(c)
+	if !ok {
+		return nil
+	}
+
+	folders, ok := transferFolder(c, qtx, ctx, folder, transfer.ToUserID, transfer.Subtree, input.Key)
+	if !ok {
+		return nil
+	}
+
+	sanitizedFolders, ok := models.SanitizeFolders(c, &folders)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedFolders)
+}
+
+func DeclineFolderTransfer(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	transfer, ok := getUserFolderTransfer(c)
+	if !ok {
+		return nil
+	}
+
+	_, err := qtx.DeleteFolderTransfer(ctx, transfer.FolderID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+// The previous owner is kept as a manager by the folders update trigger. With
+// subtree, the subfolders the previous owner owned are transferred as well.
+// The owner grant of the new owner keeps their key, or falls back to the one
+// inherited from a parent folder, but a key reaching them through a group is
+// wrapped with the group key and has to be wrapped again for them.
+func transferFolder(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, folder queries.Folder, newOwnerId int64, subtree bool, key *string) ([]queries.Folder, bool) {
+	access, ok := checkFolderMember(c, qtx, ctx, folder.ID, newOwnerId)
+	if !ok {
+		return nil, false
+	}
+
+	if access.GroupID != nil && access.Key != nil && key == nil {
+		status.BadRequest(c, errors.New("folder key wrapped with the new owner's public key is required"))
+		return nil, false
+	}
+
+	if folder.OrganizationID != nil {
+		_, err := qtx.GetOrganizationUser(ctx, queries.GetOrganizationUserParams{
+			OrganizationID: *folder.OrganizationID,
+			UserID:         newOwnerId,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				status.BadRequest(c, errors.New("new owner must be an organization member"))
+			} else {
+				status.InternalServerError(c, nil)
+			}
+
+			return nil, false
+		}
+	}
+
+	folders, err := qtx.TransferFolderOwnership(ctx, queries.TransferFolderOwnershipParams{
+		FolderID:        folder.ID,
+		Subtree:         subtree,
+		NewOwnerID:      newOwnerId,
+		PreviousOwnerID: folder.OwnerID,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	if key != nil {
+		err := qtx.SetFolderUserKey(ctx, queries.SetFolderUserKeyParams{
+			UserID:   newOwnerId,
+			FolderID: folder.ID,
+			Key:      key,
+		})
+		if err != nil {
+			status.InternalServerError(c, nil)
+			return nil, false
+		}
+	}
+
+	if _, err := qtx.DeleteFolderTransfer(ctx, folder.ID); err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	return folders, true
+}
+
+func checkFolderMember(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, folderId int64, userId int64) (queries.FolderAccess, bool) {
+	access, err := qtx.GetFolderAccess(ctx, queries.GetFolderAccessParams{
+		UserID:   userId,
+		FolderID: folderId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.BadRequest(c, errors.New("new owner must be a folder member"))
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.FolderAccess{}, false
+	}
+
+	return access, true
+}
+
+// Root folders belong to their user and can not change hands.
+func getOwnedFolder(c *fiber.Ctx, folderId int64) (queries.Folder, bool) {
+	folder, ok := getUserFolder(c, folderId)
+	if !ok {
+		return queries.Folder{}, false
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.Folder{}, false
+	}
+
+	if folder.OwnerID != user.ID {
+		status.Unauthorized(c, errors.New("only folder owner can transfer the folder"))
+		return queries.Folder{}, false
+	}
+
+	if folder.ParentID == nil {
+		status.BadRequest(c, errors.New("root folder can not be transferred"))
+		return queries.Folder{}, false
+	}
+
+	return folder, true
+}
+
+func getUserFolderTransfer(c *fiber.Ctx) (queries.FolderTransfer, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.FolderTransfer{}, false
+	}
+	defer commit()
+
+	transferId, err := c.ParamsInt("transfer_id")
+	if err != nil {
+		status.BadRequest(c, errors.New("invalid transfer_id"))
+		return queries.FolderTransfer{}, false
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.FolderTransfer{}, false
+	}
+
+	transfer, err := qtx.GetUserFolderTransfer(ctx, queries.GetUserFolderTransferParams{
+		ToUserID:   user.ID,
+		TransferID: int64(transferId),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.FolderTransfer{}, false
+	}
+
+	return transfer, true
+}
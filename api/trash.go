@@ -0,0 +1,383 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const TRASH_PURGE_INTERVAL = time.Hour
+
+// Only the folders and entries trashed on their own are listed, the content of
+// a trashed folder comes back with it.
+func GetTrash(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	folders, err := qtx.GetUserTrashedFolders(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	entries, err := qtx.GetUserTrashedEntries(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedTrash, ok := models.SanitizeTrash(c, &folders, &entries)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedTrash)
+}
+
+// The folder goes back under its original parent, or under the root folder of
+// the user when the parent is in the trash as well or no longer manageable.
+func RestoreTrashedFolder(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	if err := qtx.LockFolderTree(ctx); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	folder, ok := getUserTrashedFolder(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	if _, ok := checkTrashedFolderRole(c, qtx, ctx, folder.ID, auth.FOLDER_ROLE_OWNER); !ok {
+		return nil
+	}
+
+	parentId, ok := getRestoreFolderId(c, qtx, ctx, *folder.ParentID, auth.FOLDER_ROLE_MANAGER)
+	if !ok {
+		return nil
+	}
+
+	restoredFolders, err := qtx.RestoreFolder(ctx, queries.RestoreFolderParams{
+		FolderID: folder.ID,
+		ParentID: parentId,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	for _, restoredFolder := range restoredFolders {
+		if restoredFolder.ID != folder.ID {
+			continue
+		}
+
+		sanitizedFolder, ok := models.SanitizeFolder(c, &restoredFolder)
+		if !ok {
+			return nil
+		}
+
+		return status.Ok(c, sanitizedFolder)
+	}
+
+	return status.InternalServerError(c, nil)
+}
+
+// The entry goes back in its original folder, which has to be restored first
+// when it is in the trash since the entry is encrypted with its key.
+func RestoreTrashedEntry(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid entry_id"))
+	}
+
+	entry, ok := getUserTrashedEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
+
+	folder, ok := checkTrashedEntryRole(c, qtx, ctx, entry, auth.FOLDER_ROLE_EDITOR)
+	if !ok {
+		return nil
+	}
+
+	if folder.DeletedAt.Valid {
+		return status.BadRequest(c, errors.New("entry folder must be restored first"))
+	}
+
+	restoredEntry, err := qtx.RestoreEntry(ctx, queries.RestoreEntryParams{
+		ID:       entry.ID,
+		FolderID: entry.FolderID,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	sanitizedEntry, ok := models.SanitizeEntry(c, &restoredEntry)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, sanitizedEntry)
+}
+
+func RemoveTrashedFolder(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	folderId, err := c.ParamsInt("folder_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid folder_id"))
+	}
+
+	folder, ok := getUserTrashedFolder(c, int64(folderId))
+	if !ok {
+		return nil
+	}
+
+	if _, ok := checkTrashedFolderRole(c, qtx, ctx, folder.ID, auth.FOLDER_ROLE_OWNER); !ok {
+		return nil
+	}
+
+	err = qtx.DeleteFolder(ctx, folder.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func RemoveTrashedEntry(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	entryId, err := c.ParamsInt("entry_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid entry_id"))
+	}
+
+	entry, ok := getUserTrashedEntry(c, int64(entryId))
+	if !ok {
+		return nil
+	}
+
+	if _, ok := checkTrashedEntryRole(c, qtx, ctx, entry, auth.FOLDER_ROLE_EDITOR); !ok {
+		return nil
+	}
+
+	err = qtx.DeleteEntry(ctx, entry.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+// Permanently deletes the folders and entries trashed for longer than the
+// retention, then the blobs of the attachments deleted with them or on their
+// own, until the returned function is called.
+func startTrashPurge(retention int32) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(TRASH_PURGE_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			purgeTrash(retention)
+			purgeDeletedAttachmentBlobs()
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func purgeTrash(retention int32) {
+	conn, release, ctx, err := database.Acquire()
+	if err != nil {
+		return
+	}
+	defer release()
+
+	q := queries.New(conn)
+	if err := q.PurgeTrashedFolders(ctx, retention); err != nil {
+		return
+	}
+
+	q.PurgeTrashedEntries(ctx, retention)
+}
+
+// Same as checkFolderRole for a folder in the trash.
+func checkTrashedFolderRole(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, folderId int64, role string) (queries.FolderAccess, bool) {
+	user, ok := getUser(c)
+	if !ok {
+		return queries.FolderAccess{}, false
+	}
+
+	access, err := qtx.GetTrashedFolderAccess(ctx, queries.GetTrashedFolderAccessParams{
+		UserID:   user.ID,
+		FolderID: folderId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.FolderAccess{}, false
+	}
+
+	if !auth.HasFolderRole(access.Role, role) {
+		status.Unauthorized(c, errors.New("insufficient folder permissions"))
+		return queries.FolderAccess{}, false
+	}
+
+	return access, true
+}
+
+// A trashed entry may sit in a folder that is in the trash or not.
+func checkTrashedEntryRole(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, entry queries.Entry, role string) (queries.Folder, bool) {
+	folder, err := qtx.GetFolder(ctx, entry.FolderID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return queries.Folder{}, false
+	}
+
+	if folder.DeletedAt.Valid {
+		_, ok := checkTrashedFolderRole(c, qtx, ctx, folder.ID, role)
+		return folder, ok
+	}
+
+	_, ok := checkFolderRole(c, qtx, ctx, folder.ID, role)
+	return folder, ok
+}
+
+// Returns the folder when it is not in the trash and the user has the role in
+// it, the root folder of the user otherwise.
+func getRestoreFolderId(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, folderId int64, role string) (int64, bool) {
+	user, ok := getUser(c)
+	if !ok {
+		return 0, false
+	}
+
+	access, err := qtx.GetFolderAccess(ctx, queries.GetFolderAccessParams{
+		UserID:   user.ID,
+		FolderID: folderId,
+	})
+	if err == nil && auth.HasFolderRole(access.Role, role) {
+		return folderId, true
+	}
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	rootFolder, err := qtx.GetUserRootFolder(ctx, user.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	return rootFolder.ID, true
+}
+
+func getUserTrashedFolder(c *fiber.Ctx, folderId int64) (queries.Folder, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.Folder{}, false
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.Folder{}, false
+	}
+
+	folder, err := qtx.GetUserTrashedFolder(ctx, queries.GetUserTrashedFolderParams{
+		UserID:   user.ID,
+		FolderID: folderId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.Folder{}, false
+	}
+
+	return folder, true
+}
+
+func getUserTrashedEntry(c *fiber.Ctx, entryId int64) (queries.Entry, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.Entry{}, false
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return queries.Entry{}, false
+	}
+
+	entry, err := qtx.GetUserTrashedEntry(ctx, queries.GetUserTrashedEntryParams{
+		UserID:  user.ID,
+		EntryID: entryId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.NotFound(c, nil)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return queries.Entry{}, false
+	}
+
+	return entry, true
+}
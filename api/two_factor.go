@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func EnableTOTP(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if user.TotpEnabled {
+		return status.BadRequest(c, errors.New("totp is already enabled"))
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	err = qtx.SetUserTOTPSecret(ctx, queries.SetUserTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: &secret,
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Created(c, fiber.Map{
+		"secret": secret,
+		"uri":    auth.GetTOTPURI(secret, user.Email),
+	})
+}
+
+func VerifyTOTP(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if user.TotpEnabled {
+		return status.BadRequest(c, errors.New("totp is already enabled"))
+	}
+
+	input, ok := schemas.GetTOTPCodeInput(c)
+	if !ok {
+		return nil
+	}
+
+	if ok := auth.VerifyTOTPCode(c, user, input.Code); !ok {
+		return nil
+	}
+
+	if err := qtx.EnableUserTOTP(ctx, user.ID); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	recoveryCodes, ok := auth.GenerateRecoveryCodes(c, user.ID)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, fiber.Map{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func DisableTOTP(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if !user.TotpEnabled {
+		return status.BadRequest(c, errors.New("totp is not enabled"))
+	}
+
+	input, ok := schemas.GetSecondFactorInput(c)
+	if !ok {
+		return nil
+	}
+
+	hasWebAuthnCredentials, err := qtx.HasUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	// Verifying the second factor uses it up, refused requests must not
+	if !hasWebAuthnCredentials {
+		if ok := checkTwoFactorPolicy(c, qtx, ctx, user.ID); !ok {
+			return nil
+		}
+	}
+
+	if ok := verifySecondFactor(c, user, input); !ok {
+		return nil
+	}
+
+	if err := qtx.DisableUserTOTP(ctx, user.ID); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if err := qtx.DeleteUserRecoveryCodes(ctx, user.ID); err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, nil)
+}
+
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	if !user.TotpEnabled {
+		return status.BadRequest(c, errors.New("totp is not enabled"))
+	}
+
+	input, ok := schemas.GetTOTPCodeInput(c)
+	if !ok {
+		return nil
+	}
+
+	if ok := auth.VerifyTOTPCode(c, user, input.Code); !ok {
+		return nil
+	}
+
+	recoveryCodes, ok := auth.GenerateRecoveryCodes(c, user.ID)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, fiber.Map{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func verifySecondFactor(c *fiber.Ctx, user queries.User, input schemas.SecondFactorInput) bool {
+	if len(input.Code) != 0 {
+		if !user.TotpEnabled {
+			status.Unauthorized(c, errors.New("invalid code"))
+			return false
+		}
+
+		return auth.VerifyTOTPCode(c, user, input.Code)
+	}
+
+	return auth.UseRecoveryCode(c, user.ID, input.RecoveryCode)
+}
+
+// Members of an organization requiring two-factor authentication can not
+// remove their last second factor.
+func checkTwoFactorPolicy(c *fiber.Ctx, qtx *queries.Queries, ctx context.Context, userId int64) bool {
+	policies, err := qtx.GetUserPolicies(ctx, userId)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if policies.RequireTwoFactor {
+		status.BadRequest(c, errors.New("two-factor authentication is required by your organization"))
+		return false
+	}
+
+	return true
+}
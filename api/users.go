@@ -3,13 +3,16 @@ package api
 import (
 	"errors"
 
+	"github.com/LeonardJouve/pass-secure/auth"
 	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
 	"github.com/LeonardJouve/pass-secure/schemas"
 	"github.com/LeonardJouve/pass-secure/status"
 	"github.com/gofiber/fiber/v2"
 )
 
+// Only the members of the user's organizations can be searched.
 func GetUsers(c *fiber.Ctx) error {
 	qtx, ctx, commit, ok := database.BeginTransaction(c)
 	if !ok {
@@ -17,7 +20,15 @@ func GetUsers(c *fiber.Ctx) error {
 	}
 	defer commit()
 
-	users, err := qtx.GetUsers(ctx)
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	users, err := qtx.SearchUsers(ctx, queries.SearchUsersParams{
+		UserID: user.ID,
+		Search: c.Query("search"),
+	})
 	if err != nil {
 		return status.InternalServerError(c, nil)
 	}
@@ -25,6 +36,8 @@ func GetUsers(c *fiber.Ctx) error {
 	return status.Ok(c, models.SanitizeUsers(c, &users))
 }
 
+// Only the users sharing an organization, a group or a folder with the user,
+// or involved in one of their invitations, can be looked up.
 func GetUser(c *fiber.Ctx) error {
 	qtx, ctx, commit, ok := database.BeginTransaction(c)
 	if !ok {
@@ -32,17 +45,25 @@ func GetUser(c *fiber.Ctx) error {
 	}
 	defer commit()
 
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
 	userId, err := c.ParamsInt("user_id")
 	if err != nil {
 		return status.BadRequest(c, errors.New("invalid user_id"))
 	}
 
-	user, err := qtx.GetUser(ctx, int64(userId))
+	visibleUser, err := qtx.GetVisibleUser(ctx, queries.GetVisibleUserParams{
+		ID:     int64(userId),
+		UserID: user.ID,
+	})
 	if err != nil {
 		return status.NotFound(c, nil)
 	}
 
-	return status.Ok(c, models.SanitizeUser(c, &user))
+	return status.Ok(c, models.SanitizeUser(c, &visibleUser))
 }
 
 func GetMe(c *fiber.Ctx) error {
@@ -51,7 +72,7 @@ func GetMe(c *fiber.Ctx) error {
 		return status.Unauthorized(c, nil)
 	}
 
-	return status.Ok(c, models.SanitizeUser(c, &user))
+	return status.Ok(c, models.SanitizeMe(c, &user))
 }
 
 func RemoveMe(c *fiber.Ctx) error {
@@ -66,7 +87,31 @@ func RemoveMe(c *fiber.Ctx) error {
 		return nil
 	}
 
-	err := qtx.DeleteUser(ctx, user.ID)
+	soleOwnedOrganizationIds, err := qtx.GetUserSoleOwnedOrganizations(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if len(soleOwnedOrganizationIds) != 0 {
+		return status.BadRequest(c, errors.New("organizations must be handed over to another owner or deleted first"))
+	}
+
+	memberships, err := qtx.GetUserOrganizationMemberships(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	for _, membership := range memberships {
+		err := qtx.ReassignOrganizationFolders(ctx, queries.ReassignOrganizationFoldersParams{
+			UserID:         user.ID,
+			OrganizationID: membership.OrganizationID,
+		})
+		if err != nil {
+			return status.InternalServerError(c, nil)
+		}
+	}
+
+	err = qtx.DeleteUser(ctx, user.ID)
 	if err != nil {
 		return status.InternalServerError(c, nil)
 	}
@@ -86,7 +131,12 @@ func UpdateMe(c *fiber.Ctx) error {
 		return nil
 	}
 
-	input, ok := schemas.GetUpdateMeInput(c, user.ID)
+	policies, err := qtx.GetUserPolicies(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	input, ok := schemas.GetUpdateMeInput(c, user, policies.MinPasswordStrength)
 	if !ok {
 		return nil
 	}
@@ -96,5 +146,68 @@ func UpdateMe(c *fiber.Ctx) error {
 		return status.InternalServerError(c, nil)
 	}
 
-	return status.Ok(c, models.SanitizeUser(c, &newUser))
+	if newUser.Password != user.Password {
+		sessionId, ok := getSessionId(c)
+		if !ok {
+			return nil
+		}
+
+		if ok := auth.RevokeOtherSessions(c, user.ID, sessionId); !ok {
+			return nil
+		}
+	}
+
+	return status.Ok(c, models.SanitizeMe(c, &newUser))
+}
+
+func GetMyPolicies(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	policies, err := qtx.GetUserPolicies(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeUserPolicies(c, &policies))
+}
+
+func SetMyKeyPair(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	input, ok := schemas.GetSetKeyPairInput(c, user.ID)
+	if !ok {
+		return nil
+	}
+
+	updated, err := qtx.SetUserKeyPair(ctx, input)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if updated == 0 {
+		return status.BadRequest(c, errors.New("key pair already set"))
+	}
+
+	user.PublicKey = input.PublicKey
+	user.PrivateKey = input.PrivateKey
+
+	return status.Ok(c, models.SanitizeMe(c, &user))
 }
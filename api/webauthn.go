@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/models"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/schemas"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetWebAuthnRegistrationOptions(c *fiber.Ctx) error {
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	options, ok := auth.BeginWebAuthnRegistration(c, user)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, options)
+}
+
+func CreateWebAuthnCredential(c *fiber.Ctx) error {
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	name, response, ok := schemas.GetWebAuthnRegistrationInput(c)
+	if !ok {
+		return nil
+	}
+
+	credential, ok := auth.FinishWebAuthnRegistration(c, user, name, response)
+	if !ok {
+		return nil
+	}
+
+	return status.Created(c, models.SanitizeWebAuthnCredential(c, &credential))
+}
+
+func GetWebAuthnCredentials(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	credentials, err := qtx.GetUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	return status.Ok(c, models.SanitizeWebAuthnCredentials(c, &credentials))
+}
+
+func RemoveWebAuthnCredential(c *fiber.Ctx) error {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil
+	}
+	defer commit()
+
+	credentialId, err := c.ParamsInt("credential_id")
+	if err != nil {
+		return status.BadRequest(c, errors.New("invalid credential_id"))
+	}
+
+	user, ok := getUser(c)
+	if !ok {
+		return nil
+	}
+
+	deleted, err := qtx.DeleteUserWebAuthnCredential(ctx, queries.DeleteUserWebAuthnCredentialParams{
+		UserID: user.ID,
+		ID:     int64(credentialId),
+	})
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if deleted == 0 {
+		return status.NotFound(c, nil)
+	}
+
+	hasWebAuthnCredentials, err := qtx.HasUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return status.InternalServerError(c, nil)
+	}
+
+	if !user.TotpEnabled && !hasWebAuthnCredentials {
+		if ok := checkTwoFactorPolicy(c, qtx, ctx, user.ID); !ok {
+			return nil
+		}
+	}
+
+	return status.Ok(c, nil)
+}
+
+func GetWebAuthnLoginOptions(c *fiber.Ctx) error {
+	input, ok := schemas.GetWebAuthnLoginOptionsInput(c)
+	if !ok {
+		return nil
+	}
+
+	var userId int64
+	if len(input.MFAToken) != 0 {
+		_, userId, ok = auth.ValidateMFAToken(c, input.MFAToken)
+		if !ok {
+			return nil
+		}
+	}
+
+	options, ok := auth.BeginWebAuthnLogin(c, userId)
+	if !ok {
+		return nil
+	}
+
+	return status.Ok(c, options)
+}
+
+func LoginWebAuthn(c *fiber.Ctx) error {
+	mfaToken, response, ok := schemas.GetWebAuthnLoginInput(c)
+	if !ok {
+		return nil
+	}
+
+	var userId int64
+	if len(mfaToken) != 0 {
+		_, userId, ok = auth.ValidateMFAToken(c, mfaToken)
+		if !ok {
+			return nil
+		}
+	}
+
+	userId, ok = auth.FinishWebAuthnLogin(c, userId, response)
+	if !ok {
+		return nil
+	}
+
+	return startSession(c, userId)
+}
@@ -0,0 +1,29 @@
+package auth
+
+const (
+	FOLDER_ROLE_VIEWER  = "viewer"
+	FOLDER_ROLE_EDITOR  = "editor"
+	FOLDER_ROLE_MANAGER = "manager"
+	FOLDER_ROLE_OWNER   = "owner"
+)
+
+// Viewers read, editors manage entries, managers manage subfolders and
+// members, owners can also delete the folder and change its key.
+var folderRoleRanks = map[string]int{
+	FOLDER_ROLE_VIEWER:  1,
+	FOLDER_ROLE_EDITOR:  2,
+	FOLDER_ROLE_MANAGER: 3,
+	FOLDER_ROLE_OWNER:   4,
+}
+
+func HasFolderRole(role string, requiredRole string) bool {
+	rank, ok := folderRoleRanks[role]
+
+	return ok && rank >= folderRoleRanks[requiredRole]
+}
+
+func IsAboveFolderRole(role string, otherRole string) bool {
+	rank, ok := folderRoleRanks[role]
+
+	return ok && rank > folderRoleRanks[otherRole]
+}
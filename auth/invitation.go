@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	INVITATION_TOKEN_SIZE       = 32
+	INVITATION_STATUS_PENDING   = "pending"
+	INVITATION_STATUS_ACCEPTED  = "accepted"
+	INVITATION_STATUS_DECLINED  = "declined"
+	INVITATION_STATUS_CONFIRMED = "confirmed"
+)
+
+// Invitations are only addressed by their token on the invitee side, so that
+// their sequential ids can not be enumerated.
+func CreateInvitationToken(c *fiber.Ctx) (string, pgtype.Timestamptz, bool) {
+	invitationLifetimeString := os.Getenv("INVITATION_LIFETIME_IN_DAY")
+	invitationLifetime, err := strconv.ParseInt(invitationLifetimeString, 10, 64)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return "", pgtype.Timestamptz{}, false
+	}
+
+	tokenBytes := make([]byte, INVITATION_TOKEN_SIZE)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		status.InternalServerError(c, nil)
+		return "", pgtype.Timestamptz{}, false
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), pgtype.Timestamptz{
+		Time:  time.Now().UTC().Add(time.Duration(invitationLifetime) * 24 * time.Hour),
+		Valid: true,
+	}, true
+}
@@ -0,0 +1,18 @@
+package auth
+
+// The master password never leaves the client: it is stretched with the
+// user's KDF (salted with the lowercased email) into a master key, which is
+// hashed once more client side into the master password hash sent to the server.
+const (
+	KDF_PBKDF2                = "pbkdf2"
+	KDF_ARGON2ID              = "argon2id"
+	PBKDF2_MIN_ITERATIONS     = 600000
+	ARGON2ID_MIN_ITERATIONS   = 2
+	ARGON2ID_MIN_MEMORY       = 16
+	ARGON2ID_MAX_MEMORY       = 1024
+	ARGON2ID_MIN_PARALLELISM  = 1
+	ARGON2ID_MAX_PARALLELISM  = 16
+	DEFAULT_KDF               = KDF_PBKDF2
+	DEFAULT_KDF_ITERATIONS    = PBKDF2_MIN_ITERATIONS
+	MASTER_PASSWORD_HASH_SIZE = 44
+)
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	MFA_TOKEN              = "mfa_token"
+	MFA_TOKEN_LIFETIME     = 5 * time.Minute
+	MFA_MAX_ATTEMPTS       = 5
+	MFA_ATTEMPTS_PREFIX    = "mfa_attempts:"
+	RECOVERY_CODE_AMOUNT   = 10
+	RECOVERY_CODE_SIZE     = 10
+	RECOVERY_CODE_GROUPING = 4
+	MFA_METHOD_TOTP        = "totp"
+)
+
+// The mfa pending token proves the password step succeeded, it is signed with
+// its own key so it can never be accepted as an access token.
+func CreateMFAToken(c *fiber.Ctx, userId int64) (string, bool) {
+	privateKey, ok := getPrivateKey(c, MFA_TOKEN)
+	if !ok {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+
+	now := time.Now().UTC()
+
+	jwt.TimePrecision = time.Microsecond
+	claims := &jwt.RegisteredClaims{
+		ID:        utils.UUIDv4(),
+		Subject:   strconv.FormatInt(userId, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(MFA_TOKEN_LIFETIME)),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+
+	return token, true
+}
+
+func ValidateMFAToken(c *fiber.Ctx, token string) (jwt.RegisteredClaims, int64, bool) {
+	publicKey, ok := getPublicKey(c, MFA_TOKEN)
+	if !ok {
+		status.Unauthorized(c, nil)
+		return jwt.RegisteredClaims{}, 0, false
+	}
+
+	invalidMFATokenErr := errors.New("invalid mfa token")
+
+	var claims = jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return publicKey, nil
+	})
+	if err != nil {
+		status.Unauthorized(c, invalidMFATokenErr)
+		return jwt.RegisteredClaims{}, 0, false
+	}
+
+	userId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		status.Unauthorized(c, invalidMFATokenErr)
+		return jwt.RegisteredClaims{}, 0, false
+	}
+
+	if !registerMFAAttempt(claims) {
+		status.Unauthorized(c, errors.New("too many attempts"))
+		return jwt.RegisteredClaims{}, 0, false
+	}
+
+	return claims, userId, true
+}
+
+func GenerateRecoveryCodes(c *fiber.Ctx, userId int64) ([]string, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil, false
+	}
+	defer commit()
+
+	if err := qtx.DeleteUserRecoveryCodes(ctx, userId); err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	recoveryCodes := make([]string, RECOVERY_CODE_AMOUNT)
+	for i := range recoveryCodes {
+		codeBytes := make([]byte, RECOVERY_CODE_SIZE)
+		if _, err := rand.Read(codeBytes); err != nil {
+			status.InternalServerError(c, nil)
+			return nil, false
+		}
+
+		code := totpEncoding.EncodeToString(codeBytes)
+
+		err := qtx.CreateRecoveryCode(ctx, queries.CreateRecoveryCodeParams{
+			UserID:   userId,
+			CodeHash: hashRecoveryCode(code),
+		})
+		if err != nil {
+			status.InternalServerError(c, nil)
+			return nil, false
+		}
+
+		var groups []string
+		for j := 0; j < len(code); j += RECOVERY_CODE_GROUPING {
+			groups = append(groups, code[j:min(j+RECOVERY_CODE_GROUPING, len(code))])
+		}
+		recoveryCodes[i] = strings.Join(groups, "-")
+	}
+
+	return recoveryCodes, true
+}
+
+func UseRecoveryCode(c *fiber.Ctx, userId int64, code string) bool {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return false
+	}
+	defer commit()
+
+	updated, err := qtx.UseRecoveryCode(ctx, queries.UseRecoveryCodeParams{
+		UserID:   userId,
+		CodeHash: hashRecoveryCode(code),
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if updated == 0 {
+		status.Unauthorized(c, errors.New("invalid recovery code"))
+		return false
+	}
+
+	return true
+}
+
+func GetMFAMethods(c *fiber.Ctx, user queries.User) ([]string, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return nil, false
+	}
+	defer commit()
+
+	var methods []string
+	if user.TotpEnabled {
+		methods = append(methods, MFA_METHOD_TOTP)
+	}
+
+	hasWebAuthnCredentials, err := qtx.HasUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	if hasWebAuthnCredentials {
+		methods = append(methods, MFA_METHOD_WEBAUTHN)
+	}
+
+	return methods, true
+}
+
+// Bounds brute forcing of the second factor to a few attempts per mfa token.
+func registerMFAAttempt(claims jwt.RegisteredClaims) bool {
+	key := MFA_ATTEMPTS_PREFIX + claims.ID
+
+	attempts := 0
+	if cachedAttempts, err := sessionStorage.Get(key); err == nil && cachedAttempts != nil {
+		attempts, _ = strconv.Atoi(string(cachedAttempts))
+	}
+
+	if attempts >= MFA_MAX_ATTEMPTS {
+		return false
+	}
+
+	sessionStorage.Set(key, []byte(strconv.Itoa(attempts+1)), MFA_TOKEN_LIFETIME)
+
+	return true
+}
+
+func hashRecoveryCode(code string) string {
+	normalizedCode := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalizedCode))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,21 @@
+package auth
+
+const (
+	ORGANIZATION_ROLE_MEMBER = "member"
+	ORGANIZATION_ROLE_ADMIN  = "admin"
+	ORGANIZATION_ROLE_OWNER  = "owner"
+)
+
+// Members share folders with each other, admins manage members and policies,
+// owners can also manage other owners and delete the organization.
+var organizationRoleRanks = map[string]int{
+	ORGANIZATION_ROLE_MEMBER: 1,
+	ORGANIZATION_ROLE_ADMIN:  2,
+	ORGANIZATION_ROLE_OWNER:  3,
+}
+
+func HasOrganizationRole(role string, requiredRole string) bool {
+	rank, ok := organizationRoleRanks[role]
+
+	return ok && rank >= organizationRoleRanks[requiredRole]
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const REFRESH_TOKEN_SIZE = 32
+
+func CreateRefreshToken(c *fiber.Ctx, userId int64, family string) (string, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return "", false
+	}
+	defer commit()
+
+	refreshTokenLifetimeString := os.Getenv("REFRESH_TOKEN_LIFETIME_IN_DAY")
+	refreshTokenLifetime, err := strconv.ParseInt(refreshTokenLifetimeString, 10, 64)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+
+	tokenBytes := make([]byte, REFRESH_TOKEN_SIZE)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	expiresAt := time.Now().UTC().Add(time.Duration(refreshTokenLifetime) * 24 * time.Hour)
+
+	_, err = qtx.CreateRefreshToken(ctx, queries.CreateRefreshTokenParams{
+		UserID:    userId,
+		Family:    family,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  expiresAt,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     REFRESH_TOKEN,
+		Value:    token,
+		Path:     "/refresh",
+		Secure:   true,
+		HTTPOnly: true,
+		Domain:   os.Getenv("HOST"),
+		Expires:  expiresAt,
+	})
+
+	return token, true
+}
+
+// Refresh tokens are single-use: presenting one that was already used means it
+// leaked, so the whole family it belongs to is revoked along with its session.
+func RotateRefreshToken(c *fiber.Ctx, token string) (queries.RefreshToken, string, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.RefreshToken{}, "", false
+	}
+	defer commit()
+
+	invalidRefreshTokenErr := errors.New("invalid refresh token")
+
+	refreshToken, err := qtx.GetRefreshToken(ctx, hashRefreshToken(token))
+	if err != nil {
+		status.Unauthorized(c, invalidRefreshTokenErr)
+		return queries.RefreshToken{}, "", false
+	}
+
+	if refreshToken.Revoked {
+		status.Unauthorized(c, invalidRefreshTokenErr)
+		return queries.RefreshToken{}, "", false
+	}
+
+	if refreshToken.Used {
+		revokeLeakedSession(refreshToken.Family)
+		status.Unauthorized(c, invalidRefreshTokenErr)
+		return queries.RefreshToken{}, "", false
+	}
+
+	if refreshToken.ExpiresAt.Time.Before(time.Now().UTC()) {
+		status.Unauthorized(c, invalidRefreshTokenErr)
+		return queries.RefreshToken{}, "", false
+	}
+
+	updated, err := qtx.UseRefreshToken(ctx, refreshToken.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return queries.RefreshToken{}, "", false
+	}
+
+	// Another request consumed the token concurrently
+	if updated == 0 {
+		revokeLeakedSession(refreshToken.Family)
+		status.Unauthorized(c, invalidRefreshTokenErr)
+		return queries.RefreshToken{}, "", false
+	}
+
+	newToken, ok := CreateRefreshToken(c, refreshToken.UserID, refreshToken.Family)
+	if !ok {
+		return queries.RefreshToken{}, "", false
+	}
+
+	return refreshToken, newToken, true
+}
+
+// Runs outside of the request transaction, which is rolled back on unauthorized
+// responses. The family is the ID of the session the tokens were issued for.
+func revokeLeakedSession(family string) {
+	conn, release, ctx, err := database.Acquire()
+	if err != nil {
+		return
+	}
+	defer release()
+
+	q := queries.New(conn)
+	q.RevokeRefreshTokenFamily(ctx, family)
+	if err := q.RevokeSession(ctx, family); err != nil {
+		return
+	}
+
+	cacheSession(family, SESSION_REVOKED)
+}
+
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
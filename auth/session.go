@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	SESSION_CACHE_PREFIX   = "session:"
+	SESSION_CACHE_DURATION = time.Minute
+	SESSION_ACTIVE         = "active"
+	SESSION_REVOKED        = "revoked"
+	DEVICE_HEADER          = "X-Device-Name"
+	MAX_DEVICE_LENGTH      = 128
+	MAX_USER_AGENT_LENGTH  = 512
+)
+
+var sessionStorage fiber.Storage
+
+func InitSessionStorage(storage fiber.Storage) {
+	sessionStorage = storage
+}
+
+func CreateSession(c *fiber.Ctx, userId int64) (queries.Session, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.Session{}, false
+	}
+	defer commit()
+
+	var device *string
+	if deviceHeader := c.Get(DEVICE_HEADER); len(deviceHeader) != 0 {
+		deviceHeader = truncate(deviceHeader, MAX_DEVICE_LENGTH)
+		device = &deviceHeader
+	}
+
+	session, err := qtx.CreateSession(ctx, queries.CreateSessionParams{
+		ID:        utils.UUIDv4(),
+		UserID:    userId,
+		Device:    device,
+		UserAgent: truncate(c.Get(fiber.HeaderUserAgent), MAX_USER_AGENT_LENGTH),
+		Ip:        c.IP(),
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return queries.Session{}, false
+	}
+
+	cacheSession(session.ID, SESSION_ACTIVE)
+
+	return session, true
+}
+
+// Sessions are looked up in the cache first so that only cache misses hit the
+// database, which is also when last seen is refreshed.
+func IsRevoked(c *fiber.Ctx, claims jwt.RegisteredClaims) bool {
+	if cachedSession, err := sessionStorage.Get(SESSION_CACHE_PREFIX + claims.ID); err == nil && cachedSession != nil {
+		if string(cachedSession) == SESSION_REVOKED {
+			status.Unauthorized(c, errors.New("revoked session"))
+			return true
+		}
+
+		return false
+	}
+
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return true
+	}
+	defer commit()
+
+	session, err := qtx.TouchSession(ctx, queries.TouchSessionParams{
+		ID: claims.ID,
+		Ip: c.IP(),
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		status.InternalServerError(c, nil)
+		return true
+	}
+
+	if err != nil || strconv.FormatInt(session.UserID, 10) != claims.Subject {
+		cacheSession(claims.ID, SESSION_REVOKED)
+		status.Unauthorized(c, errors.New("revoked session"))
+		return true
+	}
+
+	cacheSession(session.ID, SESSION_ACTIVE)
+
+	return false
+}
+
+func RevokeSession(c *fiber.Ctx, sessionId string) bool {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return false
+	}
+	defer commit()
+
+	if err := qtx.RevokeSession(ctx, sessionId); err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if err := qtx.RevokeRefreshTokenFamily(ctx, sessionId); err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	cacheSession(sessionId, SESSION_REVOKED)
+
+	return true
+}
+
+func RevokeOtherSessions(c *fiber.Ctx, userId int64, sessionId string) bool {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return false
+	}
+	defer commit()
+
+	sessionIds, err := qtx.RevokeOtherUserSessions(ctx, queries.RevokeOtherUserSessionsParams{
+		UserID:    userId,
+		SessionID: sessionId,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if err := qtx.RevokeRefreshTokenFamilies(ctx, sessionIds); err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	for _, revokedSessionId := range sessionIds {
+		cacheSession(revokedSessionId, SESSION_REVOKED)
+	}
+
+	return true
+}
+
+func ClearTokens(c *fiber.Ctx) {
+	expired := time.Now().Add(-time.Hour)
+
+	c.Cookie(&fiber.Cookie{
+		Name:     ACCESS_TOKEN,
+		Path:     "/",
+		Secure:   true,
+		HTTPOnly: true,
+		Domain:   os.Getenv("HOST"),
+		Expires:  expired,
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     REFRESH_TOKEN,
+		Path:     "/refresh",
+		Secure:   true,
+		HTTPOnly: true,
+		Domain:   os.Getenv("HOST"),
+		Expires:  expired,
+	})
+}
+
+func cacheSession(sessionId string, state string) {
+	sessionStorage.Set(SESSION_CACHE_PREFIX+sessionId, []byte(state), SESSION_CACHE_DURATION)
+}
+
+// Columns are limited in characters, cutting on runes keeps multi-byte
+// characters whole.
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+
+	return string(runes[:length])
+}
@@ -9,27 +9,38 @@ import (
 	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/status"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/utils"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
-	ACCESS_TOKEN = "access_token"
-	CSRF_TOKEN   = "csrf_token"
+	ACCESS_TOKEN  = "access_token"
+	REFRESH_TOKEN = "refresh_token"
+	CSRF_TOKEN    = "csrf_token"
 )
 
-func CreateToken(c *fiber.Ctx, userId int64) (string, bool) {
+func CreateToken(c *fiber.Ctx, userId int64, sessionId string) (string, bool) {
 	privateKey, ok := getPrivateKey(c, ACCESS_TOKEN)
 	if !ok {
 		status.InternalServerError(c, nil)
 		return "", false
 	}
 
+	accessTokenLifetimeString := os.Getenv("ACCESS_TOKEN_LIFETIME_IN_MINUTE")
+	accessTokenLifetime, err := strconv.ParseInt(accessTokenLifetimeString, 10, 64)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return "", false
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(accessTokenLifetime) * time.Minute)
+
 	jwt.TimePrecision = time.Microsecond
 	claims := &jwt.RegisteredClaims{
-		ID:       utils.UUIDv4(),
-		Subject:  strconv.FormatInt(userId, 10),
-		IssuedAt: jwt.NewNumericDate(time.Now().UTC()),
+		ID:        sessionId,
+		Subject:   strconv.FormatInt(userId, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
 	}
 
 	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
@@ -45,6 +56,7 @@ func CreateToken(c *fiber.Ctx, userId int64) (string, bool) {
 		Secure:   true,
 		HTTPOnly: true,
 		Domain:   os.Getenv("HOST"),
+		Expires:  expiresAt,
 	})
 
 	return token, true
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	TOTP_SECRET_SIZE = 20
+	TOTP_PERIOD      = 30
+	TOTP_DIGITS      = 6
+	TOTP_SKEW        = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, TOTP_SECRET_SIZE)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+func GetTOTPURI(secret string, account string) string {
+	issuer := os.Getenv("TOTP_ISSUER")
+
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	values.Set("period", fmt.Sprint(TOTP_PERIOD))
+
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(account), values.Encode())
+}
+
+// RFC 6238 with the default RFC 4226 parameters: HMAC-SHA1, 6 digits, 30 seconds.
+func GenerateTOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range TOTP_DIGITS {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, value%modulo), nil
+}
+
+func GetTOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTP_PERIOD
+}
+
+// Returns the step matching the code, accepting one period of clock skew on each side.
+func MatchTOTPCode(secret string, code string) (int64, bool) {
+	currentStep := GetTOTPStep(time.Now().UTC())
+	for step := currentStep - TOTP_SKEW; step <= currentStep+TOTP_SKEW; step++ {
+		expectedCode, err := GenerateTOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expectedCode), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// Codes are single-use: a step is rejected once it or a later one has been accepted.
+func VerifyTOTPCode(c *fiber.Ctx, user queries.User, code string) bool {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return false
+	}
+	defer commit()
+
+	invalidCodeErr := errors.New("invalid code")
+
+	if user.TotpSecret == nil {
+		status.BadRequest(c, errors.New("totp is not configured"))
+		return false
+	}
+
+	step, ok := MatchTOTPCode(*user.TotpSecret, code)
+	if !ok {
+		status.Unauthorized(c, invalidCodeErr)
+		return false
+	}
+
+	updated, err := qtx.UseUserTOTPStep(ctx, queries.UseUserTOTPStepParams{
+		ID:   user.ID,
+		Step: step,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return false
+	}
+
+	if updated == 0 {
+		status.Unauthorized(c, invalidCodeErr)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/LeonardJouve/pass-secure/webauthn"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	WEBAUTHN_CHALLENGE_PREFIX = "webauthn:"
+	WEBAUTHN_REGISTRATION     = "registration"
+	WEBAUTHN_LOGIN            = "login"
+	MFA_METHOD_WEBAUTHN       = "webauthn"
+)
+
+type webauthnChallenge struct {
+	Purpose string `json:"purpose"`
+	UserID  int64  `json:"userId"`
+}
+
+func BeginWebAuthnRegistration(c *fiber.Ctx, user queries.User) (webauthn.CreationOptions, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return webauthn.CreationOptions{}, false
+	}
+	defer commit()
+
+	credentials, err := qtx.GetUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return webauthn.CreationOptions{}, false
+	}
+
+	credentialIds := make([][]byte, len(credentials))
+	for i, credential := range credentials {
+		credentialIds[i] = credential.CredentialID
+	}
+
+	challenge, ok := createWebAuthnChallenge(c, WEBAUTHN_REGISTRATION, user.ID)
+	if !ok {
+		return webauthn.CreationOptions{}, false
+	}
+
+	return getRelyingParty().NewCreationOptions(challenge, getUserHandle(user.ID), user.Email, user.Username, credentialIds), true
+}
+
+func FinishWebAuthnRegistration(c *fiber.Ctx, user queries.User, name string, response webauthn.AttestationResponse) (queries.WebauthnCredential, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return queries.WebauthnCredential{}, false
+	}
+	defer commit()
+
+	challenge, ok := consumeWebAuthnChallenge(c, response.ClientDataJSON, WEBAUTHN_REGISTRATION, user.ID)
+	if !ok {
+		return queries.WebauthnCredential{}, false
+	}
+
+	credential, err := getRelyingParty().VerifyRegistration(challenge, response, false)
+	if err != nil {
+		status.BadRequest(c, err)
+		return queries.WebauthnCredential{}, false
+	}
+
+	_, err = qtx.GetWebAuthnCredential(ctx, credential.ID)
+	if err == nil {
+		status.BadRequest(c, errors.New("credential already registered"))
+		return queries.WebauthnCredential{}, false
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		status.InternalServerError(c, nil)
+		return queries.WebauthnCredential{}, false
+	}
+
+	storedCredential, err := qtx.CreateWebAuthnCredential(ctx, queries.CreateWebAuthnCredentialParams{
+		UserID:       user.ID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Name:         name,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return queries.WebauthnCredential{}, false
+	}
+
+	return storedCredential, true
+}
+
+// A zero userId starts a passwordless login with discoverable credentials,
+// otherwise the assertion is a second factor restricted to that user's credentials.
+func BeginWebAuthnLogin(c *fiber.Ctx, userId int64) (webauthn.RequestOptions, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return webauthn.RequestOptions{}, false
+	}
+	defer commit()
+
+	userVerification := webauthn.USER_VERIFICATION_REQUIRED
+	var credentialIds [][]byte
+	if userId != 0 {
+		credentials, err := qtx.GetUserWebAuthnCredentials(ctx, userId)
+		if err != nil {
+			status.InternalServerError(c, nil)
+			return webauthn.RequestOptions{}, false
+		}
+
+		if len(credentials) == 0 {
+			status.BadRequest(c, errors.New("no webauthn credential registered"))
+			return webauthn.RequestOptions{}, false
+		}
+
+		credentialIds = make([][]byte, len(credentials))
+		for i, credential := range credentials {
+			credentialIds[i] = credential.CredentialID
+		}
+		userVerification = webauthn.USER_VERIFICATION_PREFERRED
+	}
+
+	challenge, ok := createWebAuthnChallenge(c, WEBAUTHN_LOGIN, userId)
+	if !ok {
+		return webauthn.RequestOptions{}, false
+	}
+
+	return getRelyingParty().NewRequestOptions(challenge, credentialIds, userVerification), true
+}
+
+// Passwordless logins require user verification so that the passkey alone
+// amounts to two factors.
+func FinishWebAuthnLogin(c *fiber.Ctx, userId int64, response webauthn.AssertionResponse) (int64, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		return 0, false
+	}
+	defer commit()
+
+	invalidCredentialErr := errors.New("invalid credential")
+
+	challenge, ok := consumeWebAuthnChallenge(c, response.ClientDataJSON, WEBAUTHN_LOGIN, userId)
+	if !ok {
+		return 0, false
+	}
+
+	credential, err := qtx.GetWebAuthnCredential(ctx, response.CredentialID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			status.Unauthorized(c, invalidCredentialErr)
+		} else {
+			status.InternalServerError(c, nil)
+		}
+
+		return 0, false
+	}
+
+	if userId != 0 && credential.UserID != userId {
+		status.Unauthorized(c, invalidCredentialErr)
+		return 0, false
+	}
+
+	if len(response.UserHandle) != 0 && !bytes.Equal(response.UserHandle, getUserHandle(credential.UserID)) {
+		status.Unauthorized(c, invalidCredentialErr)
+		return 0, false
+	}
+
+	signCount, err := getRelyingParty().VerifyAssertion(challenge, webauthn.Credential{
+		ID:        credential.CredentialID,
+		PublicKey: credential.PublicKey,
+		SignCount: uint32(credential.SignCount),
+	}, response, userId == 0)
+	if err != nil {
+		status.Unauthorized(c, err)
+		return 0, false
+	}
+
+	err = qtx.UseWebAuthnCredential(ctx, queries.UseWebAuthnCredentialParams{
+		ID:        credential.ID,
+		SignCount: int64(signCount),
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return 0, false
+	}
+
+	return credential.UserID, true
+}
+
+func getRelyingParty() webauthn.RelyingParty {
+	return webauthn.RelyingParty{
+		ID:      os.Getenv("WEBAUTHN_RP_ID"),
+		Name:    os.Getenv("WEBAUTHN_RP_NAME"),
+		Origins: strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ","),
+	}
+}
+
+func getUserHandle(userId int64) []byte {
+	return []byte(strconv.FormatInt(userId, 10))
+}
+
+func createWebAuthnChallenge(c *fiber.Ctx, purpose string, userId int64) ([]byte, bool) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	value, err := json.Marshal(webauthnChallenge{
+		Purpose: purpose,
+		UserID:  userId,
+	})
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	err = sessionStorage.Set(WEBAUTHN_CHALLENGE_PREFIX+webauthn.EncodeChallenge(challenge), value, webauthn.TIMEOUT)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	return challenge, true
+}
+
+// Challenges are single-use and bound to the ceremony and user they were issued for.
+func consumeWebAuthnChallenge(c *fiber.Ctx, clientDataJSON []byte, purpose string, userId int64) ([]byte, bool) {
+	invalidChallengeErr := errors.New("invalid challenge")
+
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		status.BadRequest(c, err)
+		return nil, false
+	}
+
+	key := WEBAUTHN_CHALLENGE_PREFIX + clientData.Challenge
+	value, err := sessionStorage.Get(key)
+	if err != nil || value == nil {
+		status.Unauthorized(c, invalidChallengeErr)
+		return nil, false
+	}
+	sessionStorage.Delete(key)
+
+	var storedChallenge webauthnChallenge
+	if err := json.Unmarshal(value, &storedChallenge); err != nil {
+		status.InternalServerError(c, nil)
+		return nil, false
+	}
+
+	if storedChallenge.Purpose != purpose || storedChallenge.UserID != userId {
+		status.Unauthorized(c, invalidChallengeErr)
+		return nil, false
+	}
+
+	challenge, err := base64.RawURLEncoding.DecodeString(clientData.Challenge)
+	if err != nil {
+		status.BadRequest(c, invalidChallengeErr)
+		return nil, false
+	}
+
+	return challenge, true
+}
@@ -0,0 +1,35 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+const (
+	DRIVER_LOCAL  = "local"
+	DRIVER_MEMORY = "memory"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Blobs are opaque to the store, attachments are encrypted client side before
+// being uploaded. Keys are generated by the server.
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader) (int64, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// The location is the root directory of the local driver and is ignored by the
+// memory driver, which keeps blobs until the process exits.
+func New(driver string, location string) (Store, error) {
+	switch driver {
+	case DRIVER_LOCAL:
+		return NewLocalStore(location)
+	case DRIVER_MEMORY:
+		return NewMemoryStore(), nil
+	default:
+		return nil, errors.New("unknown blob store driver")
+	}
+}
@@ -0,0 +1,80 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	blob := []byte("encrypted attachment")
+
+	size, err := store.Put(ctx, "attachment", bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != int64(len(blob)) {
+		t.Fatalf("unexpected size: %d", size)
+	}
+
+	reader, err := store.Get(ctx, "attachment")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(stored, blob) {
+		t.Fatalf("unexpected blob: %q", stored)
+	}
+
+	if err := store.Delete(ctx, "attachment"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get(ctx, "attachment"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted blob still found: %v", err)
+	}
+
+	if err := store.Delete(ctx, "attachment"); err != nil {
+		t.Fatalf("deleting a missing blob failed: %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, store)
+
+	invalidKeys := []string{"", "../attachment", "folder/attachment", "."}
+	for _, key := range invalidKeys {
+		if _, err := store.Put(context.Background(), key, bytes.NewReader(nil)); err == nil {
+			t.Errorf("invalid key accepted: %q", key)
+		}
+	}
+
+	entries, err := os.ReadDir(store.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("upload left files behind: %v", entries)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
@@ -0,0 +1,106 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	LOCAL_DIRECTORY_PERMISSIONS = 0o700
+	LOCAL_FILE_PERMISSIONS      = 0o600
+)
+
+var errInvalidKey = errors.New("invalid blob key")
+
+var keyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, LOCAL_DIRECTORY_PERMISSIONS); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{
+		root: root,
+	}, nil
+}
+
+// Blobs are written to a temporary file first so that a failed upload never
+// leaves a partial blob behind.
+func (s *LocalStore) Put(_ context.Context, key string, body io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+
+	size, err := io.Copy(file, body)
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	if err := file.Chmod(LOCAL_FILE_PERMISSIONS); err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (s *LocalStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !keyRegex.MatchString(key) {
+		return "", errInvalidKey
+	}
+
+	return filepath.Join(s.root, key), nil
+}
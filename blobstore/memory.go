@@ -0,0 +1,54 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+type MemoryStore struct {
+	sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		blobs: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Put(_ context.Context, key string, body io.Reader) (int64, error) {
+	blob, err := io.ReadAll(body)
+	if err != nil {
+		return 0, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.blobs[key] = blob
+
+	return int64(len(blob)), nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	blob, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(blob)), nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.blobs, key)
+
+	return nil
+}
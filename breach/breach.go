@@ -0,0 +1,299 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	PREFIX_LENGTH  = 5
+	PREFIX_COUNT   = 1 << (PREFIX_LENGTH * 4)
+	HASH_SIZE      = 20
+	RECORD_SIZE    = HASH_SIZE + 4
+	OFFSET_SIZE    = 8
+	INDEX_MAGIC    = "HIBPIDX1"
+	INDEX_HEADER   = len(INDEX_MAGIC) + (PREFIX_COUNT+1)*OFFSET_SIZE
+	HASH_HEX_SIZE  = HASH_SIZE * 2
+	RANGE_FILE_EXT = ".txt"
+)
+
+var (
+	ErrInvalidPrefix = errors.New("invalid hash prefix")
+	ErrInvalidIndex  = errors.New("invalid breach index")
+	ErrUnsorted      = errors.New("breach dataset is not sorted by hash")
+)
+
+type RangeEntry struct {
+	Suffix string
+	Count  uint32
+}
+
+// The index starts with the offset of the first record of each prefix, then
+// holds the records sorted by hash, each a SHA-1 hash followed by its count.
+// A range is read with a single read at its offset, the records are never
+// loaded in memory.
+type Index struct {
+	file    *os.File
+	offsets []uint64
+}
+
+type indexWriter struct {
+	writer     *bufio.Writer
+	offsets    []uint64
+	nextPrefix int
+	records    uint64
+	lastHash   []byte
+}
+
+func Open(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, INDEX_HEADER)
+	if _, err := io.ReadFull(file, header); err != nil || string(header[:len(INDEX_MAGIC)]) != INDEX_MAGIC {
+		file.Close()
+		return nil, ErrInvalidIndex
+	}
+
+	offsets := make([]uint64, PREFIX_COUNT+1)
+	for i := range offsets {
+		offsets[i] = binary.LittleEndian.Uint64(header[len(INDEX_MAGIC)+i*OFFSET_SIZE:])
+	}
+
+	return &Index{
+		file:    file,
+		offsets: offsets,
+	}, nil
+}
+
+func (index *Index) Close() error {
+	return index.file.Close()
+}
+
+// Returns the suffixes and counts of the hashes starting with the prefix, as
+// the k-anonymity range API of Have I Been Pwned.
+func (index *Index) Range(prefix string) ([]RangeEntry, error) {
+	prefixIndex, err := parsePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := index.offsets[prefixIndex], index.offsets[prefixIndex+1]
+	records := make([]byte, (end-start)*RECORD_SIZE)
+	if _, err := index.file.ReadAt(records, int64(INDEX_HEADER)+int64(start)*RECORD_SIZE); err != nil {
+		return nil, err
+	}
+
+	entries := make([]RangeEntry, end-start)
+	for i := range entries {
+		record := records[i*RECORD_SIZE : (i+1)*RECORD_SIZE]
+		entries[i] = RangeEntry{
+			Suffix: strings.ToUpper(hex.EncodeToString(record[:HASH_SIZE]))[PREFIX_LENGTH:],
+			Count:  binary.LittleEndian.Uint32(record[HASH_SIZE:]),
+		}
+	}
+
+	return entries, nil
+}
+
+// Returns how many times the SHA-1 hash appears in breaches, 0 when it does not.
+func (index *Index) Count(hash [HASH_SIZE]byte) (uint32, error) {
+	hexHash := strings.ToUpper(hex.EncodeToString(hash[:]))
+	entries, err := index.Range(hexHash[:PREFIX_LENGTH])
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		if entry.Suffix == hexHash[PREFIX_LENGTH:] {
+			return entry.Count, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// Builds the index from the dataset, either the file of hashes ordered by
+// hash or the directory of range files named after their prefix, as
+// downloaded by the Have I Been Pwned downloader. The index is written next
+// to its path and moved in place once complete.
+func BuildIndex(datasetPath string, indexPath string) error {
+	info, err := os.Stat(datasetPath)
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := indexPath + ".tmp"
+	file, err := os.Create(temporaryPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryPath)
+	defer file.Close()
+
+	writer := indexWriter{
+		writer:  bufio.NewWriter(file),
+		offsets: make([]uint64, PREFIX_COUNT+1),
+	}
+
+	// The offsets are only known once every record is written
+	if _, err := writer.writer.Write(make([]byte, INDEX_HEADER)); err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		err = writer.writeRangeDirectory(datasetPath)
+	} else {
+		err = writer.writeHashFile(datasetPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writer.writer.Flush(); err != nil {
+		return err
+	}
+	writer.setOffsets(PREFIX_COUNT)
+
+	header := make([]byte, INDEX_HEADER)
+	copy(header, INDEX_MAGIC)
+	for i, offset := range writer.offsets {
+		binary.LittleEndian.PutUint64(header[len(INDEX_MAGIC)+i*OFFSET_SIZE:], offset)
+	}
+
+	if _, err := file.WriteAt(header, 0); err != nil {
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, indexPath)
+}
+
+func (writer *indexWriter) writeHashFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writer.writeLines(file, "")
+}
+
+func (writer *indexWriter) writeRangeDirectory(path string) error {
+	names, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	// Directory entries are sorted by name, which sorts the prefixes
+	for _, name := range names {
+		prefix := strings.ToUpper(strings.TrimSuffix(name.Name(), RANGE_FILE_EXT))
+		if name.IsDir() {
+			continue
+		}
+
+		if _, err := parsePrefix(prefix); err != nil {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(path, name.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = writer.writeLines(file, prefix)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lines are a hash, or a suffix of the range prefix, and a count separated by
+// a colon.
+func (writer *indexWriter) writeLines(r io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		hexHash, countString, ok := strings.Cut(line, ":")
+		if !ok || len(prefix)+len(hexHash) != HASH_HEX_SIZE {
+			return errors.New("invalid breach dataset line: " + line)
+		}
+
+		hash, err := hex.DecodeString(prefix + hexHash)
+		if err != nil {
+			return err
+		}
+
+		count, err := strconv.ParseUint(countString, 10, 32)
+		if err != nil {
+			return err
+		}
+
+		if err := writer.writeRecord(hash, uint32(count)); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (writer *indexWriter) writeRecord(hash []byte, count uint32) error {
+	if writer.lastHash != nil && bytes.Compare(hash, writer.lastHash) <= 0 {
+		return ErrUnsorted
+	}
+	writer.lastHash = hash
+
+	writer.setOffsets(int(binary.BigEndian.Uint32(hash) >> (32 - PREFIX_LENGTH*4)))
+
+	record := make([]byte, RECORD_SIZE)
+	copy(record, hash)
+	binary.LittleEndian.PutUint32(record[HASH_SIZE:], count)
+	if _, err := writer.writer.Write(record); err != nil {
+		return err
+	}
+
+	writer.records++
+
+	return nil
+}
+
+// Prefixes up to the one of the next record start at it, those without hashes
+// are empty ranges.
+func (writer *indexWriter) setOffsets(prefixIndex int) {
+	for ; writer.nextPrefix <= prefixIndex; writer.nextPrefix++ {
+		writer.offsets[writer.nextPrefix] = writer.records
+	}
+}
+
+func parsePrefix(prefix string) (int, error) {
+	if len(prefix) != PREFIX_LENGTH {
+		return 0, ErrInvalidPrefix
+	}
+
+	prefixIndex, err := strconv.ParseUint(prefix, 16, 32)
+	if err != nil {
+		return 0, ErrInvalidPrefix
+	}
+
+	return int(prefixIndex), nil
+}
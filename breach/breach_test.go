@@ -0,0 +1,110 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// SHA-1 of "password" and "123456"
+const (
+	PASSWORD_HASH = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	NUMBERS_HASH  = "7C4A8D09CA3762AF61E59520943DC26494F8941B"
+)
+
+func TestIndex(t *testing.T) {
+	directory := t.TempDir()
+
+	datasetPath := filepath.Join(directory, "hashes.txt")
+	dataset := "00000000A8DAE4228F821FB418F59826079BF368:2\r\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n" +
+		"5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:1\r\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:37359195\r\n"
+	if err := os.WriteFile(datasetPath, []byte(dataset), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	indexPath := filepath.Join(directory, "index")
+	if err := BuildIndex(datasetPath, indexPath); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := Open(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	entries, err := index.Range("5baa6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Suffix != PASSWORD_HASH[PREFIX_LENGTH:] || entries[0].Count != 10434004 {
+		t.Fatalf("unexpected range: %+v", entries)
+	}
+
+	if entries, err := index.Range("FFFFF"); err != nil || len(entries) != 0 {
+		t.Fatalf("unexpected range: %+v %v", entries, err)
+	}
+
+	if count, err := index.Count(sha1.Sum([]byte("123456"))); err != nil || count != 37359195 {
+		t.Fatalf("unexpected count: %d %v", count, err)
+	}
+
+	if count, err := index.Count(sha1.Sum([]byte("correct horse battery staple"))); err != nil || count != 0 {
+		t.Fatalf("unexpected count: %d %v", count, err)
+	}
+
+	if _, err := index.Range("5BAAG"); !errors.Is(err, ErrInvalidPrefix) {
+		t.Fatalf("invalid prefix accepted: %v", err)
+	}
+}
+
+func TestIndexFromRanges(t *testing.T) {
+	directory := t.TempDir()
+
+	rangesPath := filepath.Join(directory, "ranges")
+	if err := os.Mkdir(rangesPath, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for prefix, content := range map[string]string{
+		PASSWORD_HASH[:PREFIX_LENGTH]: PASSWORD_HASH[PREFIX_LENGTH:] + ":10434004\n",
+		NUMBERS_HASH[:PREFIX_LENGTH]:  NUMBERS_HASH[PREFIX_LENGTH:] + ":37359195\n",
+	} {
+		if err := os.WriteFile(filepath.Join(rangesPath, prefix+RANGE_FILE_EXT), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	indexPath := filepath.Join(directory, "index")
+	if err := BuildIndex(rangesPath, indexPath); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := Open(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	if count, err := index.Count(sha1.Sum([]byte("password"))); err != nil || count != 10434004 {
+		t.Fatalf("unexpected count: %d %v", count, err)
+	}
+}
+
+func TestUnsortedDataset(t *testing.T) {
+	directory := t.TempDir()
+
+	datasetPath := filepath.Join(directory, "hashes.txt")
+	if err := os.WriteFile(datasetPath, []byte(NUMBERS_HASH+":1\n"+PASSWORD_HASH+":1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BuildIndex(datasetPath, filepath.Join(directory, "index")); !errors.Is(err, ErrUnsorted) {
+		t.Fatalf("unsorted dataset indexed: %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS kdf VARCHAR(16) NOT NULL DEFAULT 'pbkdf2',
+ADD COLUMN IF NOT EXISTS kdf_iterations INTEGER NOT NULL DEFAULT 600000,
+ADD COLUMN IF NOT EXISTS kdf_memory INTEGER NULL,
+ADD COLUMN IF NOT EXISTS kdf_parallelism INTEGER NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS key TEXT NULL;
+
+ALTER TABLE entries
+ALTER COLUMN name TYPE TEXT,
+ALTER COLUMN username TYPE TEXT,
+ALTER COLUMN password TYPE TEXT,
+ALTER COLUMN url TYPE TEXT,
+ADD COLUMN IF NOT EXISTS notes TEXT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS public_key TEXT NULL,
+ADD COLUMN IF NOT EXISTS private_key TEXT NULL;
+
+ALTER TABLE user_folders
+ADD COLUMN IF NOT EXISTS key TEXT NULL;
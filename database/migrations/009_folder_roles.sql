@@ -0,0 +1,35 @@
+ALTER TABLE user_folders
+ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'viewer',
+ADD COLUMN IF NOT EXISTS hide_passwords BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE user_folders
+SET role = 'owner'
+FROM folders
+WHERE folders.id = user_folders.folder_id AND folders.owner_id = user_folders.user_id AND user_folders.role != 'owner';
+
+CREATE OR REPLACE FUNCTION create_user_folder()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO user_folders(user_id, folder_id, role)
+    VALUES(NEW.owner_id, NEW.id, 'owner');
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION create_owner_user_folder()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.owner_id != NEW.owner_id THEN
+        UPDATE user_folders
+        SET role = 'manager'
+        WHERE user_id = OLD.owner_id AND folder_id = NEW.id;
+    END IF;
+
+    INSERT INTO user_folders (user_id, folder_id, role)
+    VALUES (NEW.owner_id, NEW.id, 'owner')
+    ON CONFLICT (user_id, folder_id) DO UPDATE SET role = 'owner';
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
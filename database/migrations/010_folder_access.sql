@@ -0,0 +1,198 @@
+-- Access granted on a folder cascades to its subfolders, an explicit
+-- user_folders row on a subfolder overrides the inherited role and flags.
+-- When a folder is reached from several granted ancestors, the path from the
+-- topmost one is kept since it went through every override on the way.
+DROP VIEW IF EXISTS folder_access;
+
+CREATE VIEW folder_access AS
+WITH RECURSIVE access AS (
+    SELECT
+        user_folders.user_id,
+        user_folders.folder_id,
+        user_folders.key,
+        user_folders.role::TEXT AS role,
+        user_folders.hide_passwords,
+        FALSE AS inherited,
+        0 AS depth
+    FROM user_folders
+
+    UNION ALL
+
+    SELECT
+        access.user_id,
+        folders.id,
+        COALESCE(user_folders.key, access.key),
+        COALESCE(user_folders.role::TEXT, access.role),
+        COALESCE(user_folders.hide_passwords, access.hide_passwords),
+        user_folders.folder_id IS NULL,
+        access.depth + 1
+    FROM access
+    JOIN folders ON folders.parent_id = access.folder_id
+    LEFT JOIN user_folders ON user_folders.user_id = access.user_id AND user_folders.folder_id = folders.id
+) CYCLE folder_id SET is_cycle USING path
+SELECT DISTINCT ON (user_id, folder_id)
+    user_id::BIGINT AS user_id,
+    folder_id::BIGINT AS folder_id,
+    key::TEXT AS key,
+    role::VARCHAR(16) AS role,
+    hide_passwords::BOOLEAN AS hide_passwords,
+    inherited::BOOLEAN AS inherited
+FROM access
+WHERE NOT is_cycle
+ORDER BY user_id, folder_id, depth DESC;
+
+CREATE OR REPLACE FUNCTION send_folder_upsert_notification()
+RETURNS trigger AS $$
+DECLARE
+    user_ids BIGINT[];
+BEGIN
+    SELECT ARRAY(
+        SELECT user_id
+        FROM folders_access(ARRAY[NEW.id])
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'folder_changed',
+                'id', NEW.id
+            )
+        )::text
+    );
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION send_folder_delete_notification()
+RETURNS trigger AS $$
+DECLARE
+    user_ids BIGINT[];
+BEGIN
+    SELECT ARRAY(
+        SELECT user_id
+        FROM folders_access(ARRAY[OLD.id])
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'folder_deleted',
+                'id', OLD.id
+            )
+        )::text
+    );
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION send_entry_upsert_notification()
+RETURNS trigger AS $$
+DECLARE
+    user_ids BIGINT[];
+BEGIN
+    SELECT ARRAY(
+        SELECT user_id
+        FROM folders_access(ARRAY[NEW.folder_id])
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'entry_changed',
+                'id', NEW.id
+            )
+        )::text
+    );
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION send_entry_delete_notification()
+RETURNS trigger AS $$
+DECLARE
+    user_ids BIGINT[];
+BEGIN
+    SELECT ARRAY(
+        SELECT user_id
+        FROM folders_access(ARRAY[OLD.folder_id])
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'entry_deleted',
+                'id', OLD.id
+            )
+        )::text
+    );
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The member is notified along with everyone who has access to the folder,
+-- even when the change removed their own access.
+CREATE OR REPLACE FUNCTION send_user_folders_upsert_notification()
+RETURNS trigger AS $$
+DECLARE
+    user_ids BIGINT[];
+BEGIN
+    SELECT ARRAY(
+        SELECT access.user_id
+        FROM folders_access(ARRAY[NEW.folder_id]) AS access
+        UNION
+        SELECT NEW.user_id
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'folder_changed',
+                'id', NEW.folder_id
+            )
+        )::text
+    );
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION send_user_folders_delete_notification()
+RETURNS trigger AS $$
+DECLARE
+    user_ids BIGINT[];
+BEGIN
+    SELECT ARRAY(
+        SELECT access.user_id
+        FROM folders_access(ARRAY[OLD.folder_id]) AS access
+        UNION
+        SELECT OLD.user_id
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'folder_changed',
+                'id', OLD.folder_id
+            )
+        )::text
+    );
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
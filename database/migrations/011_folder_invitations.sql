@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS folder_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    folder_id BIGINT NOT NULL,
+    inviter_id BIGINT NOT NULL,
+    invitee_email VARCHAR(128) NOT NULL,
+    invitee_id BIGINT NULL,
+    role VARCHAR(16) NOT NULL,
+    hide_passwords BOOLEAN NOT NULL DEFAULT FALSE,
+    token VARCHAR(64) UNIQUE NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT folder_invitations_folder_fk FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    CONSTRAINT folder_invitations_inviter_fk FOREIGN KEY (inviter_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT folder_invitations_invitee_fk FOREIGN KEY (invitee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS folder_invitations_folder_idx ON folder_invitations(folder_id);
+CREATE INDEX IF NOT EXISTS folder_invitations_invitee_idx ON folder_invitations(invitee_id);
+CREATE INDEX IF NOT EXISTS folder_invitations_invitee_email_idx ON folder_invitations(invitee_email);
+
+-- The invitee and the folder managers follow the invitation lifecycle.
+CREATE OR REPLACE FUNCTION send_folder_invitation_notification()
+RETURNS trigger AS $$
+DECLARE
+    invitation folder_invitations;
+    user_ids BIGINT[];
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        invitation := OLD;
+    ELSE
+        invitation := NEW;
+    END IF;
+
+    SELECT ARRAY(
+        SELECT access.user_id
+        FROM folders_access(ARRAY[invitation.folder_id]) AS access
+        WHERE access.role IN ('manager', 'owner')
+        UNION
+        SELECT invitation.invitee_id
+        WHERE invitation.invitee_id IS NOT NULL
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', CASE WHEN TG_OP = 'DELETE' THEN 'invitation_deleted' ELSE 'invitation_changed' END,
+                'id', invitation.id
+            )
+        )::text
+    );
+
+    RETURN invitation;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER folder_invitation_notifications
+AFTER INSERT OR UPDATE OR DELETE ON folder_invitations
+FOR EACH ROW
+EXECUTE FUNCTION send_folder_invitation_notification();
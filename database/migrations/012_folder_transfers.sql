@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS folder_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    folder_id BIGINT UNIQUE NOT NULL,
+    from_user_id BIGINT NOT NULL,
+    to_user_id BIGINT NOT NULL,
+    subtree BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT folder_transfers_folder_fk FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    CONSTRAINT folder_transfers_from_user_fk FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT folder_transfers_to_user_fk FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS folder_transfers_to_user_idx ON folder_transfers(to_user_id);
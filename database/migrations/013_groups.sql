@@ -0,0 +1,375 @@
+CREATE TABLE IF NOT EXISTS groups (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    owner_id BIGINT NOT NULL,
+    parent_id BIGINT NULL,
+    parent_key TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT groups_owner_fk FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT groups_parent_fk FOREIGN KEY (parent_id) REFERENCES groups(id) ON DELETE SET NULL
+);
+
+-- The key is the group key wrapped with the member's public key.
+CREATE TABLE IF NOT EXISTS group_users (
+    group_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    key TEXT NOT NULL,
+    CONSTRAINT group_users_pk PRIMARY KEY (group_id, user_id),
+    CONSTRAINT group_users_group_fk FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT group_users_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- The key is the folder key encrypted with the group key.
+CREATE TABLE IF NOT EXISTS group_folders (
+    group_id BIGINT NOT NULL,
+    folder_id BIGINT NOT NULL,
+    key TEXT NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'viewer',
+    hide_passwords BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT group_folders_pk PRIMARY KEY (group_id, folder_id),
+    CONSTRAINT group_folders_group_fk FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT group_folders_folder_fk FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS groups_parent_idx ON groups(parent_id);
+CREATE INDEX IF NOT EXISTS group_users_user_idx ON group_users(user_id);
+CREATE INDEX IF NOT EXISTS group_folders_folder_idx ON group_folders(folder_id);
+
+-- The members of a subgroup are members of every group above it.
+DROP VIEW IF EXISTS folder_access;
+DROP VIEW IF EXISTS group_membership;
+
+CREATE VIEW group_membership AS
+WITH RECURSIVE membership AS (
+    SELECT
+        group_users.user_id,
+        group_users.group_id,
+        FALSE AS inherited
+    FROM group_users
+
+    UNION ALL
+
+    SELECT
+        membership.user_id,
+        groups.parent_id,
+        TRUE
+    FROM membership
+    JOIN groups ON groups.id = membership.group_id
+    WHERE groups.parent_id IS NOT NULL
+) CYCLE group_id SET is_cycle USING path
+SELECT DISTINCT ON (user_id, group_id)
+    user_id::BIGINT AS user_id,
+    group_id::BIGINT AS group_id,
+    inherited::BOOLEAN AS inherited
+FROM membership
+WHERE NOT is_cycle
+ORDER BY user_id, group_id, inherited;
+
+-- Direct and group grants cascade down the folder tree separately, each one
+-- overridden by the explicit grant of the same user or group on a subfolder.
+-- The effective access is their union: the highest role, passwords hidden
+-- only when every grant hides them, and the direct key preferred on a tie.
+-- It is computed for a single user, seeded with their own grants and those of
+-- their groups, or for a set of folders, seeded with the grants on their
+-- ancestors and cascaded along that path only.
+CREATE OR REPLACE FUNCTION user_folder_access(target_user_id BIGINT)
+RETURNS TABLE (
+    folder_id BIGINT,
+    key TEXT,
+    group_id BIGINT,
+    role VARCHAR(16),
+    hide_passwords BOOLEAN,
+    inherited BOOLEAN
+) AS $$
+WITH RECURSIVE membership AS (
+    SELECT group_users.group_id
+    FROM group_users
+    WHERE group_users.user_id = target_user_id
+
+    UNION
+
+    SELECT groups.parent_id
+    FROM membership
+    JOIN groups ON groups.id = membership.group_id
+    WHERE groups.parent_id IS NOT NULL
+), grants AS (
+    SELECT
+        NULL::BIGINT AS group_id,
+        user_folders.folder_id,
+        user_folders.key,
+        user_folders.role::TEXT AS role,
+        user_folders.hide_passwords
+    FROM user_folders
+    WHERE user_folders.user_id = target_user_id
+
+    UNION ALL
+
+    SELECT
+        group_folders.group_id,
+        group_folders.folder_id,
+        group_folders.key,
+        group_folders.role::TEXT,
+        group_folders.hide_passwords
+    FROM group_folders
+    JOIN membership ON membership.group_id = group_folders.group_id
+), access AS (
+    SELECT
+        grants.group_id,
+        grants.folder_id,
+        grants.key,
+        grants.role,
+        grants.hide_passwords,
+        FALSE AS inherited,
+        0 AS depth
+    FROM grants
+
+    UNION ALL
+
+    SELECT
+        access.group_id,
+        folders.id,
+        COALESCE(grants.key, access.key),
+        COALESCE(grants.role, access.role),
+        COALESCE(grants.hide_passwords, access.hide_passwords),
+        grants.folder_id IS NULL,
+        access.depth + 1
+    FROM access
+    JOIN folders ON folders.parent_id = access.folder_id
+    LEFT JOIN grants ON grants.group_id IS NOT DISTINCT FROM access.group_id AND grants.folder_id = folders.id
+) CYCLE folder_id SET is_cycle USING path,
+sources AS (
+    SELECT DISTINCT ON (access.group_id, access.folder_id)
+        access.group_id,
+        access.folder_id,
+        access.key,
+        access.role,
+        access.hide_passwords,
+        access.inherited
+    FROM access
+    WHERE NOT access.is_cycle
+    ORDER BY access.group_id, access.folder_id, access.depth DESC
+)
+SELECT DISTINCT ON (sources.folder_id)
+    sources.folder_id::BIGINT,
+    sources.key::TEXT,
+    sources.group_id::BIGINT,
+    sources.role::VARCHAR(16),
+    (BOOL_AND(sources.hide_passwords) OVER (PARTITION BY sources.folder_id))::BOOLEAN,
+    sources.inherited::BOOLEAN
+FROM sources
+ORDER BY sources.folder_id, ARRAY_POSITION(ARRAY['viewer', 'editor', 'manager', 'owner'], sources.role) DESC, sources.group_id NULLS FIRST;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION folders_access(target_folder_ids BIGINT[])
+RETURNS TABLE (
+    user_id BIGINT,
+    folder_id BIGINT,
+    key TEXT,
+    group_id BIGINT,
+    role VARCHAR(16),
+    hide_passwords BOOLEAN,
+    inherited BOOLEAN
+) AS $$
+WITH RECURSIVE ancestors AS (
+    SELECT folders.id, folders.parent_id
+    FROM folders
+    WHERE folders.id = ANY(target_folder_ids)
+
+    UNION
+
+    SELECT folders.id, folders.parent_id
+    FROM ancestors
+    JOIN folders ON folders.id = ancestors.parent_id
+), granted_groups AS (
+    -- The members of a subgroup are members of every group above it
+    SELECT
+        group_folders.group_id AS granted_group_id,
+        group_folders.group_id
+    FROM group_folders
+    WHERE group_folders.folder_id IN (SELECT ancestors.id FROM ancestors)
+
+    UNION
+
+    SELECT
+        granted_groups.granted_group_id,
+        groups.id
+    FROM granted_groups
+    JOIN groups ON groups.parent_id = granted_groups.group_id
+), membership AS (
+    SELECT DISTINCT
+        group_users.user_id,
+        granted_groups.granted_group_id AS group_id
+    FROM granted_groups
+    JOIN group_users ON group_users.group_id = granted_groups.group_id
+), grants AS (
+    SELECT
+        user_folders.user_id,
+        NULL::BIGINT AS group_id,
+        user_folders.folder_id,
+        user_folders.key,
+        user_folders.role::TEXT AS role,
+        user_folders.hide_passwords
+    FROM user_folders
+    WHERE user_folders.folder_id IN (SELECT ancestors.id FROM ancestors)
+
+    UNION ALL
+
+    SELECT
+        membership.user_id,
+        group_folders.group_id,
+        group_folders.folder_id,
+        group_folders.key,
+        group_folders.role::TEXT,
+        group_folders.hide_passwords
+    FROM group_folders
+    JOIN membership ON membership.group_id = group_folders.group_id
+    WHERE group_folders.folder_id IN (SELECT ancestors.id FROM ancestors)
+), access AS (
+    SELECT
+        grants.user_id,
+        grants.group_id,
+        grants.folder_id,
+        grants.key,
+        grants.role,
+        grants.hide_passwords,
+        FALSE AS inherited,
+        0 AS depth
+    FROM grants
+
+    UNION ALL
+
+    SELECT
+        access.user_id,
+        access.group_id,
+        folders.id,
+        COALESCE(grants.key, access.key),
+        COALESCE(grants.role, access.role),
+        COALESCE(grants.hide_passwords, access.hide_passwords),
+        grants.folder_id IS NULL,
+        access.depth + 1
+    FROM access
+    JOIN folders ON folders.parent_id = access.folder_id AND folders.id IN (SELECT ancestors.id FROM ancestors)
+    LEFT JOIN grants ON grants.user_id = access.user_id AND grants.group_id IS NOT DISTINCT FROM access.group_id AND grants.folder_id = folders.id
+) CYCLE folder_id SET is_cycle USING path,
+sources AS (
+    SELECT DISTINCT ON (access.user_id, access.group_id, access.folder_id)
+        access.user_id,
+        access.group_id,
+        access.folder_id,
+        access.key,
+        access.role,
+        access.hide_passwords,
+        access.inherited
+    FROM access
+    WHERE NOT access.is_cycle AND access.folder_id = ANY(target_folder_ids)
+    ORDER BY access.user_id, access.group_id, access.folder_id, access.depth DESC
+)
+SELECT DISTINCT ON (sources.user_id, sources.folder_id)
+    sources.user_id::BIGINT,
+    sources.folder_id::BIGINT,
+    sources.key::TEXT,
+    sources.group_id::BIGINT,
+    sources.role::VARCHAR(16),
+    (BOOL_AND(sources.hide_passwords) OVER (PARTITION BY sources.user_id, sources.folder_id))::BOOLEAN,
+    sources.inherited::BOOLEAN
+FROM sources
+ORDER BY sources.user_id, sources.folder_id, ARRAY_POSITION(ARRAY['viewer', 'editor', 'manager', 'owner'], sources.role) DESC, sources.group_id NULLS FIRST;
+$$ LANGUAGE sql STABLE;
+
+-- Filtering the view on user_id looks the user up first, computing the access
+-- of that user only. Filtering on folder_id alone computes every user, use
+-- folders_access instead.
+CREATE VIEW folder_access AS
+SELECT
+    users.id AS user_id,
+    access.folder_id,
+    access.key,
+    access.group_id,
+    access.role,
+    access.hide_passwords,
+    access.inherited
+FROM users
+CROSS JOIN LATERAL user_folder_access(users.id) AS access;
+
+-- The group members are notified along with everyone who has access to the
+-- folder, even when the change removed their own access.
+CREATE OR REPLACE FUNCTION send_group_folders_notification()
+RETURNS trigger AS $$
+DECLARE
+    group_folder group_folders;
+    user_ids BIGINT[];
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        group_folder := OLD;
+    ELSE
+        group_folder := NEW;
+    END IF;
+
+    SELECT ARRAY(
+        SELECT access.user_id
+        FROM folders_access(ARRAY[group_folder.folder_id]) AS access
+        UNION
+        SELECT group_membership.user_id
+        FROM group_membership
+        WHERE group_membership.group_id = group_folder.group_id
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'folder_changed',
+                'id', group_folder.folder_id
+            )
+        )::text
+    );
+
+    RETURN group_folder;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER group_folders_notifications
+AFTER INSERT OR UPDATE OR DELETE ON group_folders
+FOR EACH ROW
+EXECUTE FUNCTION send_group_folders_notification();
+
+CREATE OR REPLACE FUNCTION send_group_users_notification()
+RETURNS trigger AS $$
+DECLARE
+    group_user group_users;
+    user_ids BIGINT[];
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        group_user := OLD;
+    ELSE
+        group_user := NEW;
+    END IF;
+
+    SELECT ARRAY(
+        SELECT group_membership.user_id
+        FROM group_membership
+        WHERE group_membership.group_id = group_user.group_id
+        UNION
+        SELECT group_user.user_id
+    ) INTO user_ids;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', user_ids,
+            'message', json_build_object(
+                'event', 'group_changed',
+                'id', group_user.group_id
+            )
+        )::text
+    );
+
+    RETURN group_user;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER group_users_notifications
+AFTER INSERT OR UPDATE OR DELETE ON group_users
+FOR EACH ROW
+EXECUTE FUNCTION send_group_users_notification();
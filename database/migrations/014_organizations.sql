@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_users (
+    organization_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT organization_users_pk PRIMARY KEY (organization_id, user_id),
+    CONSTRAINT organization_users_organization_fk FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT organization_users_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- The master password strength is a score from 0 to 4, 0 sets no minimum.
+CREATE TABLE IF NOT EXISTS organization_policies (
+    organization_id BIGINT PRIMARY KEY,
+    require_two_factor BOOLEAN NOT NULL DEFAULT FALSE,
+    min_password_strength SMALLINT NOT NULL DEFAULT 0,
+    disable_personal_export BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT organization_policies_organization_fk FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS organization_users_user_idx ON organization_users(user_id);
+
+-- Organization folders belong to the organization, owner_id only designates
+-- the member holding the owner role and is handed over when they leave.
+ALTER TABLE folders
+ADD COLUMN IF NOT EXISTS organization_id BIGINT NULL REFERENCES organizations(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS folders_organization_idx ON folders(organization_id);
@@ -0,0 +1,19 @@
+-- A revision holds the values of an entry before an update along with the
+-- fields the update changed. Fields are compared as encrypted strings, so a
+-- field re-encrypted by the client counts as changed.
+CREATE TABLE IF NOT EXISTS entry_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL,
+    user_id BIGINT NULL,
+    changed_fields TEXT[] NOT NULL,
+    name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    url TEXT NULL,
+    notes TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT entry_revisions_entry_fk FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE,
+    CONSTRAINT entry_revisions_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS entry_revisions_entry_idx ON entry_revisions(entry_id);
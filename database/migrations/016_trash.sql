@@ -0,0 +1,8 @@
+-- Folders and entries moved to the trash keep their place in the tree. A
+-- trashed folder takes its whole subtree with it, every row sharing the same
+-- deleted_at so it can be restored as one.
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE entries ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS folders_deleted_at_idx ON folders(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS entries_deleted_at_idx ON entries(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Entries of every type share the name and notes, logins keep their username,
+-- password and url columns while the other types store their encrypted
+-- fields in a JSON object.
+ALTER TABLE entries
+ADD COLUMN IF NOT EXISTS type VARCHAR(16) NOT NULL DEFAULT 'login',
+ADD COLUMN IF NOT EXISTS fields JSONB NULL,
+ALTER COLUMN username DROP NOT NULL,
+ALTER COLUMN password DROP NOT NULL;
+
+ALTER TABLE entry_revisions
+ADD COLUMN IF NOT EXISTS fields JSONB NULL,
+ALTER COLUMN username DROP NOT NULL,
+ALTER COLUMN password DROP NOT NULL;
@@ -0,0 +1,20 @@
+-- Both lists keep the order given by the client. The uri of each URI and the
+-- name and value of each custom field are encrypted client side, their match
+-- strategy and type are not.
+ALTER TABLE entries
+ADD COLUMN IF NOT EXISTS uris JSONB NULL,
+ADD COLUMN IF NOT EXISTS custom_fields JSONB NULL;
+
+ALTER TABLE entry_revisions
+ADD COLUMN IF NOT EXISTS uris JSONB NULL,
+ADD COLUMN IF NOT EXISTS custom_fields JSONB NULL;
+
+-- The url becomes the first URI. The column is left empty rather than
+-- dropped since earlier migrations still alter it.
+UPDATE entries
+SET uris = JSONB_BUILD_ARRAY(JSONB_BUILD_OBJECT('uri', url)), url = NULL
+WHERE url IS NOT NULL;
+
+UPDATE entry_revisions
+SET uris = JSONB_BUILD_ARRAY(JSONB_BUILD_OBJECT('uri', url)), url = NULL
+WHERE url IS NOT NULL;
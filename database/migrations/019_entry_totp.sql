@@ -0,0 +1,7 @@
+-- The TOTP secret, issuer and account are encrypted client side, which
+-- generates the codes. Only the algorithm, digits and period are readable.
+ALTER TABLE entries
+ADD COLUMN IF NOT EXISTS totp JSONB NULL;
+
+ALTER TABLE entry_revisions
+ADD COLUMN IF NOT EXISTS totp JSONB NULL;
@@ -0,0 +1,42 @@
+-- The file is encrypted client side with the attachment key, itself encrypted
+-- with the vault key like the name. The size counts against the quota of the
+-- user who uploaded it.
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL,
+    user_id BIGINT NULL,
+    name TEXT NOT NULL,
+    key TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    blob_key VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT attachments_entry_fk FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE,
+    CONSTRAINT attachments_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS attachments_entry_idx ON attachments(entry_id);
+CREATE INDEX IF NOT EXISTS attachments_user_idx ON attachments(user_id);
+
+-- Attachments are also deleted through the entry, folder and user cascades,
+-- their blobs are queued here and removed from the blob store in the
+-- background.
+CREATE TABLE IF NOT EXISTS deleted_attachment_blobs (
+    blob_key VARCHAR(64) PRIMARY KEY,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION queue_attachment_blob_deletion()
+RETURNS trigger AS $$
+BEGIN
+    INSERT INTO deleted_attachment_blobs(blob_key)
+    VALUES (OLD.blob_key)
+    ON CONFLICT (blob_key) DO NOTHING;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER queue_attachment_blob_deletion
+AFTER DELETE ON attachments
+FOR EACH ROW
+EXECUTE FUNCTION queue_attachment_blob_deletion();
@@ -0,0 +1,4 @@
+-- Shared export covers the folders of the organization, personal export the
+-- whole vault of its members.
+ALTER TABLE organization_policies
+ADD COLUMN IF NOT EXISTS disable_shared_export BOOLEAN NOT NULL DEFAULT FALSE;
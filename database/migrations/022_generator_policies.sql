@@ -0,0 +1,13 @@
+-- Generator policies clamp the options of generated passwords and passphrases,
+-- their defaults leave the options untouched.
+ALTER TABLE organization_policies
+ADD COLUMN IF NOT EXISTS generator_min_length SMALLINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS generator_require_uppercase BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS generator_require_lowercase BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS generator_require_digits BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS generator_require_symbols BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS generator_min_digits SMALLINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS generator_min_symbols SMALLINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS generator_min_word_count SMALLINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS generator_capitalize BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS generator_include_number BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,46 @@
+-- Members join a group once they accept its invitation. The key is the group
+-- key wrapped with the invitee's public key and moves to group_users on accept.
+CREATE TABLE IF NOT EXISTS group_invitations (
+    group_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    inviter_id BIGINT NOT NULL,
+    key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT group_invitations_pk PRIMARY KEY (group_id, user_id),
+    CONSTRAINT group_invitations_group_fk FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT group_invitations_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT group_invitations_inviter_fk FOREIGN KEY (inviter_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS group_invitations_user_idx ON group_invitations(user_id);
+
+CREATE OR REPLACE FUNCTION send_group_invitation_notification()
+RETURNS trigger AS $$
+DECLARE
+    invitation group_invitations;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        invitation := OLD;
+    ELSE
+        invitation := NEW;
+    END IF;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', ARRAY[invitation.user_id, invitation.inviter_id],
+            'message', json_build_object(
+                'event', CASE WHEN TG_OP = 'DELETE' THEN 'group_invitation_deleted' ELSE 'group_invitation_changed' END,
+                'id', invitation.group_id
+            )
+        )::text
+    );
+
+    RETURN invitation;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER group_invitation_notifications
+AFTER INSERT OR UPDATE OR DELETE ON group_invitations
+FOR EACH ROW
+EXECUTE FUNCTION send_group_invitation_notification();
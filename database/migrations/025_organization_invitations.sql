@@ -0,0 +1,49 @@
+-- Users join an organization, and fall under its policies, once they accept
+-- its invitation. Invitations are addressed to an email and claimed when the
+-- invitee registers, like folder invitations.
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    organization_id BIGINT NOT NULL,
+    invitee_email VARCHAR(128) NOT NULL,
+    user_id BIGINT NULL,
+    inviter_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT organization_invitations_pk PRIMARY KEY (organization_id, invitee_email),
+    CONSTRAINT organization_invitations_organization_fk FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT organization_invitations_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT organization_invitations_inviter_fk FOREIGN KEY (inviter_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS organization_invitations_user_idx ON organization_invitations(user_id);
+CREATE INDEX IF NOT EXISTS organization_invitations_invitee_email_idx ON organization_invitations(invitee_email);
+
+CREATE OR REPLACE FUNCTION send_organization_invitation_notification()
+RETURNS trigger AS $$
+DECLARE
+    invitation organization_invitations;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        invitation := OLD;
+    ELSE
+        invitation := NEW;
+    END IF;
+
+    PERFORM pg_notify(
+        'websocket_events',
+        json_build_object(
+            'user_ids', ARRAY_REMOVE(ARRAY[invitation.user_id, invitation.inviter_id], NULL),
+            'message', json_build_object(
+                'event', CASE WHEN TG_OP = 'DELETE' THEN 'organization_invitation_deleted' ELSE 'organization_invitation_changed' END,
+                'id', invitation.organization_id
+            )
+        )::text
+    );
+
+    RETURN invitation;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER organization_invitation_notifications
+AFTER INSERT OR UPDATE OR DELETE ON organization_invitations
+FOR EACH ROW
+EXECUTE FUNCTION send_organization_invitation_notification();
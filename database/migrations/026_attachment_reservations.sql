@@ -0,0 +1,11 @@
+-- Space reserved by the uploads in progress, counted in the quota until the
+-- attachment is created. Reservations left by interrupted uploads expire.
+CREATE TABLE IF NOT EXISTS attachment_reservations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT attachment_reservations_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS attachment_reservations_user_idx ON attachment_reservations(user_id);
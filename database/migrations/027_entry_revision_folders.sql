@@ -0,0 +1,14 @@
+-- Revisions are encrypted with the key of the folder the entry was in, they
+-- can only be restored while the entry is still in that folder. Revisions
+-- recorded before this column existed are assumed to belong to the current
+-- folder of their entry.
+ALTER TABLE entry_revisions
+ADD COLUMN IF NOT EXISTS folder_id BIGINT NULL;
+
+UPDATE entry_revisions
+SET folder_id = entries.folder_id
+FROM entries
+WHERE entries.id = entry_revisions.entry_id AND entry_revisions.folder_id IS NULL;
+
+ALTER TABLE entry_revisions
+ALTER COLUMN folder_id SET NOT NULL;
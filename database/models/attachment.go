@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedAttachment struct {
+	ID        int64     `json:"id"`
+	EntryID   int64     `json:"entryId"`
+	UserID    *int64    `json:"userId"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func SanitizeAttachment(_ *fiber.Ctx, attachment *queries.Attachment) SanitizedAttachment {
+	return SanitizedAttachment{
+		ID:        attachment.ID,
+		EntryID:   attachment.EntryID,
+		UserID:    attachment.UserID,
+		Name:      attachment.Name,
+		Key:       attachment.Key,
+		Size:      attachment.Size,
+		CreatedAt: attachment.CreatedAt.Time,
+	}
+}
+
+func SanitizeAttachments(c *fiber.Ctx, attachments *[]queries.Attachment) []SanitizedAttachment {
+	sanitizedAttachments := make([]SanitizedAttachment, len(*attachments))
+	for i, attachment := range *attachments {
+		sanitizedAttachments[i] = SanitizeAttachment(c, &attachment)
+	}
+
+	return sanitizedAttachments
+}
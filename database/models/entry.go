@@ -1,31 +1,233 @@
 package models
 
 import (
+	"encoding/json"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
 	"github.com/gofiber/fiber/v2"
 )
 
+const ENTRY_CUSTOM_FIELD_HIDDEN = "hidden"
+
+// The type specific fields holding secrets, left out along with passwords.
+var EntrySecretFields = []string{"number", "code", "ssn", "passportNumber", "licenseNumber", "privateKey", "secret"}
+
+type SanitizedEntryTOTP struct {
+	Secret    *string `json:"secret"`
+	Algorithm string  `json:"algorithm"`
+	Digits    int32   `json:"digits"`
+	Period    int32   `json:"period"`
+	Issuer    *string `json:"issuer"`
+	Account   *string `json:"account"`
+}
+
+type SanitizedEntryURI struct {
+	URI   string  `json:"uri"`
+	Match *string `json:"match"`
+}
+
+type SanitizedEntryCustomField struct {
+	Type  string  `json:"type"`
+	Name  string  `json:"name"`
+	Value *string `json:"value"`
+}
+
 type SanitizedEntry struct {
-	ID       int64  `json:"id"`
-	Name     string `json:"name"`
-	Password string `json:"password"`
-	FolderID int64  `json:"folderId"`
+	ID           int64                       `json:"id"`
+	Type         string                      `json:"type"`
+	Name         string                      `json:"name"`
+	Username     *string                     `json:"username"`
+	Password     *string                     `json:"password"`
+	TOTP         *SanitizedEntryTOTP         `json:"totp"`
+	Notes        *string                     `json:"notes"`
+	URIs         []SanitizedEntryURI         `json:"uris"`
+	CustomFields []SanitizedEntryCustomField `json:"customFields"`
+	Fields       map[string]string           `json:"fields"`
+	FolderID     int64                       `json:"folderId"`
+	DeletedAt    *time.Time                  `json:"deletedAt"`
 }
 
-func SanitizeEntry(_ *fiber.Ctx, entry *queries.Entry) SanitizedEntry {
-	return SanitizedEntry{
-		ID:       entry.ID,
-		Name:     entry.Name,
-		Password: entry.Password,
-		FolderID: entry.FolderID,
+func SanitizeEntry(c *fiber.Ctx, entry *queries.Entry) (SanitizedEntry, bool) {
+	sanitizedEntries, ok := SanitizeEntries(c, &[]queries.Entry{*entry})
+	if !ok {
+		return SanitizedEntry{}, false
 	}
+
+	return sanitizedEntries[0], true
 }
 
-func SanitizeEntries(c *fiber.Ctx, entries *[]queries.Entry) []SanitizedEntry {
+// Passwords, TOTP secrets, secret fields and hidden custom field values are
+// left out for members whose access to the folder hides them.
+func SanitizeEntries(c *fiber.Ctx, entries *[]queries.Entry) ([]SanitizedEntry, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		status.InternalServerError(c, nil)
+		return []SanitizedEntry{}, false
+	}
+	defer commit()
+
+	user, _ := c.Locals("user").(queries.User)
+
+	folderAccesses, err := qtx.GetUserFolderAccesses(ctx, user.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return []SanitizedEntry{}, false
+	}
+
+	hidePasswordsByFolder := make(map[int64]bool)
+	for _, folderAccess := range folderAccesses {
+		hidePasswordsByFolder[folderAccess.FolderID] = folderAccess.HidePasswords
+	}
+
 	sanitizedEntries := make([]SanitizedEntry, len(*entries))
 	for i, entry := range *entries {
-		sanitizedEntries[i] = SanitizeEntry(c, &entry)
+		sanitizedEntries[i] = SanitizedEntry{
+			ID:           entry.ID,
+			Type:         entry.Type,
+			Name:         entry.Name,
+			Username:     entry.Username,
+			Password:     entry.Password,
+			TOTP:         SanitizeEntryTOTP(entry.Totp, hidePasswordsByFolder[entry.FolderID]),
+			Notes:        entry.Notes,
+			URIs:         sanitizeEntryURIs(entry.Uris),
+			CustomFields: sanitizeEntryCustomFields(entry.CustomFields, hidePasswordsByFolder[entry.FolderID]),
+			Fields:       sanitizeEntryFields(entry.Fields, hidePasswordsByFolder[entry.FolderID]),
+			FolderID:     entry.FolderID,
+		}
+
+		if entry.DeletedAt.Valid {
+			sanitizedEntries[i].DeletedAt = &entry.DeletedAt.Time
+		}
+
+		if hidePasswordsByFolder[entry.FolderID] {
+			sanitizedEntries[i].Password = nil
+		}
+	}
+
+	return sanitizedEntries, true
+}
+
+type SanitizedEntryRevision struct {
+	ID            int64                       `json:"id"`
+	EntryID       int64                       `json:"entryId"`
+	FolderID      int64                       `json:"folderId"`
+	UserID        *int64                      `json:"userId"`
+	ChangedFields []string                    `json:"changedFields"`
+	Name          string                      `json:"name"`
+	Username      *string                     `json:"username"`
+	Password      *string                     `json:"password"`
+	TOTP          *SanitizedEntryTOTP         `json:"totp"`
+	Notes         *string                     `json:"notes"`
+	URIs          []SanitizedEntryURI         `json:"uris"`
+	CustomFields  []SanitizedEntryCustomField `json:"customFields"`
+	Fields        map[string]string           `json:"fields"`
+	CreatedAt     time.Time                   `json:"createdAt"`
+}
+
+// Previous passwords are left out like current ones when the access hides them.
+func SanitizeEntryRevision(_ *fiber.Ctx, revision *queries.EntryRevision, hidePasswords bool) SanitizedEntryRevision {
+	sanitizedRevision := SanitizedEntryRevision{
+		ID:            revision.ID,
+		EntryID:       revision.EntryID,
+		FolderID:      revision.FolderID,
+		UserID:        revision.UserID,
+		ChangedFields: revision.ChangedFields,
+		Name:          revision.Name,
+		Username:      revision.Username,
+		Password:      revision.Password,
+		TOTP:          SanitizeEntryTOTP(revision.Totp, hidePasswords),
+		Notes:         revision.Notes,
+		URIs:          sanitizeEntryURIs(revision.Uris),
+		CustomFields:  sanitizeEntryCustomFields(revision.CustomFields, hidePasswords),
+		Fields:        sanitizeEntryFields(revision.Fields, hidePasswords),
+		CreatedAt:     revision.CreatedAt.Time,
+	}
+
+	if hidePasswords {
+		sanitizedRevision.Password = nil
+	}
+
+	return sanitizedRevision
+}
+
+func SanitizeEntryRevisions(c *fiber.Ctx, revisions *[]queries.EntryRevision, hidePasswords bool) []SanitizedEntryRevision {
+	sanitizedRevisions := make([]SanitizedEntryRevision, len(*revisions))
+	for i, revision := range *revisions {
+		sanitizedRevisions[i] = SanitizeEntryRevision(c, &revision, hidePasswords)
+	}
+
+	return sanitizedRevisions
+}
+
+func sanitizeEntryFields(fields []byte, hidePasswords bool) map[string]string {
+	if fields == nil {
+		return nil
+	}
+
+	var sanitizedFields map[string]string
+	if err := json.Unmarshal(fields, &sanitizedFields); err != nil {
+		return nil
+	}
+
+	if hidePasswords {
+		for _, secretField := range EntrySecretFields {
+			delete(sanitizedFields, secretField)
+		}
+	}
+
+	return sanitizedFields
+}
+
+func SanitizeEntryTOTP(totp []byte, hidePasswords bool) *SanitizedEntryTOTP {
+	if totp == nil {
+		return nil
+	}
+
+	var sanitizedTOTP SanitizedEntryTOTP
+	if err := json.Unmarshal(totp, &sanitizedTOTP); err != nil {
+		return nil
+	}
+
+	if hidePasswords {
+		sanitizedTOTP.Secret = nil
+	}
+
+	return &sanitizedTOTP
+}
+
+func sanitizeEntryURIs(uris []byte) []SanitizedEntryURI {
+	sanitizedURIs := []SanitizedEntryURI{}
+	if uris == nil {
+		return sanitizedURIs
+	}
+
+	if err := json.Unmarshal(uris, &sanitizedURIs); err != nil {
+		return []SanitizedEntryURI{}
+	}
+
+	return sanitizedURIs
+}
+
+func sanitizeEntryCustomFields(customFields []byte, hidePasswords bool) []SanitizedEntryCustomField {
+	sanitizedCustomFields := []SanitizedEntryCustomField{}
+	if customFields == nil {
+		return sanitizedCustomFields
+	}
+
+	if err := json.Unmarshal(customFields, &sanitizedCustomFields); err != nil {
+		return []SanitizedEntryCustomField{}
+	}
+
+	if hidePasswords {
+		for i, customField := range sanitizedCustomFields {
+			if customField.Type == ENTRY_CUSTOM_FIELD_HIDDEN {
+				sanitizedCustomFields[i].Value = nil
+			}
+		}
 	}
 
-	return sanitizedEntries
+	return sanitizedCustomFields
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/exporter"
+	"github.com/gofiber/fiber/v2"
+)
+
+// The vault as stored, along with what the client needs to decrypt it from
+// the master password alone.
+type SanitizedExport struct {
+	Version    int               `json:"version"`
+	Kdf        SanitizedKdf      `json:"kdf"`
+	Key        *string           `json:"key"`
+	PrivateKey *string           `json:"privateKey"`
+	Folders    []SanitizedFolder `json:"folders"`
+	Entries    []SanitizedEntry  `json:"entries"`
+}
+
+func SanitizeExport(c *fiber.Ctx, user *queries.User, folders *[]queries.Folder, entries *[]queries.Entry) (SanitizedExport, bool) {
+	sanitizedFolders, ok := SanitizeFolders(c, folders)
+	if !ok {
+		return SanitizedExport{}, false
+	}
+
+	sanitizedEntries, ok := SanitizeEntries(c, entries)
+	if !ok {
+		return SanitizedExport{}, false
+	}
+
+	return SanitizedExport{
+		Version:    exporter.EXPORT_VERSION,
+		Kdf:        SanitizeKdf(c, user),
+		Key:        user.Key,
+		PrivateKey: user.PrivateKey,
+		Folders:    sanitizedFolders,
+		Entries:    sanitizedEntries,
+	}, true
+}
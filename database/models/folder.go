@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/database/queries"
 	"github.com/LeonardJouve/pass-secure/status"
@@ -8,36 +10,32 @@ import (
 )
 
 type SanitizedFolder struct {
-	ID       int64   `json:"id"`
-	UserIds  []int64 `json:"userIds"`
-	OwnerID  int64   `json:"ownerId"`
-	Name     string  `json:"name"`
-	ParentID *int64  `json:"parentId"`
+	ID             int64      `json:"id"`
+	UserIds        []int64    `json:"userIds"`
+	OwnerID        int64      `json:"ownerId"`
+	OrganizationID *int64     `json:"organizationId"`
+	Name           string     `json:"name"`
+	ParentID       *int64     `json:"parentId"`
+	Key            *string    `json:"key"`
+	GroupID        *int64     `json:"groupId"`
+	Role           string     `json:"role"`
+	HidePasswords  bool       `json:"hidePasswords"`
+	Inherited      bool       `json:"inherited"`
+	DeletedAt      *time.Time `json:"deletedAt"`
 }
 
 func SanitizeFolder(c *fiber.Ctx, folder *queries.Folder) (SanitizedFolder, bool) {
-	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	sanitizedFolders, ok := SanitizeFolders(c, &[]queries.Folder{*folder})
 	if !ok {
-		status.InternalServerError(c, nil)
-		return SanitizedFolder{}, false
-	}
-	defer commit()
-
-	userIds, err := qtx.GetFolderUsers(ctx, folder.ID)
-	if err != nil {
-		status.InternalServerError(c, nil)
 		return SanitizedFolder{}, false
 	}
 
-	return SanitizedFolder{
-		ID:       folder.ID,
-		OwnerID:  folder.OwnerID,
-		Name:     folder.Name,
-		ParentID: folder.ParentID,
-		UserIds:  userIds,
-	}, true
+	return sanitizedFolders[0], true
 }
 
+// Each member only receives the folder key wrapped with their own public key,
+// or encrypted with the key of the group granting them access, along with
+// their own role in the folder.
 func SanitizeFolders(c *fiber.Ctx, folders *[]queries.Folder) ([]SanitizedFolder, bool) {
 	qtx, ctx, commit, ok := database.BeginTransaction(c)
 	if !ok {
@@ -46,29 +44,48 @@ func SanitizeFolders(c *fiber.Ctx, folders *[]queries.Folder) ([]SanitizedFolder
 	}
 	defer commit()
 
+	user, _ := c.Locals("user").(queries.User)
+
 	folderIds := make([]int64, len(*folders))
 	for i, folder := range *folders {
 		folderIds[i] = folder.ID
 	}
 
-	foldersUsers, err := qtx.GetFoldersUsers(ctx, folderIds)
+	foldersAccesses, err := qtx.GetFoldersAccesses(ctx, folderIds)
 	if err != nil {
 		status.InternalServerError(c, nil)
 		return []SanitizedFolder{}, false
 	}
 
 	usersByFolder := make(map[int64][]int64)
-	for _, folderUser := range foldersUsers {
-		usersByFolder[folderUser.FolderID] = append(usersByFolder[folderUser.FolderID], folderUser.UserID)
+	accessByFolder := make(map[int64]queries.FolderAccess)
+	for _, folderAccess := range foldersAccesses {
+		usersByFolder[folderAccess.FolderID] = append(usersByFolder[folderAccess.FolderID], folderAccess.UserID)
+		if folderAccess.UserID == user.ID {
+			accessByFolder[folderAccess.FolderID] = folderAccess
+		}
 	}
 
 	sanitizedFolders := make([]SanitizedFolder, len(*folders))
 	for i, folder := range *folders {
 		sanitizedFolders[i] = SanitizedFolder{
-			ID:       folder.ID,
-			OwnerID:  folder.OwnerID,
-			Name:     folder.Name,
-			ParentID: folder.ParentID,
+			ID:             folder.ID,
+			OwnerID:        folder.OwnerID,
+			OrganizationID: folder.OrganizationID,
+			Name:           folder.Name,
+			ParentID:       folder.ParentID,
+		}
+
+		if folder.DeletedAt.Valid {
+			sanitizedFolders[i].DeletedAt = &folder.DeletedAt.Time
+		}
+
+		if access, ok := accessByFolder[folder.ID]; ok {
+			sanitizedFolders[i].Key = access.Key
+			sanitizedFolders[i].GroupID = access.GroupID
+			sanitizedFolders[i].Role = access.Role
+			sanitizedFolders[i].HidePasswords = access.HidePasswords
+			sanitizedFolders[i].Inherited = access.Inherited
 		}
 
 		if userIds, ok := usersByFolder[folder.ID]; ok {
@@ -78,3 +95,60 @@ func SanitizeFolders(c *fiber.Ctx, folders *[]queries.Folder) ([]SanitizedFolder
 
 	return sanitizedFolders, true
 }
+
+type SanitizedFolderTree struct {
+	SanitizedFolder
+	EntryCount int64                 `json:"entryCount"`
+	Children   []SanitizedFolderTree `json:"children"`
+}
+
+// Folders whose parent the user can not see, such as folders shared with them,
+// are returned at the top level next to their root folder.
+func SanitizeFolderTree(c *fiber.Ctx, folders *[]queries.Folder, entryCounts *[]queries.GetUserFolderEntryCountsRow) ([]SanitizedFolderTree, bool) {
+	sanitizedFolders, ok := SanitizeFolders(c, folders)
+	if !ok {
+		return []SanitizedFolderTree{}, false
+	}
+
+	entryCountByFolder := make(map[int64]int64)
+	for _, entryCount := range *entryCounts {
+		entryCountByFolder[entryCount.FolderID] = entryCount.EntryCount
+	}
+
+	visibleFolders := make(map[int64]bool)
+	for _, folder := range sanitizedFolders {
+		visibleFolders[folder.ID] = true
+	}
+
+	var rootFolders []SanitizedFolder
+	childrenByFolder := make(map[int64][]SanitizedFolder)
+	for _, folder := range sanitizedFolders {
+		if folder.ParentID != nil && visibleFolders[*folder.ParentID] {
+			childrenByFolder[*folder.ParentID] = append(childrenByFolder[*folder.ParentID], folder)
+		} else {
+			rootFolders = append(rootFolders, folder)
+		}
+	}
+
+	var buildTree func(folder SanitizedFolder) SanitizedFolderTree
+	buildTree = func(folder SanitizedFolder) SanitizedFolderTree {
+		tree := SanitizedFolderTree{
+			SanitizedFolder: folder,
+			EntryCount:      entryCountByFolder[folder.ID],
+			Children:        []SanitizedFolderTree{},
+		}
+
+		for _, child := range childrenByFolder[folder.ID] {
+			tree.Children = append(tree.Children, buildTree(child))
+		}
+
+		return tree
+	}
+
+	folderTree := make([]SanitizedFolderTree, len(rootFolders))
+	for i, folder := range rootFolders {
+		folderTree[i] = buildTree(folder)
+	}
+
+	return folderTree, true
+}
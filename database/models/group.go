@@ -0,0 +1,146 @@
+package models
+
+import (
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedGroup struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	OwnerID   int64   `json:"ownerId"`
+	ParentID  *int64  `json:"parentId"`
+	ParentKey *string `json:"parentKey"`
+	UserIds   []int64 `json:"userIds"`
+	Key       *string `json:"key"`
+	Inherited bool    `json:"inherited"`
+}
+
+func SanitizeGroup(c *fiber.Ctx, group *queries.Group) (SanitizedGroup, bool) {
+	sanitizedGroups, ok := SanitizeGroups(c, &[]queries.Group{*group})
+	if !ok {
+		return SanitizedGroup{}, false
+	}
+
+	return sanitizedGroups[0], true
+}
+
+// Members only receive the group key wrapped for them. Members of a subgroup
+// have no key of their own and unwrap the parent key from the subgroup instead.
+func SanitizeGroups(c *fiber.Ctx, groups *[]queries.Group) ([]SanitizedGroup, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		status.InternalServerError(c, nil)
+		return []SanitizedGroup{}, false
+	}
+	defer commit()
+
+	user, _ := c.Locals("user").(queries.User)
+
+	groupIds := make([]int64, len(*groups))
+	for i, group := range *groups {
+		groupIds[i] = group.ID
+	}
+
+	groupsUsers, err := qtx.GetGroupsUsers(ctx, groupIds)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return []SanitizedGroup{}, false
+	}
+
+	memberships, err := qtx.GetGroupMemberships(ctx, user.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return []SanitizedGroup{}, false
+	}
+
+	usersByGroup := make(map[int64][]int64)
+	keyByGroup := make(map[int64]string)
+	for _, groupUser := range groupsUsers {
+		usersByGroup[groupUser.GroupID] = append(usersByGroup[groupUser.GroupID], groupUser.UserID)
+		if groupUser.UserID == user.ID {
+			keyByGroup[groupUser.GroupID] = groupUser.Key
+		}
+	}
+
+	inheritedByGroup := make(map[int64]bool)
+	for _, membership := range memberships {
+		inheritedByGroup[membership.GroupID] = membership.Inherited
+	}
+
+	sanitizedGroups := make([]SanitizedGroup, len(*groups))
+	for i, group := range *groups {
+		sanitizedGroups[i] = SanitizedGroup{
+			ID:        group.ID,
+			Name:      group.Name,
+			OwnerID:   group.OwnerID,
+			ParentID:  group.ParentID,
+			ParentKey: group.ParentKey,
+			UserIds:   []int64{},
+			Inherited: inheritedByGroup[group.ID],
+		}
+
+		if group.ParentID == nil {
+			sanitizedGroups[i].ParentKey = nil
+		}
+
+		if key, ok := keyByGroup[group.ID]; ok {
+			sanitizedGroups[i].Key = &key
+		}
+
+		if userIds, ok := usersByGroup[group.ID]; ok {
+			sanitizedGroups[i].UserIds = userIds
+		}
+	}
+
+	return sanitizedGroups, true
+}
+
+type SanitizedFolderGroup struct {
+	GroupID       int64  `json:"groupId"`
+	FolderID      int64  `json:"folderId"`
+	Role          string `json:"role"`
+	HidePasswords bool   `json:"hidePasswords"`
+}
+
+func SanitizeFolderGroup(_ *fiber.Ctx, folderGroup *queries.GroupFolder) SanitizedFolderGroup {
+	return SanitizedFolderGroup{
+		GroupID:       folderGroup.GroupID,
+		FolderID:      folderGroup.FolderID,
+		Role:          folderGroup.Role,
+		HidePasswords: folderGroup.HidePasswords,
+	}
+}
+
+func SanitizeFolderGroups(c *fiber.Ctx, folderGroups *[]queries.GroupFolder) []SanitizedFolderGroup {
+	sanitizedFolderGroups := make([]SanitizedFolderGroup, len(*folderGroups))
+	for i, folderGroup := range *folderGroups {
+		sanitizedFolderGroups[i] = SanitizeFolderGroup(c, &folderGroup)
+	}
+
+	return sanitizedFolderGroups
+}
+
+// The wrapped key is only handed over once the invitation is accepted.
+type SanitizedGroupInvitation struct {
+	GroupID   int64     `json:"groupId"`
+	InviterID int64     `json:"inviterId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func SanitizeGroupInvitations(_ *fiber.Ctx, invitations *[]queries.GroupInvitation) []SanitizedGroupInvitation {
+	sanitizedInvitations := make([]SanitizedGroupInvitation, len(*invitations))
+	for i, invitation := range *invitations {
+		sanitizedInvitations[i] = SanitizedGroupInvitation{
+			GroupID:   invitation.GroupID,
+			InviterID: invitation.InviterID,
+			CreatedAt: invitation.CreatedAt.Time,
+		}
+	}
+
+	return sanitizedInvitations
+}
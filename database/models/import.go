@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	IMPORT_STATUS_IMPORTED = "imported"
+	IMPORT_STATUS_FAILED   = "failed"
+)
+
+// Rows are reported by the server from the entries it created or refused,
+// clients merge them with the rows they skipped while parsing.
+type ImportReportRow struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type SanitizedImport struct {
+	Folders []SanitizedFolder `json:"folders"`
+	Entries []SanitizedEntry  `json:"entries"`
+	Report  []ImportReportRow `json:"report"`
+}
+
+func SanitizeImport(c *fiber.Ctx, folders *[]queries.Folder, entries *[]queries.Entry, report []ImportReportRow) (SanitizedImport, bool) {
+	sanitizedFolders, ok := SanitizeFolders(c, folders)
+	if !ok {
+		return SanitizedImport{}, false
+	}
+
+	sanitizedEntries, ok := SanitizeEntries(c, entries)
+	if !ok {
+		return SanitizedImport{}, false
+	}
+
+	return SanitizedImport{
+		Folders: sanitizedFolders,
+		Entries: sanitizedEntries,
+		Report:  report,
+	}, true
+}
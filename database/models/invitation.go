@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+const INVITATION_STATUS_EXPIRED = "expired"
+
+// The invitee account is only revealed once the invitation was accepted, so
+// inviting an email does not tell whether it is registered.
+type SanitizedInvitation struct {
+	ID            int64     `json:"id"`
+	FolderID      int64     `json:"folderId"`
+	InviterID     int64     `json:"inviterId"`
+	Email         string    `json:"email"`
+	InviteeID     *int64    `json:"inviteeId"`
+	Role          string    `json:"role"`
+	HidePasswords bool      `json:"hidePasswords"`
+	Status        string    `json:"status"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func SanitizeInvitation(_ *fiber.Ctx, invitation *queries.FolderInvitation) SanitizedInvitation {
+	sanitizedInvitation := SanitizedInvitation{
+		ID:            invitation.ID,
+		FolderID:      invitation.FolderID,
+		InviterID:     invitation.InviterID,
+		Email:         invitation.InviteeEmail,
+		Role:          invitation.Role,
+		HidePasswords: invitation.HidePasswords,
+		Status:        getInvitationStatus(invitation),
+		ExpiresAt:     invitation.ExpiresAt.Time,
+		CreatedAt:     invitation.CreatedAt.Time,
+	}
+
+	if invitation.Status == auth.INVITATION_STATUS_ACCEPTED || invitation.Status == auth.INVITATION_STATUS_CONFIRMED {
+		sanitizedInvitation.InviteeID = invitation.InviteeID
+	}
+
+	return sanitizedInvitation
+}
+
+func SanitizeInvitations(c *fiber.Ctx, invitations *[]queries.FolderInvitation) []SanitizedInvitation {
+	sanitizedInvitations := make([]SanitizedInvitation, len(*invitations))
+	for i, invitation := range *invitations {
+		sanitizedInvitations[i] = SanitizeInvitation(c, &invitation)
+	}
+
+	return sanitizedInvitations
+}
+
+type SanitizedUserInvitation struct {
+	Token         string    `json:"token"`
+	FolderID      int64     `json:"folderId"`
+	InviterID     int64     `json:"inviterId"`
+	Role          string    `json:"role"`
+	HidePasswords bool      `json:"hidePasswords"`
+	Status        string    `json:"status"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func SanitizeUserInvitation(_ *fiber.Ctx, invitation *queries.FolderInvitation) SanitizedUserInvitation {
+	return SanitizedUserInvitation{
+		Token:         invitation.Token,
+		FolderID:      invitation.FolderID,
+		InviterID:     invitation.InviterID,
+		Role:          invitation.Role,
+		HidePasswords: invitation.HidePasswords,
+		Status:        getInvitationStatus(invitation),
+		ExpiresAt:     invitation.ExpiresAt.Time,
+		CreatedAt:     invitation.CreatedAt.Time,
+	}
+}
+
+func SanitizeUserInvitations(c *fiber.Ctx, invitations *[]queries.FolderInvitation) []SanitizedUserInvitation {
+	sanitizedInvitations := make([]SanitizedUserInvitation, len(*invitations))
+	for i, invitation := range *invitations {
+		sanitizedInvitations[i] = SanitizeUserInvitation(c, &invitation)
+	}
+
+	return sanitizedInvitations
+}
+
+func getInvitationStatus(invitation *queries.FolderInvitation) string {
+	if invitation.Status == auth.INVITATION_STATUS_PENDING && invitation.ExpiresAt.Time.Before(time.Now()) {
+		return INVITATION_STATUS_EXPIRED
+	}
+
+	return invitation.Status
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedOrganization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func SanitizeOrganization(c *fiber.Ctx, organization *queries.Organization) (SanitizedOrganization, bool) {
+	sanitizedOrganizations, ok := SanitizeOrganizations(c, &[]queries.Organization{*organization})
+	if !ok {
+		return SanitizedOrganization{}, false
+	}
+
+	return sanitizedOrganizations[0], true
+}
+
+// Each organization is returned with the role of the current user in it.
+func SanitizeOrganizations(c *fiber.Ctx, organizations *[]queries.Organization) ([]SanitizedOrganization, bool) {
+	qtx, ctx, commit, ok := database.BeginTransaction(c)
+	if !ok {
+		status.InternalServerError(c, nil)
+		return []SanitizedOrganization{}, false
+	}
+	defer commit()
+
+	user, _ := c.Locals("user").(queries.User)
+
+	memberships, err := qtx.GetUserOrganizationMemberships(ctx, user.ID)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return []SanitizedOrganization{}, false
+	}
+
+	roleByOrganization := make(map[int64]string)
+	for _, membership := range memberships {
+		roleByOrganization[membership.OrganizationID] = membership.Role
+	}
+
+	sanitizedOrganizations := make([]SanitizedOrganization, len(*organizations))
+	for i, organization := range *organizations {
+		sanitizedOrganizations[i] = SanitizedOrganization{
+			ID:        organization.ID,
+			Name:      organization.Name,
+			Role:      roleByOrganization[organization.ID],
+			CreatedAt: organization.CreatedAt.Time,
+		}
+	}
+
+	return sanitizedOrganizations, true
+}
+
+type SanitizedOrganizationUser struct {
+	UserID    int64     `json:"userId"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func SanitizeOrganizationUser(_ *fiber.Ctx, organizationUser *queries.OrganizationUser) SanitizedOrganizationUser {
+	return SanitizedOrganizationUser{
+		UserID:    organizationUser.UserID,
+		Role:      organizationUser.Role,
+		CreatedAt: organizationUser.CreatedAt.Time,
+	}
+}
+
+func SanitizeOrganizationUsers(c *fiber.Ctx, organizationUsers *[]queries.OrganizationUser) []SanitizedOrganizationUser {
+	sanitizedOrganizationUsers := make([]SanitizedOrganizationUser, len(*organizationUsers))
+	for i, organizationUser := range *organizationUsers {
+		sanitizedOrganizationUsers[i] = SanitizeOrganizationUser(c, &organizationUser)
+	}
+
+	return sanitizedOrganizationUsers
+}
+
+// The invitee account is never revealed, so inviting an email does not tell
+// whether it is registered.
+type SanitizedOrganizationInvitation struct {
+	OrganizationID int64     `json:"organizationId"`
+	Email          string    `json:"email"`
+	InviterID      int64     `json:"inviterId"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func SanitizeOrganizationInvitation(_ *fiber.Ctx, invitation *queries.OrganizationInvitation) SanitizedOrganizationInvitation {
+	return SanitizedOrganizationInvitation{
+		OrganizationID: invitation.OrganizationID,
+		Email:          invitation.InviteeEmail,
+		InviterID:      invitation.InviterID,
+		Role:           invitation.Role,
+		CreatedAt:      invitation.CreatedAt.Time,
+	}
+}
+
+func SanitizeOrganizationInvitations(c *fiber.Ctx, invitations *[]queries.OrganizationInvitation) []SanitizedOrganizationInvitation {
+	sanitizedInvitations := make([]SanitizedOrganizationInvitation, len(*invitations))
+	for i, invitation := range *invitations {
+		sanitizedInvitations[i] = SanitizeOrganizationInvitation(c, &invitation)
+	}
+
+	return sanitizedInvitations
+}
+
+type SanitizedPolicies struct {
+	RequireTwoFactor          bool  `json:"requireTwoFactor"`
+	MinPasswordStrength       int16 `json:"minPasswordStrength"`
+	DisablePersonalExport     bool  `json:"disablePersonalExport"`
+	DisableSharedExport       bool  `json:"disableSharedExport"`
+	GeneratorMinLength        int16 `json:"generatorMinLength"`
+	GeneratorRequireUppercase bool  `json:"generatorRequireUppercase"`
+	GeneratorRequireLowercase bool  `json:"generatorRequireLowercase"`
+	GeneratorRequireDigits    bool  `json:"generatorRequireDigits"`
+	GeneratorRequireSymbols   bool  `json:"generatorRequireSymbols"`
+	GeneratorMinDigits        int16 `json:"generatorMinDigits"`
+	GeneratorMinSymbols       int16 `json:"generatorMinSymbols"`
+	GeneratorMinWordCount     int16 `json:"generatorMinWordCount"`
+	GeneratorCapitalize       bool  `json:"generatorCapitalize"`
+	GeneratorIncludeNumber    bool  `json:"generatorIncludeNumber"`
+}
+
+func SanitizeOrganizationPolicies(_ *fiber.Ctx, policies *queries.OrganizationPolicy) SanitizedPolicies {
+	return SanitizedPolicies{
+		RequireTwoFactor:          policies.RequireTwoFactor,
+		MinPasswordStrength:       policies.MinPasswordStrength,
+		DisablePersonalExport:     policies.DisablePersonalExport,
+		DisableSharedExport:       policies.DisableSharedExport,
+		GeneratorMinLength:        policies.GeneratorMinLength,
+		GeneratorRequireUppercase: policies.GeneratorRequireUppercase,
+		GeneratorRequireLowercase: policies.GeneratorRequireLowercase,
+		GeneratorRequireDigits:    policies.GeneratorRequireDigits,
+		GeneratorRequireSymbols:   policies.GeneratorRequireSymbols,
+		GeneratorMinDigits:        policies.GeneratorMinDigits,
+		GeneratorMinSymbols:       policies.GeneratorMinSymbols,
+		GeneratorMinWordCount:     policies.GeneratorMinWordCount,
+		GeneratorCapitalize:       policies.GeneratorCapitalize,
+		GeneratorIncludeNumber:    policies.GeneratorIncludeNumber,
+	}
+}
+
+// The policies applying to a user are the strictest of their organizations.
+func SanitizeUserPolicies(_ *fiber.Ctx, policies *queries.GetUserPoliciesRow) SanitizedPolicies {
+	return SanitizedPolicies{
+		RequireTwoFactor:          policies.RequireTwoFactor,
+		MinPasswordStrength:       policies.MinPasswordStrength,
+		DisablePersonalExport:     policies.DisablePersonalExport,
+		DisableSharedExport:       policies.DisableSharedExport,
+		GeneratorMinLength:        policies.GeneratorMinLength,
+		GeneratorRequireUppercase: policies.GeneratorRequireUppercase,
+		GeneratorRequireLowercase: policies.GeneratorRequireLowercase,
+		GeneratorRequireDigits:    policies.GeneratorRequireDigits,
+		GeneratorRequireSymbols:   policies.GeneratorRequireSymbols,
+		GeneratorMinDigits:        policies.GeneratorMinDigits,
+		GeneratorMinSymbols:       policies.GeneratorMinSymbols,
+		GeneratorMinWordCount:     policies.GeneratorMinWordCount,
+		GeneratorCapitalize:       policies.GeneratorCapitalize,
+		GeneratorIncludeNumber:    policies.GeneratorIncludeNumber,
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedSession struct {
+	ID         string    `json:"id"`
+	Device     *string   `json:"device"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+func SanitizeSession(c *fiber.Ctx, session *queries.Session) SanitizedSession {
+	currentSessionId, _ := c.Locals("session_id").(string)
+
+	return SanitizedSession{
+		ID:         session.ID,
+		Device:     session.Device,
+		UserAgent:  session.UserAgent,
+		IP:         session.Ip,
+		CreatedAt:  session.CreatedAt.Time,
+		LastSeenAt: session.LastSeenAt.Time,
+		Current:    session.ID == currentSessionId,
+	}
+}
+
+func SanitizeSessions(c *fiber.Ctx, sessions *[]queries.Session) []SanitizedSession {
+	sanitizedSessions := make([]SanitizedSession, len(*sessions))
+	for i, session := range *sessions {
+		sanitizedSessions[i] = SanitizeSession(c, &session)
+	}
+
+	return sanitizedSessions
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedFolderTransfer struct {
+	ID         int64     `json:"id"`
+	FolderID   int64     `json:"folderId"`
+	FromUserID int64     `json:"fromUserId"`
+	ToUserID   int64     `json:"toUserId"`
+	Subtree    bool      `json:"subtree"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func SanitizeFolderTransfer(_ *fiber.Ctx, transfer *queries.FolderTransfer) SanitizedFolderTransfer {
+	return SanitizedFolderTransfer{
+		ID:         transfer.ID,
+		FolderID:   transfer.FolderID,
+		FromUserID: transfer.FromUserID,
+		ToUserID:   transfer.ToUserID,
+		Subtree:    transfer.Subtree,
+		CreatedAt:  transfer.CreatedAt.Time,
+	}
+}
+
+func SanitizeFolderTransfers(c *fiber.Ctx, transfers *[]queries.FolderTransfer) []SanitizedFolderTransfer {
+	sanitizedTransfers := make([]SanitizedFolderTransfer, len(*transfers))
+	for i, transfer := range *transfers {
+		sanitizedTransfers[i] = SanitizeFolderTransfer(c, &transfer)
+	}
+
+	return sanitizedTransfers
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedTrash struct {
+	Folders []SanitizedFolder `json:"folders"`
+	Entries []SanitizedEntry  `json:"entries"`
+}
+
+func SanitizeTrash(c *fiber.Ctx, folders *[]queries.Folder, entries *[]queries.Entry) (SanitizedTrash, bool) {
+	sanitizedFolders, ok := SanitizeFolders(c, folders)
+	if !ok {
+		return SanitizedTrash{}, false
+	}
+
+	sanitizedEntries, ok := SanitizeEntries(c, entries)
+	if !ok {
+		return SanitizedTrash{}, false
+	}
+
+	return SanitizedTrash{
+		Folders: sanitizedFolders,
+		Entries: sanitizedEntries,
+	}, true
+}
@@ -6,16 +6,33 @@ import (
 )
 
 type SanitizedUser struct {
-	ID       int64  `json:"id"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
+	ID        int64   `json:"id"`
+	Email     string  `json:"email"`
+	Username  string  `json:"username"`
+	PublicKey *string `json:"publicKey"`
 }
 
 func SanitizeUser(_ *fiber.Ctx, user *queries.User) SanitizedUser {
 	return SanitizedUser{
-		ID:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
+		ID:        user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		PublicKey: user.PublicKey,
+	}
+}
+
+// The encrypted vault and private keys are only ever returned to their owner.
+type SanitizedMe struct {
+	SanitizedUser
+	Key        *string `json:"key"`
+	PrivateKey *string `json:"privateKey"`
+}
+
+func SanitizeMe(c *fiber.Ctx, user *queries.User) SanitizedMe {
+	return SanitizedMe{
+		SanitizedUser: SanitizeUser(c, user),
+		Key:           user.Key,
+		PrivateKey:    user.PrivateKey,
 	}
 }
 
@@ -27,3 +44,19 @@ func SanitizeUsers(c *fiber.Ctx, users *[]queries.User) []SanitizedUser {
 
 	return sanitizedUsers
 }
+
+type SanitizedKdf struct {
+	Kdf            string `json:"kdf"`
+	KdfIterations  int32  `json:"kdfIterations"`
+	KdfMemory      *int32 `json:"kdfMemory"`
+	KdfParallelism *int32 `json:"kdfParallelism"`
+}
+
+func SanitizeKdf(_ *fiber.Ctx, user *queries.User) SanitizedKdf {
+	return SanitizedKdf{
+		Kdf:            user.Kdf,
+		KdfIterations:  user.KdfIterations,
+		KdfMemory:      user.KdfMemory,
+		KdfParallelism: user.KdfParallelism,
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SanitizedWebAuthnCredential struct {
+	ID           int64      `json:"id"`
+	CredentialID string     `json:"credentialId"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastUsedAt   *time.Time `json:"lastUsedAt"`
+}
+
+func SanitizeWebAuthnCredential(_ *fiber.Ctx, credential *queries.WebauthnCredential) SanitizedWebAuthnCredential {
+	sanitizedCredential := SanitizedWebAuthnCredential{
+		ID:           credential.ID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.CredentialID),
+		Name:         credential.Name,
+		CreatedAt:    credential.CreatedAt.Time,
+	}
+
+	if credential.LastUsedAt.Valid {
+		sanitizedCredential.LastUsedAt = &credential.LastUsedAt.Time
+	}
+
+	return sanitizedCredential
+}
+
+func SanitizeWebAuthnCredentials(c *fiber.Ctx, credentials *[]queries.WebauthnCredential) []SanitizedWebAuthnCredential {
+	sanitizedCredentials := make([]SanitizedWebAuthnCredential, len(*credentials))
+	for i, credential := range *credentials {
+		sanitizedCredentials[i] = SanitizeWebAuthnCredential(c, &credential)
+	}
+
+	return sanitizedCredentials
+}
@@ -1,10 +1,46 @@
--- name: GetUsers :many
-SELECT * FROM users;
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE (users.id = sqlc.arg(user_id) OR users.id IN (
+    SELECT organization_users.user_id FROM organization_users
+    WHERE organization_users.organization_id IN (
+        SELECT organization_id FROM organization_users WHERE organization_users.user_id = sqlc.arg(user_id)
+    )
+)) AND (users.email ILIKE '%' || sqlc.arg(search)::text || '%' OR users.username ILIKE '%' || sqlc.arg(search)::text || '%')
+ORDER BY users.username;
 
 -- name: GetUser :one
 SELECT * FROM users
 WHERE id = $1;
 
+-- name: GetVisibleUser :one
+SELECT * FROM users
+WHERE users.id = sqlc.arg(id) AND (users.id = sqlc.arg(user_id) OR users.id IN (
+    SELECT organization_users.user_id FROM organization_users
+    WHERE organization_users.organization_id IN (
+        SELECT organization_id FROM organization_users WHERE organization_users.user_id = sqlc.arg(user_id)
+    )
+) OR users.id IN (
+    SELECT group_membership.user_id FROM group_membership
+    WHERE group_membership.group_id IN (
+        SELECT group_id FROM group_membership WHERE group_membership.user_id = sqlc.arg(user_id)
+    )
+) OR users.id IN (
+    SELECT access.user_id FROM folders_access(ARRAY(
+        SELECT folder_id FROM folder_access WHERE folder_access.user_id = sqlc.arg(user_id)
+    )) AS access
+) OR users.id IN (
+    SELECT folder_invitations.invitee_id FROM folder_invitations
+    WHERE folder_invitations.status IN ('accepted', 'confirmed') AND folder_invitations.folder_id IN (
+        SELECT folder_id FROM folder_access WHERE folder_access.user_id = sqlc.arg(user_id)
+    )
+) OR users.id IN (
+    SELECT folder_invitations.inviter_id FROM folder_invitations WHERE folder_invitations.invitee_id = sqlc.arg(user_id)
+    UNION
+    SELECT group_invitations.inviter_id FROM group_invitations WHERE group_invitations.user_id = sqlc.arg(user_id)
+    UNION
+    SELECT organization_invitations.inviter_id FROM organization_invitations WHERE organization_invitations.user_id = sqlc.arg(user_id)
+));
+
 -- name: HasUser :one
 SELECT EXISTS (
     SELECT 1
@@ -24,46 +60,51 @@ SELECT EXISTS (
 ) AS exists;
 
 -- name: CreateUser :one
-INSERT INTO users(email, username, password)
-VALUES ($1, $2, $3)
+INSERT INTO users(email, username, password, kdf, kdf_iterations, kdf_memory, kdf_parallelism, key, public_key, private_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users
-SET email = $2, username = $3, password = $4
+SET email = $2, username = $3, password = $4, kdf = $5, kdf_iterations = $6, kdf_memory = $7, kdf_parallelism = $8, key = $9
 WHERE id = $1
 RETURNING *;
 
+-- name: SetUserKeyPair :execrows
+UPDATE users
+SET public_key = $2, private_key = $3
+WHERE id = $1 AND public_key IS NULL;
+
 -- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1;
 
 -- name: GetUserEntries :many
 SELECT * FROM entries
-WHERE id IN (
+WHERE deleted_at IS NULL AND id IN (
     SELECT id FROM entries
     WHERE folder_id IN (
-        SELECT folder_id FROM user_folders WHERE user_id = $1
+        SELECT folder_id FROM folder_access WHERE user_id = $1
     )
 );
 
 -- name: GetUserEntry :one
 SELECT * FROM entries
-WHERE entries.id = sqlc.arg(entry_id) AND entries.id IN (
+WHERE entries.id = sqlc.arg(entry_id) AND entries.deleted_at IS NULL AND entries.id IN (
     SELECT id FROM entries
     WHERE folder_id IN (
-        SELECT folder_id FROM user_folders WHERE user_id = $1
+        SELECT folder_id FROM folder_access WHERE user_id = $1
     )
 );
 
 -- name: CreateEntry :one
-INSERT INTO entries(name, username, password, url, folder_id)
-VALUES($1, $2, $3, $4, $5)
+INSERT INTO entries(type, name, username, password, totp, notes, uris, custom_fields, fields, folder_id)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: UpdateEntry :one
 UPDATE entries
-SET name = $2, username = $3, password = $4, url = $5, folder_id = $6
+SET name = $2, username = $3, password = $4, totp = $5, notes = $6, uris = $7, custom_fields = $8, fields = $9, folder_id = $10
 WHERE id = $1
 RETURNING *;
 
@@ -71,56 +112,750 @@ RETURNING *;
 DELETE FROM entries
 WHERE id = $1;
 
+-- name: TrashEntry :exec
+UPDATE entries
+SET deleted_at = NOW()
+WHERE id = $1;
+
+-- name: RestoreEntry :one
+UPDATE entries
+SET deleted_at = NULL, folder_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUserTrashedEntries :many
+SELECT entries.* FROM entries
+JOIN folders ON folders.id = entries.folder_id
+WHERE entries.deleted_at IS NOT NULL AND folders.deleted_at IS DISTINCT FROM entries.deleted_at AND entries.folder_id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+);
+
+-- name: GetUserTrashedEntry :one
+SELECT * FROM entries
+WHERE entries.id = sqlc.arg(entry_id) AND entries.deleted_at IS NOT NULL AND entries.folder_id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+);
+
+-- name: PurgeTrashedEntries :exec
+DELETE FROM entries
+WHERE deleted_at < NOW() - MAKE_INTERVAL(days => sqlc.arg(retention)::INT);
+
+-- name: CreateEntryRevision :exec
+INSERT INTO entry_revisions(entry_id, user_id, changed_fields, name, username, password, totp, notes, uris, custom_fields, fields, folder_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: PruneEntryRevisions :exec
+DELETE FROM entry_revisions
+WHERE entry_revisions.entry_id = sqlc.arg(entry_id) AND entry_revisions.id NOT IN (
+    SELECT id FROM entry_revisions AS kept
+    WHERE kept.entry_id = sqlc.arg(entry_id)
+    ORDER BY kept.id DESC
+    LIMIT sqlc.arg(retention)
+);
+
+-- name: GetEntryRevisions :many
+SELECT * FROM entry_revisions
+WHERE entry_id = $1
+ORDER BY id DESC;
+
+-- name: GetEntryRevision :one
+SELECT * FROM entry_revisions
+WHERE entry_id = $1 AND id = sqlc.arg(revision_id);
+
 -- name: GetUserRootFolder :one
 SELECT * FROM folders
-WHERE parent_id = NULL AND id IN (
-    SELECT folder_id FROM user_folders WHERE user_id = $1
-);
+WHERE parent_id IS NULL AND owner_id = $1;
 
 -- name: CreateFolder :one
-INSERT INTO folders(owner_id, name, parent_id)
-VALUES($1, $2, $3)
+INSERT INTO folders(owner_id, name, parent_id, organization_id)
+VALUES($1, $2, $3, $4)
 RETURNING *;
 
 -- name: UpdateFolder :one
 UPDATE folders
-SET name = $2, owner_id = $3, parent_id = $4
+SET name = $2
 WHERE folders.id = $1
 RETURNING *;
 
+-- name: TransferFolderOwnership :many
+WITH RECURSIVE subtree AS (
+    SELECT id FROM folders
+    WHERE folders.id = sqlc.arg(folder_id)
+    UNION
+    SELECT folders.id FROM folders
+    JOIN subtree ON folders.parent_id = subtree.id
+    WHERE sqlc.arg(subtree)::boolean
+)
+UPDATE folders
+SET owner_id = sqlc.arg(new_owner_id)
+WHERE id IN (SELECT id FROM subtree) AND owner_id = sqlc.arg(previous_owner_id)
+RETURNING *;
+
+-- name: MoveFolder :one
+UPDATE folders
+SET parent_id = $2
+WHERE folders.id = $1
+RETURNING *;
+
+-- name: LockFolderTree :exec
+SELECT pg_advisory_xact_lock(hashtext('folders'));
+
+-- name: IsFolderDescendant :one
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id FROM folders
+    WHERE folders.id = sqlc.arg(folder_id)
+    UNION
+    SELECT folders.id, folders.parent_id FROM folders
+    JOIN ancestors ON folders.id = ancestors.parent_id
+)
+SELECT EXISTS (
+    SELECT 1
+    FROM ancestors
+    WHERE id = sqlc.arg(ancestor_id)
+) AS exists;
+
 -- name: DeleteFolder :exec
 DELETE FROM folders
 WHERE id = $1;
 
+-- name: TrashFolder :exec
+WITH RECURSIVE subtree AS (
+    SELECT id FROM folders
+    WHERE folders.id = sqlc.arg(folder_id)
+    UNION
+    SELECT folders.id FROM folders
+    JOIN subtree ON folders.parent_id = subtree.id
+    WHERE folders.deleted_at IS NULL
+), trashed AS (
+    UPDATE folders
+    SET deleted_at = NOW()
+    WHERE id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+    RETURNING id
+)
+UPDATE entries
+SET deleted_at = NOW()
+WHERE folder_id IN (SELECT id FROM trashed) AND deleted_at IS NULL;
+
+-- name: RestoreFolder :many
+WITH RECURSIVE subtree AS (
+    SELECT id, deleted_at FROM folders
+    WHERE folders.id = sqlc.arg(folder_id)
+    UNION
+    SELECT folders.id, folders.deleted_at FROM folders
+    JOIN subtree ON folders.parent_id = subtree.id
+    WHERE folders.deleted_at = subtree.deleted_at
+), restored AS (
+    UPDATE entries
+    SET deleted_at = NULL
+    FROM subtree
+    WHERE entries.folder_id = subtree.id AND entries.deleted_at = subtree.deleted_at
+)
+UPDATE folders
+SET deleted_at = NULL, parent_id = CASE WHEN folders.id = sqlc.arg(folder_id) THEN sqlc.arg(parent_id)::BIGINT ELSE folders.parent_id END
+WHERE id IN (SELECT id FROM subtree)
+RETURNING *;
+
+-- name: GetUserTrashedFolders :many
+SELECT folders.* FROM folders
+LEFT JOIN folders AS parents ON parents.id = folders.parent_id
+WHERE folders.deleted_at IS NOT NULL AND parents.deleted_at IS DISTINCT FROM folders.deleted_at AND folders.id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+);
+
+-- name: GetUserTrashedFolder :one
+SELECT * FROM folders
+WHERE folders.id = sqlc.arg(folder_id) AND folders.deleted_at IS NOT NULL AND folders.id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+);
+
+-- name: PurgeTrashedFolders :exec
+DELETE FROM folders
+WHERE deleted_at < NOW() - MAKE_INTERVAL(days => sqlc.arg(retention)::INT);
+
 -- name: GetUserFolders :many
 SELECT * FROM folders
-WHERE id IN (
-    SELECT folder_id FROM user_folders WHERE user_id = $1
+WHERE deleted_at IS NULL AND id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
 );
 
 -- name: GetUserFolder :one
 SELECT * FROM folders
-WHERE id IN (
-    SELECT folder_id FROM user_folders WHERE user_id = $1
+WHERE deleted_at IS NULL AND id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
 ) AND id = sqlc.arg(folder_id);
 
+-- name: GetUserFolderEntryCounts :many
+SELECT folder_id, COUNT(*) AS entry_count FROM entries
+WHERE deleted_at IS NULL AND folder_id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+)
+GROUP BY folder_id;
+
 -- name: GetFolder :one
 SELECT * FROM folders
 WHERE id = $1;
 
 -- name: GetFolderUsers :many
-SELECT user_id FROM user_folders
-WHERE folder_id = $1;
+SELECT user_id FROM folders_access(ARRAY[sqlc.arg(folder_id)::BIGINT]);
+
+-- name: GetFoldersAccesses :many
+SELECT * FROM folders_access($1::bigint[]);
+
+-- name: GetFolderAccess :one
+SELECT * FROM folder_access
+WHERE user_id = $1 AND folder_id = $2 AND folder_id IN (
+    SELECT id FROM folders WHERE deleted_at IS NULL
+);
 
--- name: GetFoldersUsers :many
-SELECT * FROM user_folders
-WHERE folder_id = ANY($1::bigint[]);
+-- name: GetTrashedFolderAccess :one
+SELECT * FROM folder_access
+WHERE user_id = $1 AND folder_id = $2 AND folder_id IN (
+    SELECT id FROM folders WHERE deleted_at IS NOT NULL
+);
+
+-- name: GetUserFolderAccesses :many
+SELECT * FROM folder_access
+WHERE user_id = $1;
 
--- name: AddFolderUser :exec
-INSERT INTO user_folders(user_id, folder_id)
-VALUES($1, $2)
+-- name: AddFolderUser :execrows
+INSERT INTO user_folders(user_id, folder_id, key, role, hide_passwords)
+VALUES($1, $2, $3, $4, $5)
 ON CONFLICT (user_id, folder_id) DO NOTHING;
 
--- name: DeleteFolderUser :exec
+-- name: UpdateFolderUser :one
+UPDATE user_folders
+SET role = $3, hide_passwords = $4
+WHERE user_id = $1 AND folder_id = $2
+RETURNING *;
+
+-- name: OverrideFolderUser :one
+INSERT INTO user_folders(user_id, folder_id, key, role, hide_passwords)
+SELECT folder_access.user_id, folder_access.folder_id, folder_access.key, sqlc.arg(role)::VARCHAR(16), sqlc.arg(hide_passwords)::BOOLEAN
+FROM folder_access
+WHERE folder_access.user_id = sqlc.arg(user_id) AND folder_access.folder_id = sqlc.arg(folder_id) AND folder_access.group_id IS NULL AND folder_access.inherited
+RETURNING *;
+
+-- name: SetFolderUserKey :exec
+UPDATE user_folders
+SET key = $3
+WHERE user_id = $1 AND folder_id = $2;
+
+-- name: DeleteFolderUser :execrows
 DELETE FROM user_folders
 WHERE user_id = $1 AND folder_id = $2;
+
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens(user_id, family, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- name: UseRefreshToken :execrows
+UPDATE refresh_tokens
+SET used = TRUE
+WHERE id = $1 AND used = FALSE AND revoked = FALSE;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked = TRUE
+WHERE family = $1;
+
+-- name: RevokeRefreshTokenFamilies :exec
+UPDATE refresh_tokens
+SET revoked = TRUE
+WHERE family = ANY($1::varchar[]);
+
+-- name: CreateSession :one
+INSERT INTO sessions(id, user_id, device, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND revoked = FALSE
+ORDER BY last_seen_at DESC;
+
+-- name: GetUserSession :one
+SELECT * FROM sessions
+WHERE user_id = $1 AND id = sqlc.arg(session_id) AND revoked = FALSE;
+
+-- name: TouchSession :one
+UPDATE sessions
+SET last_seen_at = NOW(), ip = $2
+WHERE id = $1 AND revoked = FALSE
+RETURNING *;
+
+-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked = TRUE
+WHERE id = $1;
+
+-- name: RevokeOtherUserSessions :many
+UPDATE sessions
+SET revoked = TRUE
+WHERE user_id = $1 AND id != sqlc.arg(session_id) AND revoked = FALSE
+RETURNING id;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled = FALSE, totp_last_step = NULL
+WHERE id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled = TRUE
+WHERE id = $1;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg(step)::bigint
+WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < sqlc.arg(step)::bigint);
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes(user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used = TRUE
+WHERE user_id = $1 AND code_hash = $2 AND used = FALSE;
+
+-- name: GetUserWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: HasUserWebAuthnCredentials :one
+SELECT EXISTS (
+    SELECT 1
+    FROM webauthn_credentials
+    WHERE user_id = $1
+) AS exists;
+
+-- name: GetWebAuthnCredential :one
+SELECT * FROM webauthn_credentials
+WHERE credential_id = $1;
+
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials(user_id, credential_id, public_key, sign_count, name)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UseWebAuthnCredential :exec
+UPDATE webauthn_credentials
+SET sign_count = $2, last_used_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUserWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE user_id = $1 AND id = $2;
+
+-- name: CreateFolderInvitation :one
+INSERT INTO folder_invitations(folder_id, inviter_id, invitee_email, invitee_id, role, hide_passwords, token, expires_at)
+VALUES ($1, $2, $3, (SELECT id FROM users WHERE email = $3), $4, $5, $6, $7)
+RETURNING *;
+
+-- name: HasOpenFolderInvitation :one
+SELECT EXISTS (
+    SELECT 1
+    FROM folder_invitations
+    WHERE folder_id = $1 AND invitee_email = $2 AND status IN ('pending', 'accepted') AND expires_at > NOW()
+) AS exists;
+
+-- name: GetFolderInvitations :many
+SELECT * FROM folder_invitations
+WHERE folder_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetFolderInvitation :one
+SELECT * FROM folder_invitations
+WHERE folder_id = $1 AND id = sqlc.arg(invitation_id);
+
+-- name: GetUserInvitations :many
+SELECT * FROM folder_invitations
+WHERE invitee_id = $1 AND status = 'pending' AND expires_at > NOW()
+ORDER BY created_at DESC;
+
+-- name: GetUserInvitation :one
+SELECT * FROM folder_invitations
+WHERE invitee_id = $1 AND token = $2;
+
+-- name: SetFolderInvitationStatus :one
+UPDATE folder_invitations
+SET status = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFolderInvitation :exec
+DELETE FROM folder_invitations
+WHERE id = $1;
+
+-- name: ClaimUserInvitations :exec
+UPDATE folder_invitations
+SET invitee_id = $2
+WHERE invitee_email = $1 AND invitee_id IS NULL;
+
+-- name: ClaimUserOrganizationInvitations :exec
+UPDATE organization_invitations
+SET user_id = $2
+WHERE invitee_email = $1 AND user_id IS NULL;
+
+-- name: CreateFolderTransfer :one
+INSERT INTO folder_transfers(folder_id, from_user_id, to_user_id, subtree)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (folder_id) DO UPDATE SET from_user_id = EXCLUDED.from_user_id, to_user_id = EXCLUDED.to_user_id, subtree = EXCLUDED.subtree, created_at = NOW()
+RETURNING *;
+
+-- name: GetUserFolderTransfers :many
+SELECT * FROM folder_transfers
+WHERE to_user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetUserFolderTransfer :one
+SELECT * FROM folder_transfers
+WHERE to_user_id = $1 AND id = sqlc.arg(transfer_id);
+
+-- name: DeleteFolderTransfer :execrows
+DELETE FROM folder_transfers
+WHERE folder_id = $1;
+
+-- name: CreateGroup :one
+INSERT INTO groups(name, owner_id)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetUserGroups :many
+SELECT * FROM groups
+WHERE id IN (
+    SELECT group_id FROM group_membership WHERE user_id = $1
+);
+
+-- name: GetUserGroup :one
+SELECT * FROM groups
+WHERE id IN (
+    SELECT group_id FROM group_membership WHERE user_id = $1
+) AND id = sqlc.arg(group_id);
+
+-- name: GetGroup :one
+SELECT * FROM groups
+WHERE id = $1;
+
+-- name: UpdateGroup :one
+UPDATE groups
+SET name = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: SetGroupParent :one
+UPDATE groups
+SET parent_id = $2, parent_key = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: LockGroupTree :exec
+SELECT pg_advisory_xact_lock(hashtext('groups'));
+
+-- name: IsGroupDescendant :one
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id FROM groups
+    WHERE groups.id = sqlc.arg(group_id)
+    UNION
+    SELECT groups.id, groups.parent_id FROM groups
+    JOIN ancestors ON groups.id = ancestors.parent_id
+)
+SELECT EXISTS (
+    SELECT 1
+    FROM ancestors
+    WHERE id = sqlc.arg(ancestor_id)
+) AS exists;
+
+-- name: DeleteGroup :exec
+DELETE FROM groups
+WHERE id = $1;
+
+-- name: GetGroupsUsers :many
+SELECT * FROM group_users
+WHERE group_id = ANY($1::bigint[]);
+
+-- name: GetGroupMemberships :many
+SELECT * FROM group_membership
+WHERE user_id = $1;
+
+-- name: AddGroupUser :exec
+INSERT INTO group_users(group_id, user_id, key)
+VALUES ($1, $2, $3)
+ON CONFLICT (group_id, user_id) DO UPDATE SET key = EXCLUDED.key;
+
+-- name: CreateGroupInvitation :exec
+INSERT INTO group_invitations(group_id, user_id, inviter_id, key)
+VALUES($1, $2, $3, $4)
+ON CONFLICT (group_id, user_id) DO UPDATE SET inviter_id = EXCLUDED.inviter_id, key = EXCLUDED.key, created_at = NOW();
+
+-- name: GetUserGroupInvitations :many
+SELECT * FROM group_invitations
+WHERE user_id = $1;
+
+-- name: DeleteGroupInvitation :one
+DELETE FROM group_invitations
+WHERE group_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteGroupUser :execrows
+DELETE FROM group_users
+WHERE group_id = $1 AND user_id = $2;
+
+-- name: GetFolderGroups :many
+SELECT * FROM group_folders
+WHERE folder_id = $1;
+
+-- name: GetFolderGroup :one
+SELECT * FROM group_folders
+WHERE group_id = $1 AND folder_id = $2;
+
+-- name: SetFolderGroup :one
+INSERT INTO group_folders(group_id, folder_id, key, role, hide_passwords)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (group_id, folder_id) DO UPDATE SET key = EXCLUDED.key, role = EXCLUDED.role, hide_passwords = EXCLUDED.hide_passwords
+RETURNING *;
+
+-- name: DeleteFolderGroup :execrows
+DELETE FROM group_folders
+WHERE group_id = $1 AND folder_id = $2;
+
+-- name: CreateOrganization :one
+INSERT INTO organizations(name)
+VALUES ($1)
+RETURNING *;
+
+-- name: GetUserOrganizations :many
+SELECT * FROM organizations
+WHERE id IN (
+    SELECT organization_id FROM organization_users WHERE user_id = $1
+)
+ORDER BY name;
+
+-- name: GetUserOrganization :one
+SELECT * FROM organizations
+WHERE id IN (
+    SELECT organization_id FROM organization_users WHERE user_id = $1
+) AND id = sqlc.arg(organization_id);
+
+-- name: UpdateOrganization :one
+UPDATE organizations
+SET name = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteOrganization :exec
+DELETE FROM organizations
+WHERE id = $1;
+
+-- name: GetOrganizationUsers :many
+SELECT * FROM organization_users
+WHERE organization_id = $1
+ORDER BY created_at;
+
+-- name: GetOrganizationUser :one
+SELECT * FROM organization_users
+WHERE organization_id = $1 AND user_id = $2;
+
+-- name: GetUserOrganizationMemberships :many
+SELECT * FROM organization_users
+WHERE user_id = $1;
+
+-- name: AddOrganizationUser :one
+INSERT INTO organization_users(organization_id, user_id, role)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateOrganizationInvitation :one
+INSERT INTO organization_invitations(organization_id, invitee_email, user_id, inviter_id, role)
+VALUES ($1, $2, (SELECT id FROM users WHERE email = $2), $3, $4)
+ON CONFLICT (organization_id, invitee_email) DO UPDATE SET inviter_id = EXCLUDED.inviter_id, role = EXCLUDED.role, created_at = NOW()
+RETURNING *;
+
+-- name: GetUserOrganizationInvitations :many
+SELECT * FROM organization_invitations
+WHERE user_id = $1;
+
+-- name: DeleteOrganizationInvitation :one
+DELETE FROM organization_invitations
+WHERE organization_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: UpdateOrganizationUser :one
+UPDATE organization_users
+SET role = $3
+WHERE organization_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteOrganizationUser :exec
+DELETE FROM organization_users
+WHERE organization_id = $1 AND user_id = $2;
+
+-- name: CountOrganizationOwners :one
+SELECT COUNT(*) FROM organization_users
+WHERE organization_id = $1 AND role = 'owner';
+
+-- name: GetUserSoleOwnedOrganizations :many
+SELECT organization_id FROM organization_users
+WHERE organization_users.user_id = $1 AND organization_users.role = 'owner' AND NOT EXISTS (
+    SELECT 1
+    FROM organization_users AS owners
+    WHERE owners.organization_id = organization_users.organization_id AND owners.role = 'owner' AND owners.user_id != $1
+);
+
+-- name: ReassignOrganizationFolders :exec
+UPDATE folders
+SET owner_id = (
+    SELECT organization_users.user_id FROM organization_users
+    WHERE organization_users.organization_id = folders.organization_id AND organization_users.role = 'owner' AND organization_users.user_id != sqlc.arg(user_id)
+    ORDER BY organization_users.created_at
+    LIMIT 1
+)
+WHERE folders.owner_id = sqlc.arg(user_id) AND folders.organization_id = sqlc.arg(organization_id)::BIGINT;
+
+-- name: DeleteOrganizationFolderUser :exec
+DELETE FROM user_folders
+WHERE user_id = sqlc.arg(user_id) AND folder_id IN (
+    SELECT id FROM folders WHERE organization_id = sqlc.arg(organization_id)::BIGINT
+);
+
+-- name: GetOrganizationFolders :many
+SELECT * FROM folders
+WHERE organization_id = sqlc.arg(organization_id)::BIGINT AND deleted_at IS NULL;
+
+-- name: CreateOrganizationPolicies :exec
+INSERT INTO organization_policies(organization_id)
+VALUES ($1);
+
+-- name: GetOrganizationPolicies :one
+SELECT * FROM organization_policies
+WHERE organization_id = $1;
+
+-- name: UpdateOrganizationPolicies :one
+UPDATE organization_policies
+SET
+    require_two_factor = $2,
+    min_password_strength = $3,
+    disable_personal_export = $4,
+    disable_shared_export = $5,
+    generator_min_length = $6,
+    generator_require_uppercase = $7,
+    generator_require_lowercase = $8,
+    generator_require_digits = $9,
+    generator_require_symbols = $10,
+    generator_min_digits = $11,
+    generator_min_symbols = $12,
+    generator_min_word_count = $13,
+    generator_capitalize = $14,
+    generator_include_number = $15
+WHERE organization_id = $1
+RETURNING *;
+
+-- name: GetUserPolicies :one
+SELECT
+    COALESCE(BOOL_OR(organization_policies.require_two_factor), FALSE)::BOOLEAN AS require_two_factor,
+    COALESCE(MAX(organization_policies.min_password_strength), 0)::SMALLINT AS min_password_strength,
+    COALESCE(BOOL_OR(organization_policies.disable_personal_export), FALSE)::BOOLEAN AS disable_personal_export,
+    COALESCE(BOOL_OR(organization_policies.disable_shared_export), FALSE)::BOOLEAN AS disable_shared_export,
+    COALESCE(MAX(organization_policies.generator_min_length), 0)::SMALLINT AS generator_min_length,
+    COALESCE(BOOL_OR(organization_policies.generator_require_uppercase), FALSE)::BOOLEAN AS generator_require_uppercase,
+    COALESCE(BOOL_OR(organization_policies.generator_require_lowercase), FALSE)::BOOLEAN AS generator_require_lowercase,
+    COALESCE(BOOL_OR(organization_policies.generator_require_digits), FALSE)::BOOLEAN AS generator_require_digits,
+    COALESCE(BOOL_OR(organization_policies.generator_require_symbols), FALSE)::BOOLEAN AS generator_require_symbols,
+    COALESCE(MAX(organization_policies.generator_min_digits), 0)::SMALLINT AS generator_min_digits,
+    COALESCE(MAX(organization_policies.generator_min_symbols), 0)::SMALLINT AS generator_min_symbols,
+    COALESCE(MAX(organization_policies.generator_min_word_count), 0)::SMALLINT AS generator_min_word_count,
+    COALESCE(BOOL_OR(organization_policies.generator_capitalize), FALSE)::BOOLEAN AS generator_capitalize,
+    COALESCE(BOOL_OR(organization_policies.generator_include_number), FALSE)::BOOLEAN AS generator_include_number
+FROM organization_policies
+JOIN organization_users ON organization_users.organization_id = organization_policies.organization_id
+WHERE organization_users.user_id = $1;
+
+-- name: GetUserExportFolders :many
+SELECT * FROM folders
+WHERE deleted_at IS NULL AND id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+) AND (organization_id IS NULL OR organization_id NOT IN (
+    SELECT organization_id FROM organization_policies WHERE disable_shared_export
+));
+
+-- name: GetUserExportEntries :many
+SELECT entries.* FROM entries
+JOIN folders ON folders.id = entries.folder_id
+WHERE entries.deleted_at IS NULL AND folders.deleted_at IS NULL AND folders.id IN (
+    SELECT folder_id FROM folder_access WHERE user_id = $1
+) AND (folders.organization_id IS NULL OR folders.organization_id NOT IN (
+    SELECT organization_id FROM organization_policies WHERE disable_shared_export
+));
+
+-- name: GetOrganizationUsersWithoutTwoFactor :many
+SELECT users.id FROM users
+JOIN organization_users ON organization_users.user_id = users.id
+WHERE organization_users.organization_id = $1 AND users.totp_enabled = FALSE AND NOT EXISTS (
+    SELECT 1
+    FROM webauthn_credentials
+    WHERE webauthn_credentials.user_id = users.id
+);
+
+-- name: GetEntryAttachments :many
+SELECT * FROM attachments
+WHERE entry_id = $1
+ORDER BY id;
+
+-- name: GetEntryAttachment :one
+SELECT * FROM attachments
+WHERE entry_id = $1 AND id = sqlc.arg(attachment_id);
+
+-- name: GetUserAttachmentsSize :one
+SELECT (
+    COALESCE((SELECT SUM(size) FROM attachments WHERE user_id = sqlc.arg(user_id)::BIGINT), 0) +
+    COALESCE((SELECT SUM(size) FROM attachment_reservations WHERE user_id = sqlc.arg(user_id)::BIGINT), 0)
+)::BIGINT AS size;
+
+-- name: CreateAttachmentReservation :one
+INSERT INTO attachment_reservations(user_id, size)
+VALUES ($1, $2)
+RETURNING id;
+
+-- name: DeleteAttachmentReservation :exec
+DELETE FROM attachment_reservations
+WHERE id = $1;
+
+-- name: DeleteExpiredAttachmentReservations :exec
+DELETE FROM attachment_reservations
+WHERE user_id = $1 AND created_at < NOW() - INTERVAL '1 day';
+
+-- name: CreateAttachment :one
+INSERT INTO attachments(entry_id, user_id, name, key, size, blob_key)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = $1;
+
+-- name: GetDeletedAttachmentBlobs :many
+SELECT blob_key FROM deleted_attachment_blobs
+ORDER BY deleted_at
+LIMIT $1;
+
+-- name: DeleteDeletedAttachmentBlob :exec
+DELETE FROM deleted_attachment_blobs
+WHERE blob_key = $1;
+
+-- name: LockUserAttachments :exec
+SELECT pg_advisory_xact_lock(hashtext('attachments:' || sqlc.arg(user_id)::BIGINT));
@@ -0,0 +1,99 @@
+package encstring
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Encrypted values are opaque to the server, only the envelope is checked:
+// "<type>.<iv>|<ciphertext>|<mac>" for symmetric values and "<type>.<ciphertext>"
+// for values wrapped with a user's public key, each part base64 encoded.
+const (
+	TYPE_AES_CBC_256_HMAC_SHA256 = 2
+	TYPE_RSA_2048_OAEP_SHA256    = 3
+	TYPE_RSA_2048_OAEP_SHA1      = 4
+	AES_BLOCK_SIZE               = 16
+	HMAC_SHA256_SIZE             = 32
+	RSA_MIN_CIPHERTEXT_SIZE      = 256
+	RSA_MAX_CIPHERTEXT_SIZE      = 512
+)
+
+var errInvalidEncString = errors.New("invalid encrypted string")
+
+type EncString struct {
+	Type       int
+	IV         []byte
+	Ciphertext []byte
+	MAC        []byte
+}
+
+func Parse(value string) (EncString, error) {
+	encType, data, found := strings.Cut(value, ".")
+	if !found {
+		return EncString{}, errInvalidEncString
+	}
+
+	t, err := strconv.Atoi(encType)
+	if err != nil {
+		return EncString{}, errInvalidEncString
+	}
+
+	parts := strings.Split(data, "|")
+	decodedParts := make([][]byte, len(parts))
+	for i, part := range parts {
+		decodedParts[i], err = base64.StdEncoding.DecodeString(part)
+		if err != nil || len(decodedParts[i]) == 0 {
+			return EncString{}, errInvalidEncString
+		}
+	}
+
+	switch t {
+	case TYPE_AES_CBC_256_HMAC_SHA256:
+		if len(decodedParts) != 3 {
+			return EncString{}, errInvalidEncString
+		}
+
+		encString := EncString{
+			Type:       t,
+			IV:         decodedParts[0],
+			Ciphertext: decodedParts[1],
+			MAC:        decodedParts[2],
+		}
+		if len(encString.IV) != AES_BLOCK_SIZE || len(encString.Ciphertext)%AES_BLOCK_SIZE != 0 || len(encString.MAC) != HMAC_SHA256_SIZE {
+			return EncString{}, errInvalidEncString
+		}
+
+		return encString, nil
+	case TYPE_RSA_2048_OAEP_SHA256, TYPE_RSA_2048_OAEP_SHA1:
+		if len(decodedParts) != 1 || len(decodedParts[0]) < RSA_MIN_CIPHERTEXT_SIZE || len(decodedParts[0]) > RSA_MAX_CIPHERTEXT_SIZE {
+			return EncString{}, errInvalidEncString
+		}
+
+		return EncString{
+			Type:       t,
+			Ciphertext: decodedParts[0],
+		}, nil
+	}
+
+	return EncString{}, errInvalidEncString
+}
+
+func (e EncString) IsSymmetric() bool {
+	return e.Type == TYPE_AES_CBC_256_HMAC_SHA256
+}
+
+func (e EncString) IsAsymmetric() bool {
+	return e.Type == TYPE_RSA_2048_OAEP_SHA256 || e.Type == TYPE_RSA_2048_OAEP_SHA1
+}
+
+func IsValid(value string) bool {
+	encString, err := Parse(value)
+	return err == nil && encString.IsSymmetric()
+}
+
+func IsValidAsymmetric(value string) bool {
+	encString, err := Parse(value)
+	return err == nil && encString.IsAsymmetric()
+}
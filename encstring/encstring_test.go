@@ -0,0 +1,60 @@
+package encstring
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func encode(size int) string {
+	return base64.StdEncoding.EncodeToString(make([]byte, size))
+}
+
+func TestParse(t *testing.T) {
+	valid := "2." + encode(AES_BLOCK_SIZE) + "|" + encode(2*AES_BLOCK_SIZE) + "|" + encode(HMAC_SHA256_SIZE)
+
+	encString, err := Parse(valid)
+	if err != nil {
+		t.Fatalf("valid encrypted string rejected: %v", err)
+	}
+
+	if encString.Type != TYPE_AES_CBC_256_HMAC_SHA256 || len(encString.Ciphertext) != 2*AES_BLOCK_SIZE {
+		t.Fatalf("unexpected parsed value: %+v", encString)
+	}
+
+	invalid := []string{
+		"",
+		"plaintext",
+		"2.",
+		"9." + encode(AES_BLOCK_SIZE) + "|" + encode(AES_BLOCK_SIZE) + "|" + encode(HMAC_SHA256_SIZE),
+		"2." + encode(AES_BLOCK_SIZE) + "|" + encode(AES_BLOCK_SIZE),
+		"2." + encode(12) + "|" + encode(AES_BLOCK_SIZE) + "|" + encode(HMAC_SHA256_SIZE),
+		"2." + encode(AES_BLOCK_SIZE) + "|" + encode(AES_BLOCK_SIZE+1) + "|" + encode(HMAC_SHA256_SIZE),
+		"2." + encode(AES_BLOCK_SIZE) + "|" + encode(AES_BLOCK_SIZE) + "|" + encode(20),
+		"2." + encode(AES_BLOCK_SIZE) + "||" + encode(HMAC_SHA256_SIZE),
+		"2." + strings.Repeat("!", 24) + "|" + encode(AES_BLOCK_SIZE) + "|" + encode(HMAC_SHA256_SIZE),
+		"4." + encode(RSA_MIN_CIPHERTEXT_SIZE),
+	}
+	for _, value := range invalid {
+		if IsValid(value) {
+			t.Errorf("invalid encrypted string accepted: %q", value)
+		}
+	}
+}
+
+func TestParseAsymmetric(t *testing.T) {
+	if !IsValidAsymmetric("4." + encode(RSA_MIN_CIPHERTEXT_SIZE)) {
+		t.Fatal("valid wrapped key rejected")
+	}
+
+	invalid := []string{
+		"4." + encode(64),
+		"4." + encode(RSA_MIN_CIPHERTEXT_SIZE) + "|" + encode(HMAC_SHA256_SIZE),
+		"2." + encode(AES_BLOCK_SIZE) + "|" + encode(AES_BLOCK_SIZE) + "|" + encode(HMAC_SHA256_SIZE),
+	}
+	for _, value := range invalid {
+		if IsValidAsymmetric(value) {
+			t.Errorf("invalid wrapped key accepted: %q", value)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/LeonardJouve/pass-secure/importer"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	EXPORT_VERSION         = 1
+	EXPORT_KDF             = "argon2id"
+	EXPORT_KDF_ITERATIONS  = 3
+	EXPORT_KDF_MEMORY      = 64
+	EXPORT_KDF_PARALLELISM = 4
+	EXPORT_SALT_SIZE       = 32
+	EXPORT_KEY_SIZE        = 32
+)
+
+// Exports hold the decrypted vault in the shape of parsed imports, folders
+// reference their parent and items their folder through refs.
+type Vault struct {
+	Folders []importer.Folder `json:"folders"`
+	Items   []importer.Item   `json:"items"`
+}
+
+// A folder of the export dump once its name is decrypted.
+type Folder struct {
+	ID       int64
+	ParentID *int64
+	Name     string
+}
+
+// An entry of the export dump once decrypted, in the shape of parsed imports.
+type Item struct {
+	importer.Item
+	FolderID int64
+}
+
+// Passphrase encrypted exports are JSON objects with the following fields:
+//
+//   - encrypted: always true
+//   - version: 1
+//   - kdf: "argon2id"
+//   - kdfIterations, kdfMemory (in MiB) and kdfParallelism: the Argon2id
+//     parameters deriving the 32 bytes key from the passphrase and salt
+//   - salt: the base64 encoded salt
+//   - nonce: the base64 encoded AES-256-GCM nonce
+//   - data: the base64 encoded AES-256-GCM ciphertext of the plain JSON export,
+//     followed by its tag and without additional data
+type EncryptedVault struct {
+	Encrypted      bool   `json:"encrypted"`
+	Version        int    `json:"version"`
+	Kdf            string `json:"kdf"`
+	KdfIterations  uint32 `json:"kdfIterations"`
+	KdfMemory      uint32 `json:"kdfMemory"`
+	KdfParallelism uint8  `json:"kdfParallelism"`
+	Salt           []byte `json:"salt"`
+	Nonce          []byte `json:"nonce"`
+	Data           []byte `json:"data"`
+}
+
+// The server never sees the vault in plaintext, clients decrypt the dump of
+// GET /export then write it in one of the formats below. Folders are
+// referenced by their id, root folders are not exported as folders and their
+// items go at the top of the export.
+func NewVault(folders []Folder, items []Item) Vault {
+	exportedFolders := make(map[int64]Folder)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			exportedFolders[folder.ID] = folder
+		}
+	}
+
+	vault := Vault{
+		Folders: []importer.Folder{},
+		Items:   make([]importer.Item, len(items)),
+	}
+
+	for _, folder := range folders {
+		if _, ok := exportedFolders[folder.ID]; !ok {
+			continue
+		}
+
+		exportedFolder := importer.Folder{
+			Ref:  strconv.FormatInt(folder.ID, 10),
+			Name: folder.Name,
+		}
+
+		if _, ok := exportedFolders[*folder.ParentID]; ok {
+			exportedFolder.ParentRef = strconv.FormatInt(*folder.ParentID, 10)
+		}
+
+		vault.Folders = append(vault.Folders, exportedFolder)
+	}
+
+	for i, itemInput := range items {
+		item := itemInput.Item
+		item.Row = i + 1
+		item.FolderRef = ""
+		if _, ok := exportedFolders[itemInput.FolderID]; ok {
+			item.FolderRef = strconv.FormatInt(itemInput.FolderID, 10)
+		}
+
+		vault.Items[i] = item
+	}
+
+	return vault
+}
+
+func WriteJSON(w io.Writer, vault Vault) error {
+	return json.NewEncoder(w).Encode(vault)
+}
+
+func WriteEncryptedJSON(w io.Writer, vault Vault, passphrase string) error {
+	data, err := json.Marshal(vault)
+	if err != nil {
+		return err
+	}
+
+	encryptedVault := EncryptedVault{
+		Encrypted:      true,
+		Version:        EXPORT_VERSION,
+		Kdf:            EXPORT_KDF,
+		KdfIterations:  EXPORT_KDF_ITERATIONS,
+		KdfMemory:      EXPORT_KDF_MEMORY,
+		KdfParallelism: EXPORT_KDF_PARALLELISM,
+		Salt:           make([]byte, EXPORT_SALT_SIZE),
+	}
+	if _, err := rand.Read(encryptedVault.Salt); err != nil {
+		return err
+	}
+
+	key := deriveKey([]byte(passphrase), encryptedVault.Salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	encryptedVault.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(encryptedVault.Nonce); err != nil {
+		return err
+	}
+
+	encryptedVault.Data = aead.Seal(nil, encryptedVault.Nonce, data, nil)
+
+	return json.NewEncoder(w).Encode(encryptedVault)
+}
+
+// Derives the key of JSON exports from the passphrase, and the transformed key
+// of KDBX files from their composite key.
+func deriveKey(secret []byte, salt []byte) []byte {
+	return argon2.IDKey(secret, salt, EXPORT_KDF_ITERATIONS, EXPORT_KDF_MEMORY*1024, EXPORT_KDF_PARALLELISM, EXPORT_KEY_SIZE)
+}
@@ -0,0 +1,263 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/LeonardJouve/pass-secure/importer"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+func testVault() Vault {
+	return Vault{
+		Folders: []importer.Folder{
+			{Ref: "1", Name: "Work"},
+			{Ref: "2", Name: "Servers", ParentRef: "1"},
+		},
+		Items: []importer.Item{
+			{
+				Type:     importer.ITEM_TYPE_LOGIN,
+				Name:     "Server",
+				Username: "root",
+				Password: "secret",
+				URIs:     []importer.URI{{URI: "https://a.example.com"}, {URI: "https://b.example.com"}},
+				TOTP:     &importer.TOTP{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", Account: "root"},
+				CustomFields: []importer.CustomField{
+					{Type: importer.CUSTOM_FIELD_HIDDEN, Name: "pin", Value: "1234"},
+					{Type: importer.CUSTOM_FIELD_TEXT, Name: "pin", Value: "5678"},
+				},
+				FolderRef: "2",
+			},
+			{
+				Type:   importer.ITEM_TYPE_CARD,
+				Name:   "Card",
+				Fields: map[string]string{"number": "4111111111111111"},
+			},
+		},
+	}
+}
+
+func TestNewVault(t *testing.T) {
+	rootId, workId := int64(1), int64(2)
+	vault := NewVault([]Folder{
+		{ID: rootId, Name: "Root"},
+		{ID: workId, ParentID: &rootId, Name: "Work"},
+		{ID: 3, ParentID: &workId, Name: "Servers"},
+	}, []Item{
+		{Item: importer.Item{Type: importer.ITEM_TYPE_LOGIN, Name: "Server", FolderRef: "stale"}, FolderID: 3},
+		{Item: importer.Item{Type: importer.ITEM_TYPE_LOGIN, Name: "Mail"}, FolderID: rootId},
+	})
+
+	if len(vault.Folders) != 2 || vault.Folders[0].ParentRef != "" || vault.Folders[1].ParentRef != "2" {
+		t.Fatalf("unexpected folders: %+v", vault.Folders)
+	}
+
+	if vault.Items[0].FolderRef != "3" || vault.Items[0].Row != 1 || vault.Items[1].FolderRef != "" {
+		t.Fatalf("unexpected items: %+v", vault.Items)
+	}
+}
+
+func TestWriteEncryptedJSON(t *testing.T) {
+	var output bytes.Buffer
+	if err := WriteEncryptedJSON(&output, testVault(), "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	var encryptedVault EncryptedVault
+	if err := json.Unmarshal(output.Bytes(), &encryptedVault); err != nil {
+		t.Fatal(err)
+	}
+
+	if !encryptedVault.Encrypted || encryptedVault.Kdf != EXPORT_KDF || encryptedVault.Version != EXPORT_VERSION {
+		t.Fatalf("unexpected export: %+v", encryptedVault)
+	}
+
+	key := argon2.IDKey([]byte("passphrase"), encryptedVault.Salt, encryptedVault.KdfIterations, encryptedVault.KdfMemory*1024, encryptedVault.KdfParallelism, EXPORT_KEY_SIZE)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := aead.Open(nil, encryptedVault.Nonce, encryptedVault.Data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vault Vault
+	if err := json.Unmarshal(data, &vault); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vault.Folders) != 2 || len(vault.Items) != 2 || vault.Items[0].Password != "secret" {
+		t.Fatalf("unexpected vault: %+v", vault)
+	}
+}
+
+// Reads the file back the way KeePass clients do, checking the header and
+// block HMACs before decrypting the payload.
+func TestWriteKDBX(t *testing.T) {
+	var output bytes.Buffer
+	if err := WriteKDBX(&output, testVault(), "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := output.Bytes()
+	if binary.LittleEndian.Uint32(file[0:]) != KDBX_SIGNATURE_1 || binary.LittleEndian.Uint32(file[4:]) != KDBX_SIGNATURE_2 || binary.LittleEndian.Uint32(file[8:]) != KDBX_VERSION {
+		t.Fatal("unexpected signature")
+	}
+
+	fields := make(map[byte][]byte)
+	offset := 12
+	for {
+		id := file[offset]
+		size := int(binary.LittleEndian.Uint32(file[offset+1:]))
+		fields[id] = file[offset+5 : offset+5+size]
+		offset += 5 + size
+
+		if id == KDBX_HEADER_END {
+			break
+		}
+	}
+	header := file[:offset]
+
+	if headerHash := sha256.Sum256(header); !bytes.Equal(headerHash[:], file[offset:offset+32]) {
+		t.Fatal("unexpected header hash")
+	}
+	offset += 32
+
+	if !bytes.Equal(fields[KDBX_HEADER_CIPHER_ID], kdbxAES256UUID) {
+		t.Fatal("unexpected cipher")
+	}
+
+	kdfParameters := fields[KDBX_HEADER_KDF_PARAMETERS]
+	saltIndex := bytes.Index(kdfParameters, []byte("S"))
+	salt := kdfParameters[saltIndex+5 : saltIndex+5+EXPORT_SALT_SIZE]
+	if !bytes.Contains(kdfParameters, kdbxArgon2idUUID) {
+		t.Fatal("unexpected kdf")
+	}
+
+	masterSeed := fields[KDBX_HEADER_MASTER_SEED]
+	passphraseHash := sha256.Sum256([]byte("passphrase"))
+	compositeKey := sha256.Sum256(passphraseHash[:])
+	transformedKey := argon2.IDKey(compositeKey[:], salt, EXPORT_KDF_ITERATIONS, EXPORT_KDF_MEMORY*1024, EXPORT_KDF_PARALLELISM, EXPORT_KEY_SIZE)
+	encryptionKey := sha256.Sum256(slices.Concat(masterSeed, transformedKey))
+	hmacKey := sha512.Sum512(slices.Concat(masterSeed, transformedKey, []byte{1}))
+
+	headerHmac := hmac.New(sha256.New, kdbxBlockKey(hmacKey[:], KDBX_HEADER_INDEX))
+	headerHmac.Write(header)
+	if !hmac.Equal(headerHmac.Sum(nil), file[offset:offset+32]) {
+		t.Fatal("unexpected header hmac")
+	}
+	offset += 32
+
+	var payload []byte
+	for index := uint64(0); ; index++ {
+		size := int(binary.LittleEndian.Uint32(file[offset+32:]))
+		block := file[offset+36 : offset+36+size]
+
+		blockHmac := hmac.New(sha256.New, kdbxBlockKey(hmacKey[:], index))
+		binary.Write(blockHmac, binary.LittleEndian, index)
+		binary.Write(blockHmac, binary.LittleEndian, int32(size))
+		blockHmac.Write(block)
+		if !hmac.Equal(blockHmac.Sum(nil), file[offset:offset+32]) {
+			t.Fatalf("unexpected hmac of block %d", index)
+		}
+
+		payload = append(payload, block...)
+		offset += 36 + size
+
+		if size == 0 {
+			break
+		}
+	}
+
+	block, err := aes.NewCipher(encryptionKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCDecrypter(block, fields[KDBX_HEADER_ENCRYPTION_IV]).CryptBlocks(payload, payload)
+	payload = payload[:len(payload)-int(payload[len(payload)-1])]
+
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content[0] != KDBX_INNER_HEADER_STREAM_ID || content[9] != KDBX_INNER_HEADER_STREAM_KEY {
+		t.Fatal("unexpected inner header")
+	}
+	streamKey := content[14 : 14+KDBX_STREAM_KEY]
+	document := content[14+KDBX_STREAM_KEY+5:]
+
+	var keePassFile kdbxFile
+	if err := xml.Unmarshal(document, &keePassFile); err != nil {
+		t.Fatal(err)
+	}
+
+	root := keePassFile.Root.Group
+	if len(root.Entries) != 1 || len(root.Groups) != 1 || root.Groups[0].Name != "Work" || len(root.Groups[0].Groups) != 1 {
+		t.Fatalf("unexpected groups: %+v", root)
+	}
+
+	server := root.Groups[0].Groups[0]
+	if server.Name != "Servers" || len(server.Entries) != 1 {
+		t.Fatalf("unexpected group: %+v", server)
+	}
+
+	streamKeyHash := sha512.Sum512(streamKey)
+	innerStream, err := chacha20.NewUnauthenticatedCipher(streamKeyHash[:32], streamKeyHash[32:44])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Protected values are decrypted in document order, the card comes first
+	values := make(map[string]string)
+	for _, entry := range []kdbxEntry{root.Entries[0], server.Entries[0]} {
+		for _, entryString := range entry.Strings {
+			value := entryString.Value.Value
+			if entryString.Value.Protected == KDBX_PROTECTED {
+				data, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				innerStream.XORKeyStream(data, data)
+				value = string(data)
+			}
+
+			values[entryString.Key] = value
+		}
+	}
+
+	if values["number"] != "4111111111111111" || values["Password"] != "secret" || values["URL"] != "https://a.example.com" || values[KDBX_URL_KEY+"1"] != "https://b.example.com" {
+		t.Fatalf("unexpected values: %+v", values)
+	}
+
+	if values["pin"] != "1234" || values["pin (2)"] != "5678" || !strings.HasPrefix(values[KDBX_OTP_KEY], "otpauth://totp/Example:root?") {
+		t.Fatalf("unexpected values: %+v", values)
+	}
+}
@@ -0,0 +1,492 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/LeonardJouve/pass-secure/importer"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	KDBX_SIGNATURE_1  = 0x9AA2D903
+	KDBX_SIGNATURE_2  = 0xB54BFB67
+	KDBX_VERSION      = 0x00040000
+	KDBX_BLOCK_SIZE   = 1024 * 1024
+	KDBX_GENERATOR    = "pass-secure"
+	KDBX_ROOT_NAME    = "pass-secure"
+	KDBX_ARGON2_V13   = 0x13
+	KDBX_SEED_SIZE    = 32
+	KDBX_STREAM_KEY   = 64
+	KDBX_COMPRESSION  = 1
+	KDBX_STREAM_ID    = 3
+	KDBX_UUID_SIZE    = 16
+	KDBX_PROTECTED    = "True"
+	KDBX_OTP_KEY      = "otp"
+	KDBX_URL_KEY      = "KP2A_URL_"
+	KDBX_HEADER_INDEX = math.MaxUint64
+)
+
+const (
+	KDBX_HEADER_END            = 0
+	KDBX_HEADER_CIPHER_ID      = 2
+	KDBX_HEADER_COMPRESSION    = 3
+	KDBX_HEADER_MASTER_SEED    = 4
+	KDBX_HEADER_ENCRYPTION_IV  = 7
+	KDBX_HEADER_KDF_PARAMETERS = 11
+)
+
+const (
+	KDBX_INNER_HEADER_END        = 0
+	KDBX_INNER_HEADER_STREAM_ID  = 1
+	KDBX_INNER_HEADER_STREAM_KEY = 2
+)
+
+const (
+	KDBX_VARIANT_VERSION    = 0x0100
+	KDBX_VARIANT_END        = 0x00
+	KDBX_VARIANT_UINT32     = 0x04
+	KDBX_VARIANT_UINT64     = 0x05
+	KDBX_VARIANT_BYTE_ARRAY = 0x42
+)
+
+var (
+	kdbxAES256UUID   = []byte{0x31, 0xc1, 0xf2, 0xe6, 0xbf, 0x71, 0x43, 0x50, 0xbe, 0x58, 0x05, 0x21, 0x6a, 0xfc, 0x5a, 0xff}
+	kdbxArgon2idUUID = []byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+)
+
+// The type specific fields protected in memory, along with passwords, TOTP
+// secrets and hidden custom fields.
+var kdbxProtectedFields = []string{"number", "code", "ssn", "passportNumber", "licenseNumber", "privateKey", "secret"}
+
+type kdbxFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		Generator    string `xml:"Generator"`
+		DatabaseName string `xml:"DatabaseName"`
+	} `xml:"Meta"`
+	Root struct {
+		Group kdbxGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type kdbxGroup struct {
+	UUID    string      `xml:"UUID"`
+	Name    string      `xml:"Name"`
+	Entries []kdbxEntry `xml:"Entry"`
+	Groups  []kdbxGroup `xml:"Group"`
+}
+
+type kdbxEntry struct {
+	UUID    string       `xml:"UUID"`
+	Strings []kdbxString `xml:"String"`
+}
+
+type kdbxString struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Value     string `xml:",chardata"`
+		Protected string `xml:"Protected,attr,omitempty"`
+	} `xml:"Value"`
+}
+
+type kdbxWriter struct {
+	vault        Vault
+	innerStream  *chacha20.Cipher
+	childFolders map[string][]importer.Folder
+	folderItems  map[string][]importer.Item
+}
+
+// Writes a KeePass KDBX 4 file protected by the passphrase, using Argon2id,
+// AES-256 and gzip compression. Folders become groups under a root group
+// holding the items without a folder.
+func WriteKDBX(w io.Writer, vault Vault, passphrase string) error {
+	masterSeed, err := randomBytes(KDBX_SEED_SIZE)
+	if err != nil {
+		return err
+	}
+
+	salt, err := randomBytes(EXPORT_SALT_SIZE)
+	if err != nil {
+		return err
+	}
+
+	iv, err := randomBytes(aes.BlockSize)
+	if err != nil {
+		return err
+	}
+
+	streamKey, err := randomBytes(KDBX_STREAM_KEY)
+	if err != nil {
+		return err
+	}
+
+	passphraseHash := sha256.Sum256([]byte(passphrase))
+	compositeKey := sha256.Sum256(passphraseHash[:])
+	transformedKey := deriveKey(compositeKey[:], salt)
+	encryptionKey := sha256.Sum256(slices.Concat(masterSeed, transformedKey))
+	hmacKey := sha512.Sum512(slices.Concat(masterSeed, transformedKey, []byte{1}))
+
+	header := writeKDBXHeader(masterSeed, salt, iv)
+	headerHash := sha256.Sum256(header)
+	headerHmac := hmac.New(sha256.New, kdbxBlockKey(hmacKey[:], KDBX_HEADER_INDEX))
+	headerHmac.Write(header)
+
+	payload, err := writeKDBXPayload(vault, streamKey)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(encryptionKey[:])
+	if err != nil {
+		return err
+	}
+
+	padding := aes.BlockSize - len(payload)%aes.BlockSize
+	payload = append(payload, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(payload, payload)
+
+	var file bytes.Buffer
+	file.Write(header)
+	file.Write(headerHash[:])
+	file.Write(headerHmac.Sum(nil))
+	writeKDBXBlocks(&file, payload, hmacKey[:])
+
+	_, err = file.WriteTo(w)
+	return err
+}
+
+func writeKDBXHeader(masterSeed []byte, salt []byte, iv []byte) []byte {
+	var kdfParameters bytes.Buffer
+	binary.Write(&kdfParameters, binary.LittleEndian, uint16(KDBX_VARIANT_VERSION))
+	writeKDBXVariant(&kdfParameters, KDBX_VARIANT_BYTE_ARRAY, "$UUID", kdbxArgon2idUUID)
+	writeKDBXVariant(&kdfParameters, KDBX_VARIANT_BYTE_ARRAY, "S", salt)
+	writeKDBXVariant(&kdfParameters, KDBX_VARIANT_UINT32, "P", binary.LittleEndian.AppendUint32(nil, EXPORT_KDF_PARALLELISM))
+	writeKDBXVariant(&kdfParameters, KDBX_VARIANT_UINT64, "M", binary.LittleEndian.AppendUint64(nil, EXPORT_KDF_MEMORY*1024*1024))
+	writeKDBXVariant(&kdfParameters, KDBX_VARIANT_UINT64, "I", binary.LittleEndian.AppendUint64(nil, EXPORT_KDF_ITERATIONS))
+	writeKDBXVariant(&kdfParameters, KDBX_VARIANT_UINT32, "V", binary.LittleEndian.AppendUint32(nil, KDBX_ARGON2_V13))
+	kdfParameters.WriteByte(KDBX_VARIANT_END)
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, uint32(KDBX_SIGNATURE_1))
+	binary.Write(&header, binary.LittleEndian, uint32(KDBX_SIGNATURE_2))
+	binary.Write(&header, binary.LittleEndian, uint32(KDBX_VERSION))
+	writeKDBXHeaderField(&header, KDBX_HEADER_CIPHER_ID, kdbxAES256UUID)
+	writeKDBXHeaderField(&header, KDBX_HEADER_COMPRESSION, binary.LittleEndian.AppendUint32(nil, KDBX_COMPRESSION))
+	writeKDBXHeaderField(&header, KDBX_HEADER_MASTER_SEED, masterSeed)
+	writeKDBXHeaderField(&header, KDBX_HEADER_ENCRYPTION_IV, iv)
+	writeKDBXHeaderField(&header, KDBX_HEADER_KDF_PARAMETERS, kdfParameters.Bytes())
+	writeKDBXHeaderField(&header, KDBX_HEADER_END, []byte("\r\n\r\n"))
+
+	return header.Bytes()
+}
+
+func writeKDBXHeaderField(header *bytes.Buffer, id byte, data []byte) {
+	header.WriteByte(id)
+	binary.Write(header, binary.LittleEndian, uint32(len(data)))
+	header.Write(data)
+}
+
+func writeKDBXVariant(dictionary *bytes.Buffer, valueType byte, key string, value []byte) {
+	dictionary.WriteByte(valueType)
+	binary.Write(dictionary, binary.LittleEndian, uint32(len(key)))
+	dictionary.WriteString(key)
+	binary.Write(dictionary, binary.LittleEndian, uint32(len(value)))
+	dictionary.Write(value)
+}
+
+// The compressed inner header and XML document. Protected values are
+// encrypted with the ChaCha20 inner stream in the order of the document.
+func writeKDBXPayload(vault Vault, streamKey []byte) ([]byte, error) {
+	streamKeyHash := sha512.Sum512(streamKey)
+	innerStream, err := chacha20.NewUnauthenticatedCipher(streamKeyHash[:32], streamKeyHash[32:44])
+	if err != nil {
+		return nil, err
+	}
+
+	writer := kdbxWriter{
+		vault:        vault,
+		innerStream:  innerStream,
+		childFolders: make(map[string][]importer.Folder),
+		folderItems:  make(map[string][]importer.Item),
+	}
+
+	file := kdbxFile{}
+	file.Meta.Generator = KDBX_GENERATOR
+	file.Meta.DatabaseName = KDBX_ROOT_NAME
+	file.Root.Group, err = writer.rootGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload bytes.Buffer
+	compressor := gzip.NewWriter(&payload)
+
+	var innerHeader bytes.Buffer
+	writeKDBXInnerHeaderField(&innerHeader, KDBX_INNER_HEADER_STREAM_ID, binary.LittleEndian.AppendUint32(nil, KDBX_STREAM_ID))
+	writeKDBXInnerHeaderField(&innerHeader, KDBX_INNER_HEADER_STREAM_KEY, streamKey)
+	writeKDBXInnerHeaderField(&innerHeader, KDBX_INNER_HEADER_END, nil)
+	compressor.Write(innerHeader.Bytes())
+
+	compressor.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(compressor).Encode(file); err != nil {
+		return nil, err
+	}
+
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+
+	return payload.Bytes(), nil
+}
+
+func writeKDBXInnerHeaderField(header *bytes.Buffer, id byte, data []byte) {
+	header.WriteByte(id)
+	binary.Write(header, binary.LittleEndian, int32(len(data)))
+	header.Write(data)
+}
+
+// Splits the payload in blocks authenticated by their index, ending with an
+// empty block.
+func writeKDBXBlocks(file *bytes.Buffer, payload []byte, hmacKey []byte) {
+	for index := uint64(0); ; index++ {
+		size := min(len(payload), KDBX_BLOCK_SIZE)
+		block := payload[:size]
+		payload = payload[size:]
+
+		blockHmac := hmac.New(sha256.New, kdbxBlockKey(hmacKey, index))
+		binary.Write(blockHmac, binary.LittleEndian, index)
+		binary.Write(blockHmac, binary.LittleEndian, int32(size))
+		blockHmac.Write(block)
+
+		file.Write(blockHmac.Sum(nil))
+		binary.Write(file, binary.LittleEndian, int32(size))
+		file.Write(block)
+
+		if size == 0 {
+			return
+		}
+	}
+}
+
+func kdbxBlockKey(hmacKey []byte, index uint64) []byte {
+	blockKey := sha512.Sum512(slices.Concat(binary.LittleEndian.AppendUint64(nil, index), hmacKey))
+	return blockKey[:]
+}
+
+// Folders whose parent is not exported and items whose folder is not go in
+// the root group.
+func (w *kdbxWriter) rootGroup() (kdbxGroup, error) {
+	folderRefs := make(map[string]bool)
+	for _, folder := range w.vault.Folders {
+		folderRefs[folder.Ref] = true
+	}
+
+	for _, folder := range w.vault.Folders {
+		parentRef := folder.ParentRef
+		if !folderRefs[parentRef] || parentRef == folder.Ref {
+			parentRef = ""
+		}
+
+		w.childFolders[parentRef] = append(w.childFolders[parentRef], folder)
+	}
+
+	for _, item := range w.vault.Items {
+		folderRef := item.FolderRef
+		if !folderRefs[folderRef] {
+			folderRef = ""
+		}
+
+		w.folderItems[folderRef] = append(w.folderItems[folderRef], item)
+	}
+
+	return w.group("", KDBX_ROOT_NAME, make(map[string]bool))
+}
+
+// Entries are encoded before subgroups, as they are laid out in the document.
+func (w *kdbxWriter) group(ref string, name string, visited map[string]bool) (kdbxGroup, error) {
+	visited[ref] = true
+
+	uuid, err := kdbxUUID()
+	if err != nil {
+		return kdbxGroup{}, err
+	}
+
+	group := kdbxGroup{
+		UUID: uuid,
+		Name: name,
+	}
+
+	for _, item := range w.folderItems[ref] {
+		entry, err := w.entry(item)
+		if err != nil {
+			return kdbxGroup{}, err
+		}
+
+		group.Entries = append(group.Entries, entry)
+	}
+
+	for _, folder := range w.childFolders[ref] {
+		if visited[folder.Ref] {
+			continue
+		}
+
+		subgroup, err := w.group(folder.Ref, folder.Name, visited)
+		if err != nil {
+			return kdbxGroup{}, err
+		}
+
+		group.Groups = append(group.Groups, subgroup)
+	}
+
+	return group, nil
+}
+
+// Standard strings are always present, other strings are named after the
+// custom field or type specific field they come from.
+func (w *kdbxWriter) entry(item importer.Item) (kdbxEntry, error) {
+	uuid, err := kdbxUUID()
+	if err != nil {
+		return kdbxEntry{}, err
+	}
+
+	entry := kdbxEntry{
+		UUID: uuid,
+	}
+
+	url := ""
+	if len(item.URIs) != 0 {
+		url = item.URIs[0].URI
+	}
+
+	entry.addString(w, "Title", item.Name, false)
+	entry.addString(w, "UserName", item.Username, false)
+	entry.addString(w, "Password", item.Password, true)
+	entry.addString(w, "URL", url, false)
+	entry.addString(w, "Notes", item.Notes, false)
+
+	for i, uri := range item.URIs {
+		if i != 0 {
+			entry.addString(w, KDBX_URL_KEY+strconv.Itoa(i), uri.URI, false)
+		}
+	}
+
+	if item.TOTP != nil {
+		entry.addString(w, KDBX_OTP_KEY, kdbxOTPURI(item.TOTP), true)
+	}
+
+	fieldKeys := make([]string, 0, len(item.Fields))
+	for key := range item.Fields {
+		fieldKeys = append(fieldKeys, key)
+	}
+	slices.Sort(fieldKeys)
+
+	for _, key := range fieldKeys {
+		entry.addString(w, key, item.Fields[key], slices.Contains(kdbxProtectedFields, key))
+	}
+
+	for _, customField := range item.CustomFields {
+		entry.addString(w, customField.Name, customField.Value, customField.Type == importer.CUSTOM_FIELD_HIDDEN)
+	}
+
+	return entry, nil
+}
+
+// Keys are unique within an entry, repeated ones are numbered.
+func (e *kdbxEntry) addString(w *kdbxWriter, key string, value string, protected bool) {
+	uniqueKey := key
+	for i := 2; e.hasString(uniqueKey) || len(uniqueKey) == 0; i++ {
+		uniqueKey = key + " (" + strconv.Itoa(i) + ")"
+	}
+
+	entryString := kdbxString{
+		Key: uniqueKey,
+	}
+	entryString.Value.Value = value
+
+	if protected {
+		protectedValue := []byte(value)
+		w.innerStream.XORKeyStream(protectedValue, protectedValue)
+		entryString.Value.Value = base64.StdEncoding.EncodeToString(protectedValue)
+		entryString.Value.Protected = KDBX_PROTECTED
+	}
+
+	e.Strings = append(e.Strings, entryString)
+}
+
+func (e *kdbxEntry) hasString(key string) bool {
+	for _, entryString := range e.Strings {
+		if entryString.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func kdbxOTPURI(totp *importer.TOTP) string {
+	query := url.Values{}
+	query.Set("secret", totp.Secret)
+
+	if len(totp.Issuer) != 0 {
+		query.Set("issuer", totp.Issuer)
+	}
+
+	if len(totp.Algorithm) != 0 {
+		query.Set("algorithm", totp.Algorithm)
+	}
+
+	if totp.Digits != 0 {
+		query.Set("digits", strconv.Itoa(int(totp.Digits)))
+	}
+
+	if totp.Period != 0 {
+		query.Set("period", strconv.Itoa(int(totp.Period)))
+	}
+
+	label := totp.Account
+	if len(totp.Issuer) != 0 {
+		label = totp.Issuer + ":" + totp.Account
+	}
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + label,
+		RawQuery: query.Encode(),
+	}
+
+	return uri.String()
+}
+
+func kdbxUUID() (string, error) {
+	uuid, err := randomBytes(KDBX_UUID_SIZE)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(uuid), nil
+}
+
+func randomBytes(size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,219 @@
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	LOWERCASE_CHARACTERS = "abcdefghijklmnopqrstuvwxyz"
+	UPPERCASE_CHARACTERS = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DIGIT_CHARACTERS     = "0123456789"
+	SYMBOL_CHARACTERS    = "!@#$%^&*"
+	AMBIGUOUS_CHARACTERS = "IlO01"
+)
+
+var ErrInvalidOptions = errors.New("invalid generator options")
+
+// A character class is used when enabled or when it has a minimum count.
+type PasswordOptions struct {
+	Length         int  `json:"length"`
+	Lowercase      bool `json:"lowercase"`
+	Uppercase      bool `json:"uppercase"`
+	Digits         bool `json:"digits"`
+	Symbols        bool `json:"symbols"`
+	MinLowercase   int  `json:"minLowercase"`
+	MinUppercase   int  `json:"minUppercase"`
+	MinDigits      int  `json:"minDigits"`
+	MinSymbols     int  `json:"minSymbols"`
+	AvoidAmbiguous bool `json:"avoidAmbiguous"`
+}
+
+type PassphraseOptions struct {
+	WordCount     int    `json:"wordCount"`
+	Separator     string `json:"separator"`
+	Capitalize    bool   `json:"capitalize"`
+	IncludeNumber bool   `json:"includeNumber"`
+}
+
+// Organization policies only ever make the options stronger, a zero policy
+// leaves them untouched.
+type Policy struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigits    bool
+	RequireSymbols   bool
+	MinDigits        int
+	MinSymbols       int
+	MinWordCount     int
+	Capitalize       bool
+	IncludeNumber    bool
+}
+
+type characterClass struct {
+	characters string
+	minCount   int
+}
+
+func (p Policy) ClampPassword(options PasswordOptions) PasswordOptions {
+	options.Lowercase = options.Lowercase || p.RequireLowercase
+	options.Uppercase = options.Uppercase || p.RequireUppercase
+	options.Digits = options.Digits || p.RequireDigits
+	options.Symbols = options.Symbols || p.RequireSymbols
+	options.MinDigits = max(options.MinDigits, p.MinDigits)
+	options.MinSymbols = max(options.MinSymbols, p.MinSymbols)
+
+	// Enabling a class does not guarantee it is drawn, required classes get at
+	// least one character
+	if p.RequireLowercase {
+		options.MinLowercase = max(options.MinLowercase, 1)
+	}
+	if p.RequireUppercase {
+		options.MinUppercase = max(options.MinUppercase, 1)
+	}
+	if p.RequireDigits {
+		options.MinDigits = max(options.MinDigits, 1)
+	}
+	if p.RequireSymbols {
+		options.MinSymbols = max(options.MinSymbols, 1)
+	}
+
+	// Minimum counts can not be met by a shorter password
+	minLength := options.MinLowercase + options.MinUppercase + options.MinDigits + options.MinSymbols
+	options.Length = max(options.Length, p.MinLength, minLength)
+
+	return options
+}
+
+func (p Policy) ClampPassphrase(options PassphraseOptions) PassphraseOptions {
+	options.WordCount = max(options.WordCount, p.MinWordCount)
+	options.Capitalize = options.Capitalize || p.Capitalize
+	options.IncludeNumber = options.IncludeNumber || p.IncludeNumber
+
+	return options
+}
+
+// Minimum counts are drawn first from their class, the rest of the password
+// from every used class, before the characters are shuffled.
+func Password(options PasswordOptions) (string, error) {
+	classes := []characterClass{
+		{LOWERCASE_CHARACTERS, options.MinLowercase},
+		{UPPERCASE_CHARACTERS, options.MinUppercase},
+		{DIGIT_CHARACTERS, options.MinDigits},
+		{SYMBOL_CHARACTERS, options.MinSymbols},
+	}
+	enabled := []bool{options.Lowercase, options.Uppercase, options.Digits, options.Symbols}
+
+	var password []byte
+	var characters string
+	for i, class := range classes {
+		if !enabled[i] && class.minCount == 0 {
+			continue
+		}
+
+		if options.AvoidAmbiguous {
+			class.characters = removeCharacters(class.characters, AMBIGUOUS_CHARACTERS)
+		}
+		characters += class.characters
+
+		for range class.minCount {
+			character, err := randomCharacter(class.characters)
+			if err != nil {
+				return "", err
+			}
+
+			password = append(password, character)
+		}
+	}
+
+	if len(characters) == 0 || len(password) > options.Length {
+		return "", ErrInvalidOptions
+	}
+
+	for len(password) < options.Length {
+		character, err := randomCharacter(characters)
+		if err != nil {
+			return "", err
+		}
+
+		password = append(password, character)
+	}
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+// The number is a single digit appended to one of the words.
+func (w Wordlist) Passphrase(options PassphraseOptions) (string, error) {
+	if options.WordCount <= 0 || len(w) == 0 {
+		return "", ErrInvalidOptions
+	}
+
+	words := make([]string, options.WordCount)
+	for i := range words {
+		index, err := randomInt(len(w))
+		if err != nil {
+			return "", err
+		}
+
+		words[i] = w[index]
+		if options.Capitalize {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+
+	if options.IncludeNumber {
+		index, err := randomInt(len(words))
+		if err != nil {
+			return "", err
+		}
+
+		digit, err := randomCharacter(DIGIT_CHARACTERS)
+		if err != nil {
+			return "", err
+		}
+
+		words[index] += string(digit)
+	}
+
+	return strings.Join(words, options.Separator), nil
+}
+
+func removeCharacters(characters string, removed string) string {
+	return strings.Map(func(character rune) rune {
+		if strings.ContainsRune(removed, character) {
+			return -1
+		}
+
+		return character
+	}, characters)
+}
+
+func randomCharacter(characters string) (byte, error) {
+	index, err := randomInt(len(characters))
+	if err != nil {
+		return 0, err
+	}
+
+	return characters[index], nil
+}
+
+func randomInt(n int) (int, error) {
+	value, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(value.Int64()), nil
+}
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func countCharacters(password string, characters string) int {
+	count := 0
+	for _, character := range password {
+		if strings.ContainsRune(characters, character) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestPassword(t *testing.T) {
+	for range 100 {
+		password, err := Password(PasswordOptions{
+			Length:         12,
+			Lowercase:      true,
+			MinUppercase:   2,
+			MinDigits:      3,
+			MinSymbols:     1,
+			AvoidAmbiguous: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(password) != 12 || countCharacters(password, UPPERCASE_CHARACTERS) < 2 || countCharacters(password, DIGIT_CHARACTERS) < 3 || countCharacters(password, SYMBOL_CHARACTERS) < 1 {
+			t.Fatalf("unexpected password: %q", password)
+		}
+
+		if countCharacters(password, AMBIGUOUS_CHARACTERS) != 0 {
+			t.Fatalf("ambiguous characters in password: %q", password)
+		}
+	}
+
+	password, err := Password(PasswordOptions{Length: 32, Digits: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if countCharacters(password, DIGIT_CHARACTERS) != 32 {
+		t.Fatalf("unexpected password: %q", password)
+	}
+
+	if _, err := Password(PasswordOptions{Length: 12}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("password generated without characters: %v", err)
+	}
+
+	if _, err := Password(PasswordOptions{Length: 2, MinDigits: 3}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("password generated shorter than its minimums: %v", err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := Policy{
+		MinLength:      16,
+		RequireSymbols: true,
+		MinDigits:      2,
+		MinWordCount:   5,
+		Capitalize:     true,
+	}
+
+	options := policy.ClampPassword(PasswordOptions{Length: 8, Lowercase: true, MinDigits: 1})
+	if options.Length != 16 || !options.Lowercase || !options.Symbols || options.MinSymbols != 1 || options.MinDigits != 2 {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+
+	options = policy.ClampPassword(PasswordOptions{Length: 20, MinUppercase: 20})
+	if options.Length != 23 {
+		t.Fatalf("unexpected length: %d", options.Length)
+	}
+
+	passphraseOptions := policy.ClampPassphrase(PassphraseOptions{WordCount: 3, IncludeNumber: true})
+	if passphraseOptions.WordCount != 5 || !passphraseOptions.Capitalize || !passphraseOptions.IncludeNumber {
+		t.Fatalf("unexpected options: %+v", passphraseOptions)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	wordlist, err := ParseWordlist(strings.NewReader("11111\tabacus\n11112\tabdomen\n\n11113\tabdominal\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(wordlist) != 3 {
+		t.Fatalf("unexpected wordlist: %v", wordlist)
+	}
+
+	passphrase, err := wordlist.Passphrase(PassphraseOptions{
+		WordCount:     6,
+		Separator:     "-",
+		Capitalize:    true,
+		IncludeNumber: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words := strings.Split(passphrase, "-")
+	if len(words) != 6 || countCharacters(passphrase, DIGIT_CHARACTERS) != 1 {
+		t.Fatalf("unexpected passphrase: %q", passphrase)
+	}
+
+	for _, word := range words {
+		if !unicode.IsUpper(rune(word[0])) {
+			t.Fatalf("word not capitalized: %q", passphrase)
+		}
+	}
+
+	if _, err := ParseWordlist(strings.NewReader("abacus\nabacus\n")); err == nil {
+		t.Fatal("wordlist with duplicates parsed")
+	}
+}
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// The EFF large wordlist, https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt,
+// has 7776 words, about 12.9 bits of entropy each.
+type Wordlist []string
+
+func LoadWordlist(path string) (Wordlist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseWordlist(file)
+}
+
+// Lines hold a word, optionally preceded by its dice roll as in the EFF lists.
+// Duplicates would skew the draw and are refused.
+func ParseWordlist(r io.Reader) (Wordlist, error) {
+	var wordlist Wordlist
+	words := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		word := fields[len(fields)-1]
+		if words[word] {
+			return nil, errors.New("duplicate word " + word)
+		}
+
+		words[word] = true
+		wordlist = append(wordlist, word)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(wordlist) == 0 {
+		return nil, errors.New("empty wordlist")
+	}
+
+	return wordlist, nil
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+const (
+	BITWARDEN_TYPE_LOGIN       = 1
+	BITWARDEN_TYPE_SECURE_NOTE = 2
+	BITWARDEN_TYPE_CARD        = 3
+	BITWARDEN_TYPE_IDENTITY    = 4
+	BITWARDEN_TYPE_SSH_KEY     = 5
+)
+
+const (
+	BITWARDEN_FIELD_TEXT    = 0
+	BITWARDEN_FIELD_HIDDEN  = 1
+	BITWARDEN_FIELD_BOOLEAN = 2
+)
+
+const BITWARDEN_FOLDER_SEPARATOR = "/"
+
+// Indexed by the Bitwarden URI match detection, "never" has no equivalent.
+var bitwardenURIMatches = []string{"base_domain", "host", "starts_with", "exact", "regex", ""}
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	FolderID *string `json:"folderId"`
+	Type     int     `json:"type"`
+	Name     string  `json:"name"`
+	Notes    string  `json:"notes"`
+	Fields   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login *struct {
+		URIs []struct {
+			URI   string `json:"uri"`
+			Match *int   `json:"match"`
+		} `json:"uris"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+	} `json:"login"`
+	Card     map[string]any `json:"card"`
+	Identity map[string]any `json:"identity"`
+	SSHKey   map[string]any `json:"sshKey"`
+}
+
+// Bitwarden cards and identities share the keys of entry fields.
+var bitwardenCardFields = []string{"cardholderName", "brand", "number", "expMonth", "expYear", "code"}
+
+var bitwardenIdentityFields = []string{"title", "firstName", "middleName", "lastName", "company", "email", "phone", "address1", "address2", "city", "state", "postalCode", "country", "ssn", "passportNumber", "licenseNumber"}
+
+// Nested folders are named after their path in Bitwarden exports. Password
+// protected and account encrypted exports can not be read, linked custom
+// fields are dropped since they only point to other fields.
+func parseBitwarden(data []byte) (*Vault, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	if export.Encrypted {
+		return nil, errors.New("encrypted bitwarden exports are not supported")
+	}
+
+	vault := &Vault{}
+	paths := newFolderPaths(vault, BITWARDEN_FOLDER_SEPARATOR)
+	folderRefs := make(map[string]string)
+	for _, folder := range export.Folders {
+		folderRefs[folder.ID] = paths.ref(folder.Name)
+	}
+
+	for i, bitwardenItem := range export.Items {
+		item := Item{
+			Row:   i + 1,
+			Name:  bitwardenItem.Name,
+			Notes: bitwardenItem.Notes,
+		}
+
+		if bitwardenItem.FolderID != nil {
+			item.FolderRef = folderRefs[*bitwardenItem.FolderID]
+		}
+
+		switch bitwardenItem.Type {
+		case BITWARDEN_TYPE_LOGIN:
+			item.Type = ITEM_TYPE_LOGIN
+			if bitwardenItem.Login != nil {
+				item.Username = bitwardenItem.Login.Username
+				item.Password = bitwardenItem.Login.Password
+				item.setTOTP(bitwardenItem.Login.TOTP)
+				for _, uri := range bitwardenItem.Login.URIs {
+					match := ""
+					if uri.Match != nil && *uri.Match >= 0 && *uri.Match < len(bitwardenURIMatches) {
+						match = bitwardenURIMatches[*uri.Match]
+					}
+
+					item.addURI(uri.URI, match)
+				}
+			}
+		case BITWARDEN_TYPE_SECURE_NOTE:
+			item.Type = ITEM_TYPE_SECURE_NOTE
+		case BITWARDEN_TYPE_CARD:
+			item.Type = ITEM_TYPE_CARD
+			for _, key := range bitwardenCardFields {
+				item.setField(key, jsonString(bitwardenItem.Card[key]))
+			}
+		case BITWARDEN_TYPE_IDENTITY:
+			item.Type = ITEM_TYPE_IDENTITY
+			for _, key := range bitwardenIdentityFields {
+				item.setField(key, jsonString(bitwardenItem.Identity[key]))
+			}
+
+			if address3 := jsonString(bitwardenItem.Identity["address3"]); len(address3) != 0 {
+				item.addCustomField(CUSTOM_FIELD_TEXT, "address3", address3)
+			}
+
+			if username := jsonString(bitwardenItem.Identity["username"]); len(username) != 0 {
+				item.addCustomField(CUSTOM_FIELD_TEXT, "username", username)
+			}
+		case BITWARDEN_TYPE_SSH_KEY:
+			item.Type = ITEM_TYPE_SSH_KEY
+			item.setField("privateKey", jsonString(bitwardenItem.SSHKey["privateKey"]))
+			item.setField("publicKey", jsonString(bitwardenItem.SSHKey["publicKey"]))
+			item.setField("fingerprint", jsonString(bitwardenItem.SSHKey["keyFingerprint"]))
+		default:
+			vault.skip(item.Row, item.Name, "unsupported item type "+strconv.Itoa(bitwardenItem.Type))
+			continue
+		}
+
+		for _, field := range bitwardenItem.Fields {
+			switch field.Type {
+			case BITWARDEN_FIELD_TEXT:
+				item.addCustomField(CUSTOM_FIELD_TEXT, field.Name, field.Value)
+			case BITWARDEN_FIELD_HIDDEN:
+				item.addCustomField(CUSTOM_FIELD_HIDDEN, field.Name, field.Value)
+			case BITWARDEN_FIELD_BOOLEAN:
+				item.addCustomField(CUSTOM_FIELD_BOOLEAN, field.Name, field.Value)
+			}
+		}
+
+		vault.addItem(item)
+	}
+
+	return vault, nil
+}
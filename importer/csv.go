@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+const CSV_BYTE_ORDER_MARK = "\ufeff"
+
+const (
+	LASTPASS_SECURE_NOTE_URL  = "http://sn"
+	LASTPASS_FOLDER_SEPARATOR = "\\"
+)
+
+// Records are read by column name since the columns of the exports changed
+// across versions of the exporting applications.
+type csvRecord struct {
+	row     int
+	columns map[string]int
+	values  []string
+}
+
+func (r *csvRecord) get(column string) string {
+	index, ok := r.columns[column]
+	if !ok || index >= len(r.values) {
+		return ""
+	}
+
+	return r.values[index]
+}
+
+func readCSV(data []byte, requiredColumns []string, parseRecord func(record csvRecord)) error {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte(CSV_BYTE_ORDER_MARK))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, column := range requiredColumns {
+		if _, ok := columns[column]; !ok {
+			return errors.New("missing column " + column)
+		}
+	}
+
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		parseRecord(csvRecord{
+			row:     line,
+			columns: columns,
+			values:  values,
+		})
+	}
+}
+
+// Secure notes of LastPass have a fake URL, the content of typed ones such as
+// cards is kept in the notes as LastPass formats it.
+func parseLastPass(data []byte) (*Vault, error) {
+	vault := &Vault{}
+	paths := newFolderPaths(vault, LASTPASS_FOLDER_SEPARATOR)
+	err := readCSV(data, []string{"url", "username", "password", "name"}, func(record csvRecord) {
+		item := Item{
+			Row:       record.row,
+			Type:      ITEM_TYPE_LOGIN,
+			Name:      record.get("name"),
+			Notes:     record.get("extra"),
+			FolderRef: paths.ref(record.get("grouping")),
+		}
+
+		if record.get("url") == LASTPASS_SECURE_NOTE_URL {
+			item.Type = ITEM_TYPE_SECURE_NOTE
+		} else {
+			item.Username = record.get("username")
+			item.Password = record.get("password")
+			item.setTOTP(record.get("totp"))
+			item.addURI(record.get("url"), "")
+		}
+
+		vault.addItem(item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vault, nil
+}
+
+func parseChrome(data []byte) (*Vault, error) {
+	vault := &Vault{}
+	err := readCSV(data, []string{"url", "username", "password"}, func(record csvRecord) {
+		item := Item{
+			Row:      record.row,
+			Type:     ITEM_TYPE_LOGIN,
+			Name:     record.get("name"),
+			Username: record.get("username"),
+			Password: record.get("password"),
+			Notes:    record.get("note"),
+		}
+		item.addURI(record.get("url"), "")
+
+		vault.addItem(item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vault, nil
+}
+
+// Firefox logins have no name, they are named after their URL.
+func parseFirefox(data []byte) (*Vault, error) {
+	vault := &Vault{}
+	err := readCSV(data, []string{"url", "username", "password"}, func(record csvRecord) {
+		item := Item{
+			Row:      record.row,
+			Type:     ITEM_TYPE_LOGIN,
+			Username: record.get("username"),
+			Password: record.get("password"),
+		}
+		item.addURI(record.get("url"), "")
+
+		vault.addItem(item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vault, nil
+}
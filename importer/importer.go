@@ -0,0 +1,354 @@
+package importer
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	FORMAT_BITWARDEN_JSON = "bitwarden_json"
+	FORMAT_KEEPASS_XML    = "keepass_xml"
+	FORMAT_1PASSWORD_1PUX = "1password_1pux"
+	FORMAT_LASTPASS_CSV   = "lastpass_csv"
+	FORMAT_CHROME_CSV     = "chrome_csv"
+	FORMAT_FIREFOX_CSV    = "firefox_csv"
+)
+
+// Same types as entries.
+const (
+	ITEM_TYPE_LOGIN          = "login"
+	ITEM_TYPE_SECURE_NOTE    = "secure_note"
+	ITEM_TYPE_CARD           = "card"
+	ITEM_TYPE_IDENTITY       = "identity"
+	ITEM_TYPE_SSH_KEY        = "ssh_key"
+	ITEM_TYPE_API_CREDENTIAL = "api_credential"
+)
+
+const (
+	CUSTOM_FIELD_TEXT    = "text"
+	CUSTOM_FIELD_HIDDEN  = "hidden"
+	CUSTOM_FIELD_BOOLEAN = "boolean"
+)
+
+const (
+	REPORT_STATUS_SKIPPED = "skipped"
+	REPORT_STATUS_FAILED  = "failed"
+)
+
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Folders reference their parent through its ref, folders without one go
+// under the folder chosen for the import.
+type Folder struct {
+	Ref       string `json:"ref"`
+	Name      string `json:"name"`
+	ParentRef string `json:"parentRef,omitempty"`
+}
+
+type URI struct {
+	URI   string `json:"uri"`
+	Match string `json:"match,omitempty"`
+}
+
+type CustomField struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+type TOTP struct {
+	Secret    string `json:"secret"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int32  `json:"digits,omitempty"`
+	Period    int32  `json:"period,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+}
+
+// Items have the shape of the entries created through the API, in plain text.
+// The row locates the item in the imported file: the line of CSV files and
+// the position of the item for the others, starting at 1.
+type Item struct {
+	Row          int               `json:"row"`
+	Type         string            `json:"type"`
+	Name         string            `json:"name"`
+	Username     string            `json:"username,omitempty"`
+	Password     string            `json:"password,omitempty"`
+	TOTP         *TOTP             `json:"totp,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+	URIs         []URI             `json:"uris,omitempty"`
+	CustomFields []CustomField     `json:"customFields,omitempty"`
+	Fields       map[string]string `json:"fields,omitempty"`
+	FolderRef    string            `json:"folderRef,omitempty"`
+}
+
+type ReportRow struct {
+	Row    int    `json:"row"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type Vault struct {
+	Folders []Folder    `json:"folders"`
+	Items   []Item      `json:"items"`
+	Report  []ReportRow `json:"report"`
+}
+
+// Parses an export of another password manager. Clients run it on the file
+// and encrypt the items before importing them, the file never reaches the
+// server. Items that can not be imported are left out and reported, the error
+// is only set when the file itself can not be read.
+func Parse(format string, data []byte) (Vault, error) {
+	var vault *Vault
+	var err error
+	switch format {
+	case FORMAT_BITWARDEN_JSON:
+		vault, err = parseBitwarden(data)
+	case FORMAT_KEEPASS_XML:
+		vault, err = parseKeePass(data)
+	case FORMAT_1PASSWORD_1PUX:
+		vault, err = parseOnePassword(data)
+	case FORMAT_LASTPASS_CSV:
+		vault, err = parseLastPass(data)
+	case FORMAT_CHROME_CSV:
+		vault, err = parseChrome(data)
+	case FORMAT_FIREFOX_CSV:
+		vault, err = parseFirefox(data)
+	default:
+		return Vault{}, ErrUnknownFormat
+	}
+	if err != nil {
+		return Vault{}, err
+	}
+
+	if vault.Folders == nil {
+		vault.Folders = []Folder{}
+	}
+
+	if vault.Items == nil {
+		vault.Items = []Item{}
+	}
+
+	if vault.Report == nil {
+		vault.Report = []ReportRow{}
+	}
+
+	return *vault, nil
+}
+
+// Items without a name are named after their first URI, items without a name
+// nor any content are skipped.
+func (v *Vault) addItem(item Item) {
+	if len(item.Name) == 0 && len(item.URIs) != 0 {
+		item.Name = uriName(item.URIs[0].URI)
+	}
+
+	if len(item.Name) == 0 {
+		if item.isEmpty() {
+			v.skip(item.Row, "", "empty item")
+			return
+		}
+
+		v.fail(item.Row, "", "missing name")
+		return
+	}
+
+	v.Items = append(v.Items, item)
+}
+
+func (v *Vault) skip(row int, name string, reason string) {
+	v.Report = append(v.Report, ReportRow{
+		Row:    row,
+		Name:   name,
+		Status: REPORT_STATUS_SKIPPED,
+		Reason: reason,
+	})
+}
+
+func (v *Vault) fail(row int, name string, reason string) {
+	v.Report = append(v.Report, ReportRow{
+		Row:    row,
+		Name:   name,
+		Status: REPORT_STATUS_FAILED,
+		Reason: reason,
+	})
+}
+
+// Builds folders from paths such as "Work/Servers", returning the ref of the
+// last one. Folders are created once per path.
+type folderPaths struct {
+	vault     *Vault
+	separator string
+	refs      map[string]string
+}
+
+func newFolderPaths(vault *Vault, separator string) *folderPaths {
+	return &folderPaths{
+		vault:     vault,
+		separator: separator,
+		refs:      make(map[string]string),
+	}
+}
+
+func (p *folderPaths) ref(path string) string {
+	parentRef := ""
+	currentPath := ""
+	for _, name := range strings.Split(path, p.separator) {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+
+		currentPath += p.separator + name
+		ref, ok := p.refs[currentPath]
+		if !ok {
+			ref = p.vault.addFolder(name, parentRef)
+			p.refs[currentPath] = ref
+		}
+
+		parentRef = ref
+	}
+
+	return parentRef
+}
+
+func (v *Vault) addFolder(name string, parentRef string) string {
+	ref := strconv.Itoa(len(v.Folders) + 1)
+	v.Folders = append(v.Folders, Folder{
+		Ref:       ref,
+		Name:      name,
+		ParentRef: parentRef,
+	})
+
+	return ref
+}
+
+func (i *Item) isEmpty() bool {
+	return len(i.Username) == 0 && len(i.Password) == 0 && i.TOTP == nil && len(i.Notes) == 0 && len(i.URIs) == 0 && len(i.CustomFields) == 0 && len(i.Fields) == 0
+}
+
+// Values found in several places keep the first one.
+func (i *Item) setField(key string, value string) {
+	if len(value) == 0 {
+		return
+	}
+
+	if i.Fields == nil {
+		i.Fields = make(map[string]string)
+	}
+
+	if _, ok := i.Fields[key]; !ok {
+		i.Fields[key] = value
+	}
+}
+
+func (i *Item) addURI(uri string, match string) {
+	uri = strings.TrimSpace(uri)
+	if len(uri) == 0 {
+		return
+	}
+
+	i.URIs = append(i.URIs, URI{
+		URI:   uri,
+		Match: match,
+	})
+}
+
+func (i *Item) addCustomField(fieldType string, name string, value string) {
+	if len(name) == 0 && len(value) == 0 {
+		return
+	}
+
+	i.CustomFields = append(i.CustomFields, CustomField{
+		Type:  fieldType,
+		Name:  name,
+		Value: value,
+	})
+}
+
+// Only logins hold a TOTP, the secret of other items is kept as a hidden
+// custom field.
+func (i *Item) setTOTP(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return
+	}
+
+	if i.Type != ITEM_TYPE_LOGIN {
+		i.addCustomField(CUSTOM_FIELD_HIDDEN, "TOTP", value)
+		return
+	}
+
+	i.TOTP = parseTOTP(value)
+}
+
+// Accepts otpauth:// URIs as well as bare secrets. Unsupported parameters are
+// dropped, the client then applies the defaults of the URI format, and URIs
+// without a secret are ignored.
+func parseTOTP(value string) *TOTP {
+	uri, err := url.Parse(value)
+	if err != nil || uri.Scheme != "otpauth" {
+		return &TOTP{
+			Secret: value,
+		}
+	}
+
+	query := uri.Query()
+	if len(query.Get("secret")) == 0 {
+		return nil
+	}
+
+	totp := TOTP{
+		Secret: query.Get("secret"),
+		Issuer: query.Get("issuer"),
+	}
+
+	label := strings.TrimPrefix(uri.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		if len(totp.Issuer) == 0 {
+			totp.Issuer = strings.TrimSpace(issuer)
+		}
+		totp.Account = strings.TrimSpace(account)
+	} else {
+		totp.Account = label
+	}
+
+	switch algorithm := strings.ToUpper(query.Get("algorithm")); algorithm {
+	case "SHA1", "SHA256", "SHA512":
+		totp.Algorithm = algorithm
+	}
+
+	if digits, err := strconv.ParseInt(query.Get("digits"), 10, 32); err == nil && digits >= 6 && digits <= 8 {
+		totp.Digits = int32(digits)
+	}
+
+	if period, err := strconv.ParseInt(query.Get("period"), 10, 32); err == nil && period >= 1 && period <= 300 {
+		totp.Period = int32(period)
+	}
+
+	return &totp
+}
+
+func uriName(uri string) string {
+	parsedURI, err := url.Parse(uri)
+	if err != nil || len(parsedURI.Hostname()) == 0 {
+		return uri
+	}
+
+	return parsedURI.Hostname()
+}
+
+// Numbers are kept as text, other values are dropped.
+func jsonString(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,262 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func parse(t *testing.T, format string, data []byte) Vault {
+	vault, err := Parse(format, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return vault
+}
+
+func findItem(t *testing.T, vault Vault, name string) Item {
+	for _, item := range vault.Items {
+		if item.Name == name {
+			return item
+		}
+	}
+
+	t.Fatalf("missing item %q", name)
+	return Item{}
+}
+
+func findFolder(t *testing.T, vault Vault, ref string) Folder {
+	for _, folder := range vault.Folders {
+		if folder.Ref == ref {
+			return folder
+		}
+	}
+
+	t.Fatalf("missing folder %q", ref)
+	return Folder{}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := Parse("unknown", nil); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseTOTP(t *testing.T) {
+	totp := parseTOTP("otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&algorithm=sha256&digits=8&period=60")
+	if totp == nil || totp.Secret != "JBSWY3DPEHPK3PXP" || totp.Issuer != "Example" || totp.Account != "alice@example.com" || totp.Algorithm != "SHA256" || totp.Digits != 8 || totp.Period != 60 {
+		t.Fatalf("unexpected totp: %+v", totp)
+	}
+
+	totp = parseTOTP("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5&digits=12")
+	if totp == nil || totp.Account != "alice" || len(totp.Algorithm) != 0 || totp.Digits != 0 {
+		t.Fatalf("unexpected totp: %+v", totp)
+	}
+
+	if totp := parseTOTP("JBSWY3DPEHPK3PXP"); totp == nil || totp.Secret != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("unexpected totp: %+v", totp)
+	}
+
+	if totp := parseTOTP("otpauth://totp/alice"); totp != nil {
+		t.Fatalf("totp without secret parsed: %+v", totp)
+	}
+}
+
+func TestParseBitwarden(t *testing.T) {
+	vault := parse(t, FORMAT_BITWARDEN_JSON, []byte(`{
+		"encrypted": false,
+		"folders": [{"id": "a", "name": "Work/Servers"}],
+		"items": [
+			{
+				"type": 1, "name": "Server", "folderId": "a", "notes": null,
+				"fields": [{"name": "pin", "value": "1234", "type": 1}, {"name": "linked", "value": null, "type": 3}],
+				"login": {"username": "root", "password": "secret", "totp": "JBSWY3DPEHPK3PXP", "uris": [{"uri": "https://example.com", "match": 1}]}
+			},
+			{"type": 3, "name": "Card", "card": {"cardholderName": "Alice", "number": "4111111111111111", "expMonth": "1", "expYear": "2030", "code": "123"}},
+			{"type": 2, "name": "Note", "notes": "content", "secureNote": {"type": 0}},
+			{"type": 9, "name": "Unknown"},
+			{"type": 1, "name": "", "login": {}}
+		]
+	}`))
+
+	if len(vault.Folders) != 2 {
+		t.Fatalf("unexpected folders: %+v", vault.Folders)
+	}
+
+	server := findItem(t, vault, "Server")
+	if server.Type != ITEM_TYPE_LOGIN || server.Username != "root" || server.Password != "secret" || server.TOTP == nil || server.TOTP.Secret != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("unexpected login: %+v", server)
+	}
+
+	if len(server.URIs) != 1 || server.URIs[0].Match != "host" {
+		t.Fatalf("unexpected uris: %+v", server.URIs)
+	}
+
+	if len(server.CustomFields) != 1 || server.CustomFields[0].Type != CUSTOM_FIELD_HIDDEN {
+		t.Fatalf("unexpected custom fields: %+v", server.CustomFields)
+	}
+
+	folder := findFolder(t, vault, server.FolderRef)
+	if folder.Name != "Servers" || findFolder(t, vault, folder.ParentRef).Name != "Work" {
+		t.Fatalf("unexpected folder: %+v", folder)
+	}
+
+	card := findItem(t, vault, "Card")
+	if card.Type != ITEM_TYPE_CARD || card.Fields["number"] != "4111111111111111" || card.Fields["code"] != "123" {
+		t.Fatalf("unexpected card: %+v", card)
+	}
+
+	if note := findItem(t, vault, "Note"); note.Type != ITEM_TYPE_SECURE_NOTE || note.Notes != "content" {
+		t.Fatalf("unexpected note: %+v", note)
+	}
+
+	if len(vault.Report) != 2 || vault.Report[0].Row != 4 || vault.Report[1].Row != 5 || vault.Report[1].Status != REPORT_STATUS_SKIPPED {
+		t.Fatalf("unexpected report: %+v", vault.Report)
+	}
+
+	if _, err := Parse(FORMAT_BITWARDEN_JSON, []byte(`{"encrypted": true}`)); err == nil {
+		t.Fatal("encrypted export parsed")
+	}
+}
+
+func TestParseKeePass(t *testing.T) {
+	vault := parse(t, FORMAT_KEEPASS_XML, []byte(`<?xml version="1.0" encoding="utf-8"?>
+<KeePassFile>
+	<Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+	<Root>
+		<Group>
+			<UUID>root</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>Mail</Value></String>
+				<String><Key>UserName</Key><Value>alice</Value></String>
+				<String><Key>Password</Key><Value ProtectInMemory="True">secret</Value></String>
+				<String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+				<String><Key>Recovery</Key><Value ProtectInMemory="True">code</Value></String>
+			</Entry>
+			<Group>
+				<UUID>work</UUID>
+				<Name>Work</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>VPN</Value></String>
+					<String><Key>otp</Key><Value>otpauth://totp/VPN?secret=JBSWY3DPEHPK3PXP</Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>bin</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>Old</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`))
+
+	mail := findItem(t, vault, "Mail")
+	if mail.Username != "alice" || mail.Password != "secret" || len(mail.FolderRef) != 0 {
+		t.Fatalf("unexpected entry: %+v", mail)
+	}
+
+	if len(mail.CustomFields) != 1 || mail.CustomFields[0].Type != CUSTOM_FIELD_HIDDEN || mail.CustomFields[0].Name != "Recovery" {
+		t.Fatalf("unexpected custom fields: %+v", mail.CustomFields)
+	}
+
+	vpn := findItem(t, vault, "VPN")
+	if vpn.TOTP == nil || findFolder(t, vault, vpn.FolderRef).Name != "Work" {
+		t.Fatalf("unexpected entry: %+v", vpn)
+	}
+
+	if len(vault.Folders) != 1 || len(vault.Report) != 1 || vault.Report[0].Name != "Old" {
+		t.Fatalf("unexpected folders or report: %+v %+v", vault.Folders, vault.Report)
+	}
+}
+
+func TestParseOnePassword(t *testing.T) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	file, err := writer.Create(ONEPASSWORD_DATA_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file.Write([]byte(`{"accounts": [{"vaults": [{"attrs": {"name": "Personal"}, "items": [
+		{
+			"state": "active", "categoryUuid": "001",
+			"overview": {"title": "Bank", "urls": [{"url": "https://bank.example.com"}]},
+			"details": {
+				"loginFields": [{"value": "alice", "designation": "username"}, {"value": "secret", "designation": "password"}],
+				"sections": [{"fields": [{"title": "one-time password", "id": "totp", "value": {"totp": "JBSWY3DPEHPK3PXP"}}]}]
+			}
+		},
+		{
+			"state": "active", "categoryUuid": "002",
+			"overview": {"title": "Visa"},
+			"details": {"sections": [{"fields": [
+				{"title": "number", "id": "ccnum", "value": {"creditCardNumber": "4111111111111111"}},
+				{"title": "expiry", "id": "expiry", "value": {"monthYear": 203012}},
+				{"title": "pin", "id": "pin", "value": {"concealed": "0000"}}
+			]}]}
+		},
+		{"state": "archived", "categoryUuid": "001", "overview": {"title": "Archived"}}
+	]}]}]}`))
+	writer.Close()
+
+	vault := parse(t, FORMAT_1PASSWORD_1PUX, archive.Bytes())
+
+	bank := findItem(t, vault, "Bank")
+	if bank.Username != "alice" || bank.Password != "secret" || bank.TOTP == nil || len(bank.URIs) != 1 {
+		t.Fatalf("unexpected login: %+v", bank)
+	}
+
+	if findFolder(t, vault, bank.FolderRef).Name != "Personal" {
+		t.Fatalf("unexpected folder: %+v", vault.Folders)
+	}
+
+	visa := findItem(t, vault, "Visa")
+	if visa.Fields["number"] != "4111111111111111" || visa.Fields["expMonth"] != "12" || visa.Fields["expYear"] != "2030" {
+		t.Fatalf("unexpected card: %+v", visa)
+	}
+
+	if len(visa.CustomFields) != 1 || visa.CustomFields[0].Type != CUSTOM_FIELD_HIDDEN {
+		t.Fatalf("unexpected custom fields: %+v", visa.CustomFields)
+	}
+
+	if len(vault.Report) != 1 || vault.Report[0].Row != 3 {
+		t.Fatalf("unexpected report: %+v", vault.Report)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	lastPass := parse(t, FORMAT_LASTPASS_CSV, []byte("url,username,password,totp,extra,name,grouping,fav\n"+
+		"https://example.com,alice,secret,,,Example,Work\\Web,0\n"+
+		"http://sn,,,,\"NoteType:Server\nHostname:db\",Database,Work,0\n"))
+
+	example := findItem(t, lastPass, "Example")
+	if example.Row != 2 || example.Username != "alice" || findFolder(t, lastPass, example.FolderRef).Name != "Web" {
+		t.Fatalf("unexpected login: %+v", example)
+	}
+
+	database := findItem(t, lastPass, "Database")
+	if database.Row != 3 || database.Type != ITEM_TYPE_SECURE_NOTE || len(database.URIs) != 0 {
+		t.Fatalf("unexpected note: %+v", database)
+	}
+
+	if len(lastPass.Folders) != 2 {
+		t.Fatalf("unexpected folders: %+v", lastPass.Folders)
+	}
+
+	chrome := parse(t, FORMAT_CHROME_CSV, []byte(CSV_BYTE_ORDER_MARK+"name,url,username,password,note\nExample,https://example.com,alice,secret,\n,,,,\n"))
+	if item := findItem(t, chrome, "Example"); item.Password != "secret" || len(chrome.Report) != 1 {
+		t.Fatalf("unexpected items: %+v %+v", chrome.Items, chrome.Report)
+	}
+
+	firefox := parse(t, FORMAT_FIREFOX_CSV, []byte("\"url\",\"username\",\"password\",\"httpRealm\"\n\"https://login.example.com\",\"alice\",\"secret\",\"\"\n"))
+	if item := findItem(t, firefox, "login.example.com"); item.Username != "alice" {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+
+	if _, err := Parse(FORMAT_CHROME_CSV, []byte("name,url\n")); err == nil {
+		t.Fatal("csv without required columns parsed")
+	}
+}
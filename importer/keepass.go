@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	KEEPASS_KEY_TITLE    = "Title"
+	KEEPASS_KEY_USERNAME = "UserName"
+	KEEPASS_KEY_PASSWORD = "Password"
+	KEEPASS_KEY_URL      = "URL"
+	KEEPASS_KEY_NOTES    = "Notes"
+	KEEPASS_KEY_OTP      = "otp"
+)
+
+type keePassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Value     string `xml:",chardata"`
+			Protected string `xml:"ProtectInMemory,attr"`
+		} `xml:"Value"`
+	} `xml:"String"`
+}
+
+type keePassParser struct {
+	vault          *Vault
+	recycleBinUUID string
+	row            int
+}
+
+// Reads KeePass 2 XML exports, whose top level group is the database itself:
+// its entries go in the folder chosen for the import and its subgroups become
+// folders. The recycle bin and the history of entries are left out.
+func parseKeePass(data []byte) (*Vault, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	parser := keePassParser{
+		vault:          &Vault{},
+		recycleBinUUID: file.Meta.RecycleBinUUID,
+	}
+
+	for _, group := range file.Root.Groups {
+		parser.parseGroup(group, "", true)
+	}
+
+	return parser.vault, nil
+}
+
+func (p *keePassParser) parseGroup(group keePassGroup, parentRef string, isRoot bool) {
+	if len(p.recycleBinUUID) != 0 && group.UUID == p.recycleBinUUID {
+		for _, entry := range group.Entries {
+			p.row++
+			p.vault.skip(p.row, keePassString(entry, KEEPASS_KEY_TITLE), "in recycle bin")
+		}
+
+		return
+	}
+
+	folderRef := parentRef
+	if !isRoot {
+		folderRef = p.vault.addFolder(group.Name, parentRef)
+	}
+
+	for _, entry := range group.Entries {
+		p.row++
+		p.parseEntry(entry, folderRef)
+	}
+
+	for _, subgroup := range group.Groups {
+		p.parseGroup(subgroup, folderRef, false)
+	}
+}
+
+// Strings other than the standard ones are kept as custom fields, hidden when
+// protected in memory.
+func (p *keePassParser) parseEntry(entry keePassEntry, folderRef string) {
+	item := Item{
+		Row:       p.row,
+		Type:      ITEM_TYPE_LOGIN,
+		FolderRef: folderRef,
+	}
+
+	for _, entryString := range entry.Strings {
+		value := entryString.Value.Value
+		switch entryString.Key {
+		case KEEPASS_KEY_TITLE:
+			item.Name = value
+		case KEEPASS_KEY_USERNAME:
+			item.Username = value
+		case KEEPASS_KEY_PASSWORD:
+			item.Password = value
+		case KEEPASS_KEY_URL:
+			item.addURI(value, "")
+		case KEEPASS_KEY_NOTES:
+			item.Notes = value
+		case KEEPASS_KEY_OTP:
+			item.setTOTP(value)
+		default:
+			if len(value) == 0 {
+				continue
+			}
+
+			fieldType := CUSTOM_FIELD_TEXT
+			if strings.EqualFold(entryString.Value.Protected, "true") {
+				fieldType = CUSTOM_FIELD_HIDDEN
+			}
+
+			item.addCustomField(fieldType, entryString.Key, value)
+		}
+	}
+
+	p.vault.addItem(item)
+}
+
+func keePassString(entry keePassEntry, key string) string {
+	for _, entryString := range entry.Strings {
+		if entryString.Key == key {
+			return entryString.Value.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,276 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	ONEPASSWORD_DATA_FILE      = "export.data"
+	ONEPASSWORD_STATE_ARCHIVED = "archived"
+)
+
+const (
+	ONEPASSWORD_CATEGORY_LOGIN          = "001"
+	ONEPASSWORD_CATEGORY_CREDIT_CARD    = "002"
+	ONEPASSWORD_CATEGORY_SECURE_NOTE    = "003"
+	ONEPASSWORD_CATEGORY_IDENTITY       = "004"
+	ONEPASSWORD_CATEGORY_PASSWORD       = "005"
+	ONEPASSWORD_CATEGORY_DOCUMENT       = "006"
+	ONEPASSWORD_CATEGORY_API_CREDENTIAL = "112"
+	ONEPASSWORD_CATEGORY_SSH_KEY        = "114"
+)
+
+// Section fields are identified by their id, mapped to the entry fields of
+// the item type. The other ones are kept as custom fields.
+var onePasswordFields = map[string]map[string]string{
+	ITEM_TYPE_CARD: {
+		"cardholder": "cardholderName",
+		"type":       "brand",
+		"ccnum":      "number",
+		"cvv":        "code",
+	},
+	ITEM_TYPE_IDENTITY: {
+		"firstname": "firstName",
+		"initial":   "middleName",
+		"lastname":  "lastName",
+		"company":   "company",
+		"email":     "email",
+		"defphone":  "phone",
+	},
+	ITEM_TYPE_API_CREDENTIAL: {
+		"username":   "clientId",
+		"credential": "secret",
+		"hostname":   "endpoint",
+	},
+}
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	State        string `json:"state"`
+	CategoryUUID string `json:"categoryUuid"`
+	Details      struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Name        string `json:"name"`
+			FieldType   string `json:"fieldType"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                     `json:"title"`
+				ID    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+		Password string `json:"password"`
+	} `json:"details"`
+	Overview struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+}
+
+// Reads 1PUX exports, zip archives holding the items as JSON. Each vault
+// becomes a folder, archived items and documents are left out. Categories
+// without an equivalent type are imported as secure notes.
+func parseOnePassword(data []byte) (*Vault, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	dataFile, err := archive.Open(ONEPASSWORD_DATA_FILE)
+	if err != nil {
+		return nil, errors.New("missing " + ONEPASSWORD_DATA_FILE + " in 1pux archive")
+	}
+	defer dataFile.Close()
+
+	exportData, err := io.ReadAll(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var export onePasswordExport
+	if err := json.Unmarshal(exportData, &export); err != nil {
+		return nil, err
+	}
+
+	vault := &Vault{}
+	row := 0
+	for _, account := range export.Accounts {
+		for _, onePasswordVault := range account.Vaults {
+			folderRef := vault.addFolder(onePasswordVault.Attrs.Name, "")
+			for _, onePasswordItem := range onePasswordVault.Items {
+				row++
+				parseOnePasswordItem(vault, onePasswordItem, row, folderRef)
+			}
+		}
+	}
+
+	return vault, nil
+}
+
+func parseOnePasswordItem(vault *Vault, onePasswordItem onePasswordItem, row int, folderRef string) {
+	item := Item{
+		Row:       row,
+		Name:      onePasswordItem.Overview.Title,
+		Notes:     onePasswordItem.Details.NotesPlain,
+		FolderRef: folderRef,
+	}
+
+	if onePasswordItem.State == ONEPASSWORD_STATE_ARCHIVED {
+		vault.skip(row, item.Name, "archived item")
+		return
+	}
+
+	switch onePasswordItem.CategoryUUID {
+	case ONEPASSWORD_CATEGORY_LOGIN, ONEPASSWORD_CATEGORY_PASSWORD:
+		item.Type = ITEM_TYPE_LOGIN
+	case ONEPASSWORD_CATEGORY_SECURE_NOTE:
+		item.Type = ITEM_TYPE_SECURE_NOTE
+	case ONEPASSWORD_CATEGORY_CREDIT_CARD:
+		item.Type = ITEM_TYPE_CARD
+	case ONEPASSWORD_CATEGORY_IDENTITY:
+		item.Type = ITEM_TYPE_IDENTITY
+	case ONEPASSWORD_CATEGORY_API_CREDENTIAL:
+		item.Type = ITEM_TYPE_API_CREDENTIAL
+	case ONEPASSWORD_CATEGORY_SSH_KEY:
+		item.Type = ITEM_TYPE_SSH_KEY
+	case ONEPASSWORD_CATEGORY_DOCUMENT:
+		vault.skip(row, item.Name, "documents are not supported")
+		return
+	default:
+		item.Type = ITEM_TYPE_SECURE_NOTE
+	}
+
+	if item.Type == ITEM_TYPE_LOGIN {
+		item.Password = onePasswordItem.Details.Password
+		for _, loginField := range onePasswordItem.Details.LoginFields {
+			switch loginField.Designation {
+			case "username":
+				item.Username = loginField.Value
+			case "password":
+				item.Password = loginField.Value
+			}
+		}
+
+		if len(onePasswordItem.Overview.URLs) == 0 {
+			item.addURI(onePasswordItem.Overview.URL, "")
+		}
+
+		for _, url := range onePasswordItem.Overview.URLs {
+			item.addURI(url.URL, "")
+		}
+	}
+
+	fields := onePasswordFields[item.Type]
+	for _, section := range onePasswordItem.Details.Sections {
+		for _, field := range section.Fields {
+			parseOnePasswordField(&item, fields, field.ID, field.Title, field.Value)
+		}
+	}
+
+	vault.addItem(item)
+}
+
+func parseOnePasswordField(item *Item, fields map[string]string, id string, title string, value map[string]json.RawMessage) {
+	for valueType, rawValue := range value {
+		switch valueType {
+		case "totp":
+			var totp string
+			json.Unmarshal(rawValue, &totp)
+			item.setTOTP(totp)
+		case "concealed":
+			var concealed string
+			json.Unmarshal(rawValue, &concealed)
+			if key, ok := fields[id]; ok {
+				item.setField(key, concealed)
+			} else {
+				item.addCustomField(CUSTOM_FIELD_HIDDEN, title, concealed)
+			}
+		case "monthYear":
+			var monthYear int
+			json.Unmarshal(rawValue, &monthYear)
+			if item.Type == ITEM_TYPE_CARD && id == "expiry" && monthYear != 0 {
+				item.setField("expMonth", strconv.Itoa(monthYear%100))
+				item.setField("expYear", strconv.Itoa(monthYear/100))
+			} else if monthYear != 0 {
+				item.addCustomField(CUSTOM_FIELD_TEXT, title, strconv.Itoa(monthYear))
+			}
+		case "address":
+			var address struct {
+				Street  string `json:"street"`
+				City    string `json:"city"`
+				Country string `json:"country"`
+				Zip     string `json:"zip"`
+				State   string `json:"state"`
+			}
+			json.Unmarshal(rawValue, &address)
+			if item.Type == ITEM_TYPE_IDENTITY {
+				item.setField("address1", address.Street)
+				item.setField("city", address.City)
+				item.setField("state", address.State)
+				item.setField("postalCode", address.Zip)
+				item.setField("country", address.Country)
+			} else {
+				item.addCustomField(CUSTOM_FIELD_TEXT, title, address.Street)
+			}
+		case "email":
+			var email struct {
+				EmailAddress string `json:"email_address"`
+			}
+			if err := json.Unmarshal(rawValue, &email); err != nil {
+				json.Unmarshal(rawValue, &email.EmailAddress)
+			}
+			parseOnePasswordString(item, fields, id, title, email.EmailAddress)
+		case "sshKey":
+			var sshKey struct {
+				PrivateKey string `json:"privateKey"`
+				Metadata   struct {
+					PublicKey   string `json:"publicKey"`
+					Fingerprint string `json:"fingerprint"`
+				} `json:"metadata"`
+			}
+			json.Unmarshal(rawValue, &sshKey)
+			if item.Type == ITEM_TYPE_SSH_KEY {
+				item.setField("privateKey", sshKey.PrivateKey)
+				item.setField("publicKey", sshKey.Metadata.PublicKey)
+				item.setField("fingerprint", sshKey.Metadata.Fingerprint)
+			} else {
+				item.addCustomField(CUSTOM_FIELD_HIDDEN, title, sshKey.PrivateKey)
+			}
+		default:
+			var text any
+			json.Unmarshal(rawValue, &text)
+			parseOnePasswordString(item, fields, id, title, jsonString(text))
+		}
+	}
+}
+
+func parseOnePasswordString(item *Item, fields map[string]string, id string, title string, value string) {
+	if key, ok := fields[id]; ok {
+		item.setField(key, value)
+		return
+	}
+
+	item.addCustomField(CUSTOM_FIELD_TEXT, title, value)
+}
@@ -0,0 +1,35 @@
+package schemas
+
+import (
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ATTACHMENT_NAME_HEADER = "X-Attachment-Name"
+	ATTACHMENT_KEY_HEADER  = "X-Attachment-Key"
+)
+
+// The body is the encrypted file, so the attachment name and key are sent as
+// headers and the size is the announced content length.
+type CreateAttachmentInput struct {
+	Name string `reqHeader:"X-Attachment-Name" validate:"required,encstring"`
+	Key  string `reqHeader:"X-Attachment-Key" validate:"required,encstring"`
+	Size int64  `validate:"required,min=1"`
+}
+
+func GetCreateAttachmentInput(c *fiber.Ctx) (CreateAttachmentInput, bool) {
+	var input CreateAttachmentInput
+	if err := c.ReqHeaderParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return CreateAttachmentInput{}, false
+	}
+	input.Size = int64(c.Request().Header.ContentLength())
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return CreateAttachmentInput{}, false
+	}
+
+	return input, true
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/LeonardJouve/pass-secure/auth"
 	"github.com/LeonardJouve/pass-secure/database"
 	"github.com/LeonardJouve/pass-secure/database/queries"
 	"github.com/LeonardJouve/pass-secure/status"
@@ -12,11 +13,20 @@ import (
 )
 
 type RegisterInput struct {
-	Email    string `json:"email" validate:"required,email"`
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required,min=8"`
+	Email              string `json:"email" validate:"required,email"`
+	Username           string `json:"username" validate:"required"`
+	MasterPasswordHash string `json:"masterPasswordHash" validate:"required,base64,len=44"`
+	Key                string `json:"key" validate:"required,encstring"`
+	PublicKey          string `json:"publicKey" validate:"required,rsapublickey"`
+	PrivateKey         string `json:"privateKey" validate:"required,encstring"`
+	// The server never sees the master password, its strength score is
+	// estimated by the client. It is advisory, the server can not verify it
+	MasterPasswordStrength int16 `json:"masterPasswordStrength" validate:"min=0,max=4,password_strength"`
+	KdfInput
 }
 
+// Whether the master password appears in a known breach is checked by the
+// client against the breach range endpoint, the server can not check it.
 func GetRegisterUserInput(c *fiber.Ctx) (queries.CreateUserParams, bool) {
 	var input RegisterInput
 	if err := c.BodyParser(&input); err != nil {
@@ -25,26 +35,56 @@ func GetRegisterUserInput(c *fiber.Ctx) (queries.CreateUserParams, bool) {
 	}
 
 	if err := validate.Struct(input); err != nil {
-		status.BadRequest(c, err)
+		if strengthError, ok := getPasswordStrengthError(err); ok {
+			passwordStrengthError(c, strengthError)
+		} else {
+			status.BadRequest(c, err)
+		}
 		return queries.CreateUserParams{}, false
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.MasterPasswordHash), bcrypt.DefaultCost)
 	if err != nil {
 		status.InternalServerError(c, nil)
 		return queries.CreateUserParams{}, false
 	}
 
 	return queries.CreateUserParams{
-		Email:    input.Email,
-		Username: input.Username,
-		Password: string(hashedPassword),
+		Email:          input.Email,
+		Username:       input.Username,
+		Password:       string(hashedPassword),
+		Kdf:            input.Kdf,
+		KdfIterations:  input.KdfIterations,
+		KdfMemory:      input.KdfMemory,
+		KdfParallelism: input.KdfParallelism,
+		Key:            &input.Key,
+		PublicKey:      &input.PublicKey,
+		PrivateKey:     &input.PrivateKey,
 	}, true
 }
 
+type PreloginInput struct {
+	Email string `json:"email" validate:"required"`
+}
+
+func GetPreloginInput(c *fiber.Ctx) (PreloginInput, bool) {
+	var input PreloginInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return PreloginInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return PreloginInput{}, false
+	}
+
+	return input, true
+}
+
 type LoginInput struct {
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Email              string `json:"email" validate:"required"`
+	MasterPasswordHash string `json:"masterPasswordHash" validate:"required"`
 }
 
 func GetLoginUserInput(c *fiber.Ctx) (queries.User, bool) {
@@ -77,10 +117,94 @@ func GetLoginUserInput(c *fiber.Ctx) (queries.User, bool) {
 		}
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.MasterPasswordHash)); err != nil {
 		status.Unauthorized(c, invalidCredentialsErr)
 		return queries.User{}, false
 	}
 
 	return user, true
 }
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func GetRefreshInput(c *fiber.Ctx) (RefreshInput, bool) {
+	var input RefreshInput
+	if len(c.Body()) != 0 {
+		if err := c.BodyParser(&input); err != nil {
+			status.BadRequest(c, err)
+			return RefreshInput{}, false
+		}
+	}
+
+	if len(input.RefreshToken) == 0 {
+		input.RefreshToken = c.Cookies(auth.REFRESH_TOKEN)
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return RefreshInput{}, false
+	}
+
+	return input, true
+}
+
+type SecondFactorInput struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recoveryCode" validate:"required_without=Code"`
+}
+
+type LoginMFAInput struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	SecondFactorInput
+}
+
+func GetLoginMFAInput(c *fiber.Ctx) (LoginMFAInput, bool) {
+	var input LoginMFAInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return LoginMFAInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return LoginMFAInput{}, false
+	}
+
+	return input, true
+}
+
+func GetSecondFactorInput(c *fiber.Ctx) (SecondFactorInput, bool) {
+	var input SecondFactorInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return SecondFactorInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return SecondFactorInput{}, false
+	}
+
+	return input, true
+}
+
+type TOTPCodeInput struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+func GetTOTPCodeInput(c *fiber.Ctx) (TOTPCodeInput, bool) {
+	var input TOTPCodeInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return TOTPCodeInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return TOTPCodeInput{}, false
+	}
+
+	return input, true
+}
@@ -1,77 +1,389 @@
 package schemas
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"github.com/LeonardJouve/pass-secure/database/queries"
 	"github.com/LeonardJouve/pass-secure/status"
 	"github.com/gofiber/fiber/v2"
 )
 
+const (
+	ENTRY_TYPE_LOGIN          = "login"
+	ENTRY_TYPE_SECURE_NOTE    = "secure_note"
+	ENTRY_TYPE_CARD           = "card"
+	ENTRY_TYPE_IDENTITY       = "identity"
+	ENTRY_TYPE_SSH_KEY        = "ssh_key"
+	ENTRY_TYPE_API_CREDENTIAL = "api_credential"
+)
+
+const (
+	ENTRY_TOTP_URI_SCHEME        = "otpauth"
+	ENTRY_TOTP_URI_TYPE          = "totp"
+	ENTRY_TOTP_DEFAULT_ALGORITHM = "SHA1"
+	ENTRY_TOTP_DEFAULT_DIGITS    = 6
+	ENTRY_TOTP_DEFAULT_PERIOD    = 30
+)
+
+// The fields of the types other than logins and secure notes, each one
+// encrypted client side like the common fields.
+type CardFields struct {
+	CardholderName string `json:"cardholderName,omitempty" validate:"omitempty,encstring"`
+	Brand          string `json:"brand,omitempty" validate:"omitempty,encstring"`
+	Number         string `json:"number,omitempty" validate:"omitempty,encstring"`
+	ExpMonth       string `json:"expMonth,omitempty" validate:"omitempty,encstring"`
+	ExpYear        string `json:"expYear,omitempty" validate:"omitempty,encstring"`
+	Code           string `json:"code,omitempty" validate:"omitempty,encstring"`
+}
+
+type IdentityFields struct {
+	Title          string `json:"title,omitempty" validate:"omitempty,encstring"`
+	FirstName      string `json:"firstName,omitempty" validate:"omitempty,encstring"`
+	MiddleName     string `json:"middleName,omitempty" validate:"omitempty,encstring"`
+	LastName       string `json:"lastName,omitempty" validate:"omitempty,encstring"`
+	Company        string `json:"company,omitempty" validate:"omitempty,encstring"`
+	Email          string `json:"email,omitempty" validate:"omitempty,encstring"`
+	Phone          string `json:"phone,omitempty" validate:"omitempty,encstring"`
+	Address1       string `json:"address1,omitempty" validate:"omitempty,encstring"`
+	Address2       string `json:"address2,omitempty" validate:"omitempty,encstring"`
+	City           string `json:"city,omitempty" validate:"omitempty,encstring"`
+	State          string `json:"state,omitempty" validate:"omitempty,encstring"`
+	PostalCode     string `json:"postalCode,omitempty" validate:"omitempty,encstring"`
+	Country        string `json:"country,omitempty" validate:"omitempty,encstring"`
+	SSN            string `json:"ssn,omitempty" validate:"omitempty,encstring"`
+	PassportNumber string `json:"passportNumber,omitempty" validate:"omitempty,encstring"`
+	LicenseNumber  string `json:"licenseNumber,omitempty" validate:"omitempty,encstring"`
+}
+
+type SSHKeyFields struct {
+	PrivateKey  string `json:"privateKey,omitempty" validate:"omitempty,encstring"`
+	PublicKey   string `json:"publicKey,omitempty" validate:"omitempty,encstring"`
+	Fingerprint string `json:"fingerprint,omitempty" validate:"omitempty,encstring"`
+}
+
+type APICredentialFields struct {
+	ClientID string `json:"clientId,omitempty" validate:"omitempty,encstring"`
+	Secret   string `json:"secret,omitempty" validate:"omitempty,encstring"`
+	Endpoint string `json:"endpoint,omitempty" validate:"omitempty,encstring"`
+}
+
+// Without a match strategy the client applies its default one.
+type EntryURIInput struct {
+	URI   string `json:"uri" validate:"required,encstring"`
+	Match string `json:"match,omitempty" validate:"omitempty,oneof=exact host base_domain starts_with regex"`
+}
+
+// Boolean values are encrypted like the others, hidden values are left out
+// along with passwords.
+type EntryCustomFieldInput struct {
+	Type  string `json:"type" validate:"required,oneof=text hidden boolean"`
+	Name  string `json:"name" validate:"required,encstring"`
+	Value string `json:"value,omitempty" validate:"omitempty,encstring"`
+}
+
+var errInvalidTOTPURI = errors.New("invalid otpauth uri")
+
+// The parameters of an otpauth:// URI, either given one by one or as the URI
+// itself with the secret, issuer and account encrypted client side. Omitted
+// parameters take the defaults of the URI format.
+type EntryTOTPInput struct {
+	URI       string `json:"uri,omitempty"`
+	Secret    string `json:"secret" validate:"required_without=URI,omitempty,encstring"`
+	Algorithm string `json:"algorithm" validate:"omitempty,oneof=SHA1 SHA256 SHA512"`
+	Digits    int32  `json:"digits" validate:"omitempty,min=6,max=8"`
+	Period    int32  `json:"period" validate:"omitempty,min=1,max=300"`
+	Issuer    string `json:"issuer,omitempty" validate:"omitempty,encstring"`
+	Account   string `json:"account,omitempty" validate:"omitempty,encstring"`
+}
+
+var entryTypeFields = map[string]func() any{
+	ENTRY_TYPE_CARD:           func() any { return &CardFields{} },
+	ENTRY_TYPE_IDENTITY:       func() any { return &IdentityFields{} },
+	ENTRY_TYPE_SSH_KEY:        func() any { return &SSHKeyFields{} },
+	ENTRY_TYPE_API_CREDENTIAL: func() any { return &APICredentialFields{} },
+}
+
+// Every field but the folder is encrypted client side with the vault key,
+// the server only checks the encrypted string envelope. Username, password and
+// URIs belong to logins, the other types but secure notes use fields.
 type CreateEntryInput struct {
-	Name     string `json:"name" validate:"required"`
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	Url      string `json:"url" validate:"omitempty"`
-	FolderID int64  `json:"folderId" validate:"required"`
+	Type         string                  `json:"type" validate:"required,oneof=login secure_note card identity ssh_key api_credential"`
+	Name         string                  `json:"name" validate:"required,encstring"`
+	Username     string                  `json:"username" validate:"required_if=Type login,excluded_unless=Type login,omitempty,encstring"`
+	Password     string                  `json:"password" validate:"required_if=Type login,excluded_unless=Type login,omitempty,encstring"`
+	TOTP         *EntryTOTPInput         `json:"totp" validate:"excluded_unless=Type login"`
+	Notes        string                  `json:"notes" validate:"required_if=Type secure_note,omitempty,encstring"`
+	URIs         []EntryURIInput         `json:"uris" validate:"excluded_unless=Type login,dive"`
+	CustomFields []EntryCustomFieldInput `json:"customFields" validate:"dive"`
+	Fields       map[string]string       `json:"fields" validate:"excluded_if=Type login,excluded_if=Type secure_note"`
+	FolderID     int64                   `json:"folderId" validate:"required"`
 }
 
+// Entries created without a type are logins.
 func GetCreateEntryInput(c *fiber.Ctx) (queries.CreateEntryParams, bool) {
-	var input CreateEntryInput
+	input := CreateEntryInput{
+		Type: ENTRY_TYPE_LOGIN,
+	}
 	if err := c.BodyParser(&input); err != nil {
 		status.BadRequest(c, err)
 		return queries.CreateEntryParams{}, false
 	}
 
-	if err := validate.Struct(input); err != nil {
+	result, err := getCreateEntryParams(input)
+	if err != nil {
 		status.BadRequest(c, err)
 		return queries.CreateEntryParams{}, false
 	}
 
+	return result, true
+}
+
+func getCreateEntryParams(input CreateEntryInput) (queries.CreateEntryParams, error) {
+	if err := validate.Struct(input); err != nil {
+		return queries.CreateEntryParams{}, err
+	}
+
+	fields, err := getEntryFields(input.Type, input.Fields)
+	if err != nil {
+		return queries.CreateEntryParams{}, err
+	}
+
+	totp, err := getEntryTOTP(input.TOTP)
+	if err != nil {
+		return queries.CreateEntryParams{}, err
+	}
+
+	uris, err := marshalEntryList(input.URIs)
+	if err != nil {
+		return queries.CreateEntryParams{}, err
+	}
+
+	customFields, err := marshalEntryList(input.CustomFields)
+	if err != nil {
+		return queries.CreateEntryParams{}, err
+	}
+
 	result := queries.CreateEntryParams{
-		Name:     input.Name,
-		Username: input.Username,
-		Password: input.Password,
-		Url:      &input.Url,
-		FolderID: input.FolderID,
+		Type:         input.Type,
+		Name:         input.Name,
+		Username:     &input.Username,
+		Password:     &input.Password,
+		Totp:         totp,
+		Notes:        &input.Notes,
+		Uris:         uris,
+		CustomFields: customFields,
+		Fields:       fields,
+		FolderID:     input.FolderID,
 	}
 
-	if len(input.Url) == 0 {
-		result.Url = nil
+	if len(input.Username) == 0 {
+		result.Username = nil
 	}
 
-	return result, true
+	if len(input.Password) == 0 {
+		result.Password = nil
+	}
+
+	if len(input.Notes) == 0 {
+		result.Notes = nil
+	}
+
+	return result, nil
 }
 
+// The type of an entry can not change. An omitted password keeps the current
+// one, as does an omitted secret field, while an omitted TOTP removes it.
 type UpdateEntryInput struct {
-	Name     string `json:"name" validate:"required"`
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	Url      string `json:"url" validate:"omitempty"`
-	FolderID int64  `json:"folderId" validate:"required"`
+	Type         string                  `json:"-"`
+	Name         string                  `json:"name" validate:"required,encstring"`
+	Username     string                  `json:"username" validate:"required_if=Type login,excluded_unless=Type login,omitempty,encstring"`
+	Password     string                  `json:"password" validate:"excluded_unless=Type login,omitempty,encstring"`
+	TOTP         *EntryTOTPInput         `json:"totp" validate:"excluded_unless=Type login"`
+	Notes        string                  `json:"notes" validate:"required_if=Type secure_note,omitempty,encstring"`
+	URIs         []EntryURIInput         `json:"uris" validate:"excluded_unless=Type login,dive"`
+	CustomFields []EntryCustomFieldInput `json:"customFields" validate:"dive"`
+	Fields       map[string]string       `json:"fields" validate:"excluded_if=Type login,excluded_if=Type secure_note"`
+	FolderID     int64                   `json:"folderId" validate:"required"`
 }
 
-func GetUpdateEntryInput(c *fiber.Ctx) (queries.UpdateEntryParams, bool) {
+func GetUpdateEntryInput(c *fiber.Ctx, entryType string) (queries.UpdateEntryParams, bool) {
 	var input UpdateEntryInput
 	if err := c.BodyParser(&input); err != nil {
 		status.BadRequest(c, err)
 		return queries.UpdateEntryParams{}, false
 	}
+	input.Type = entryType
 
 	if err := validate.Struct(input); err != nil {
 		status.BadRequest(c, err)
 		return queries.UpdateEntryParams{}, false
 	}
 
+	fields, err := getEntryFields(input.Type, input.Fields)
+	if err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateEntryParams{}, false
+	}
+
+	totp, err := getEntryTOTP(input.TOTP)
+	if err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateEntryParams{}, false
+	}
+
+	uris, err := marshalEntryList(input.URIs)
+	if err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateEntryParams{}, false
+	}
+
+	customFields, err := marshalEntryList(input.CustomFields)
+	if err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateEntryParams{}, false
+	}
+
 	result := queries.UpdateEntryParams{
-		Name:     input.Name,
-		Username: input.Username,
-		Password: input.Password,
-		Url:      &input.Url,
-		FolderID: input.FolderID,
+		Name:         input.Name,
+		Username:     &input.Username,
+		Password:     &input.Password,
+		Totp:         totp,
+		Notes:        &input.Notes,
+		Uris:         uris,
+		CustomFields: customFields,
+		Fields:       fields,
+		FolderID:     input.FolderID,
 	}
 
-	if len(input.Url) == 0 {
-		result.Url = nil
+	if len(input.Username) == 0 {
+		result.Username = nil
+	}
+
+	if len(input.Password) == 0 {
+		result.Password = nil
+	}
+
+	if len(input.Notes) == 0 {
+		result.Notes = nil
 	}
 
 	return result, true
 }
+
+// Checks the fields against the schema of the entry type and returns them as
+// the JSON object stored with the entry.
+func getEntryFields(entryType string, fields map[string]string) ([]byte, error) {
+	newTypeFields, ok := entryTypeFields[entryType]
+	if !ok || len(fields) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	typeFields := newTypeFields()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(typeFields); err != nil {
+		return nil, err
+	}
+
+	if err := validate.Struct(typeFields); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(typeFields)
+}
+
+func getEntryTOTP(input *EntryTOTPInput) ([]byte, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	totp := *input
+	if len(totp.URI) != 0 {
+		var err error
+		totp, err = parseEntryTOTPURI(totp.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := validate.Struct(totp); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(totp.Algorithm) == 0 {
+		totp.Algorithm = ENTRY_TOTP_DEFAULT_ALGORITHM
+	}
+
+	if totp.Digits == 0 {
+		totp.Digits = ENTRY_TOTP_DEFAULT_DIGITS
+	}
+
+	if totp.Period == 0 {
+		totp.Period = ENTRY_TOTP_DEFAULT_PERIOD
+	}
+
+	return json.Marshal(totp)
+}
+
+// Empty lists are stored as null like the other omitted fields.
+func marshalEntryList[T any](list []T) ([]byte, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(list)
+}
+
+// Parses otpauth://totp/[issuer:]account?secret=...&issuer=...&algorithm=...&digits=...&period=...
+// where the secret, issuer and account are percent encoded encrypted strings.
+// The issuer parameter takes precedence over the one of the label.
+func parseEntryTOTPURI(uri string) (EntryTOTPInput, error) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil || parsedURI.Scheme != ENTRY_TOTP_URI_SCHEME || !strings.EqualFold(parsedURI.Host, ENTRY_TOTP_URI_TYPE) {
+		return EntryTOTPInput{}, errInvalidTOTPURI
+	}
+
+	query, err := url.ParseQuery(parsedURI.RawQuery)
+	if err != nil {
+		return EntryTOTPInput{}, errInvalidTOTPURI
+	}
+
+	totp := EntryTOTPInput{
+		Secret:    query.Get("secret"),
+		Issuer:    query.Get("issuer"),
+		Algorithm: strings.ToUpper(query.Get("algorithm")),
+	}
+
+	issuer, account, found := strings.Cut(strings.TrimPrefix(parsedURI.Path, "/"), ":")
+	if !found {
+		issuer, account = "", issuer
+	}
+	totp.Account = account
+	if len(totp.Issuer) == 0 {
+		totp.Issuer = issuer
+	}
+
+	for parameter, value := range map[string]*int32{"digits": &totp.Digits, "period": &totp.Period} {
+		if !query.Has(parameter) {
+			continue
+		}
+
+		parsedValue, err := strconv.ParseInt(query.Get(parameter), 10, 32)
+		if err != nil || parsedValue <= 0 {
+			return EntryTOTPInput{}, errInvalidTOTPURI
+		}
+		*value = int32(parsedValue)
+	}
+
+	return totp, nil
+}
@@ -0,0 +1,42 @@
+package schemas
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func TestGetEntryTOTP(t *testing.T) {
+	Init()
+
+	secret := "2.AsskQwGKfG8ym1gsNgqrNQ==|WCCMx/xgjLOujp0m5RRDww==|/jG++81zQVHSdEs6PC/NJZwFyfKBNdZOZqUT1KAK86c="
+	encoded := url.QueryEscape(secret)
+
+	totp, err := getEntryTOTP(&EntryTOTPInput{URI: "otpauth://totp/" + encoded + ":" + encoded + "?secret=" + encoded + "&algorithm=sha256&digits=8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsedTOTP EntryTOTPInput
+	if err := json.Unmarshal(totp, &parsedTOTP); err != nil {
+		t.Fatal(err)
+	}
+
+	if parsedTOTP.URI != "" || parsedTOTP.Secret != secret || parsedTOTP.Issuer != secret || parsedTOTP.Account != secret || parsedTOTP.Algorithm != "SHA256" || parsedTOTP.Digits != 8 || parsedTOTP.Period != ENTRY_TOTP_DEFAULT_PERIOD {
+		t.Fatalf("unexpected totp: %+v", parsedTOTP)
+	}
+
+	for _, uri := range []string{
+		"otpauth://hotp/account?secret=" + encoded,
+		"https://totp/account?secret=" + encoded,
+		"otpauth://totp/account?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/account?secret=" + encoded + "&algorithm=MD5",
+		"otpauth://totp/account?secret=" + encoded + "&digits=4",
+		"otpauth://totp/account?secret=" + encoded + "&period=0",
+		"otpauth://totp/account?secret=" + encoded + "&period=thirty",
+	} {
+		if _, err := getEntryTOTP(&EntryTOTPInput{URI: uri}); err == nil {
+			t.Fatalf("%q was accepted", uri)
+		}
+	}
+}
@@ -7,8 +7,9 @@ import (
 )
 
 type CreateFolderInput struct {
-	Name     string `json:"name" validate:"required"`
-	ParentID int64  `json:"parentId" validate:"required"`
+	Name           string `json:"name" validate:"required"`
+	ParentID       int64  `json:"parentId" validate:"required"`
+	OrganizationID *int64 `json:"organizationId"`
 }
 
 func GetCreateFolderInput(c *fiber.Ctx, userId int64) (queries.CreateFolderParams, bool) {
@@ -24,16 +25,17 @@ func GetCreateFolderInput(c *fiber.Ctx, userId int64) (queries.CreateFolderParam
 	}
 
 	return queries.CreateFolderParams{
-		Name:     input.Name,
-		OwnerID:  userId,
-		ParentID: &input.ParentID,
+		Name:           input.Name,
+		OwnerID:        userId,
+		ParentID:       &input.ParentID,
+		OrganizationID: input.OrganizationID,
 	}, true
 }
 
+// Folders are moved and transferred through their own endpoints, see
+// MoveFolderInput and TransferFolderInput.
 type UpdateFolderInput struct {
-	Name     string `json:"name" validate:"required"`
-	OwnerID  int64  `json:"ownerId" validate:"required"`
-	ParentID int64  `json:"parentId" validate:"required"`
+	Name string `json:"name" validate:"required"`
 }
 
 func GetUpdateFolderInput(c *fiber.Ctx) (queries.UpdateFolderParams, bool) {
@@ -49,27 +51,126 @@ func GetUpdateFolderInput(c *fiber.Ctx) (queries.UpdateFolderParams, bool) {
 	}
 
 	return queries.UpdateFolderParams{
-		Name:     input.Name,
-		OwnerID:  input.OwnerID,
+		Name: input.Name,
+	}, true
+}
+
+type MoveFolderInput struct {
+	ParentID int64 `json:"parentId" validate:"required"`
+}
+
+func GetMoveFolderInput(c *fiber.Ctx, folderId int64) (queries.MoveFolderParams, bool) {
+	var input MoveFolderInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.MoveFolderParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.MoveFolderParams{}, false
+	}
+
+	return queries.MoveFolderParams{
+		ID:       folderId,
 		ParentID: &input.ParentID,
 	}, true
 }
 
-type AddFolderUserInput struct {
-	Email string `json:"email" validate:"required"`
+// The key is the folder key wrapped with the new owner's public key, required
+// when their access comes through a group since the group key can not unwrap
+// the owner's own grant.
+type TransferFolderInput struct {
+	UserID            int64   `json:"userId" validate:"required"`
+	Subtree           bool    `json:"subtree"`
+	RequireAcceptance bool    `json:"requireAcceptance"`
+	Key               *string `json:"key" validate:"omitempty,encstring_rsa"`
 }
 
-func GetAddFolderUserInput(c *fiber.Ctx) (AddFolderUserInput, bool) {
-	var input AddFolderUserInput
+func GetTransferFolderInput(c *fiber.Ctx) (TransferFolderInput, bool) {
+	var input TransferFolderInput
 	if err := c.BodyParser(&input); err != nil {
 		status.BadRequest(c, err)
-		return AddFolderUserInput{}, false
+		return TransferFolderInput{}, false
 	}
 
 	if err := validate.Struct(input); err != nil {
 		status.BadRequest(c, err)
-		return AddFolderUserInput{}, false
+		return TransferFolderInput{}, false
 	}
 
 	return input, true
 }
+
+// Same key as TransferFolderInput, wrapped by the new owner on accept.
+type AcceptFolderTransferInput struct {
+	Key *string `json:"key" validate:"omitempty,encstring_rsa"`
+}
+
+// The body is optional, the key is only required for some transfers.
+func GetAcceptFolderTransferInput(c *fiber.Ctx) (AcceptFolderTransferInput, bool) {
+	var input AcceptFolderTransferInput
+	if len(c.Body()) == 0 {
+		return input, true
+	}
+
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return AcceptFolderTransferInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return AcceptFolderTransferInput{}, false
+	}
+
+	return input, true
+}
+
+type UpdateFolderUserInput struct {
+	Role          string `json:"role" validate:"required,oneof=viewer editor manager"`
+	HidePasswords bool   `json:"hidePasswords"`
+}
+
+func GetUpdateFolderUserInput(c *fiber.Ctx, userId int64, folderId int64) (queries.UpdateFolderUserParams, bool) {
+	var input UpdateFolderUserInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateFolderUserParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateFolderUserParams{}, false
+	}
+
+	return queries.UpdateFolderUserParams{
+		UserID:        userId,
+		FolderID:      folderId,
+		Role:          input.Role,
+		HidePasswords: input.HidePasswords,
+	}, true
+}
+
+type SetFolderKeyInput struct {
+	Key string `json:"key" validate:"required,encstring_rsa"`
+}
+
+func GetSetFolderKeyInput(c *fiber.Ctx, userId int64, folderId int64) (queries.SetFolderUserKeyParams, bool) {
+	var input SetFolderKeyInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetFolderUserKeyParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetFolderUserKeyParams{}, false
+	}
+
+	return queries.SetFolderUserKeyParams{
+		UserID:   userId,
+		FolderID: folderId,
+		Key:      &input.Key,
+	}, true
+}
@@ -0,0 +1,79 @@
+package schemas
+
+import (
+	"github.com/LeonardJouve/pass-secure/generator"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	GENERATOR_TYPE_PASSWORD   = "password"
+	GENERATOR_TYPE_PASSPHRASE = "passphrase"
+)
+
+// Options left out keep their defaults, a 20 characters password of letters
+// and digits or a 6 words passphrase separated by dashes.
+type GeneratorInput struct {
+	Type           string `json:"type" validate:"required,oneof=password passphrase"`
+	Length         int    `json:"length" validate:"min=1,max=128"`
+	Lowercase      bool   `json:"lowercase"`
+	Uppercase      bool   `json:"uppercase"`
+	Digits         bool   `json:"digits"`
+	Symbols        bool   `json:"symbols"`
+	MinLowercase   int    `json:"minLowercase" validate:"min=0,max=128"`
+	MinUppercase   int    `json:"minUppercase" validate:"min=0,max=128"`
+	MinDigits      int    `json:"minDigits" validate:"min=0,max=128"`
+	MinSymbols     int    `json:"minSymbols" validate:"min=0,max=128"`
+	AvoidAmbiguous bool   `json:"avoidAmbiguous"`
+	WordCount      int    `json:"wordCount" validate:"min=1,max=20"`
+	Separator      string `json:"separator" validate:"max=8"`
+	Capitalize     bool   `json:"capitalize"`
+	IncludeNumber  bool   `json:"includeNumber"`
+}
+
+func GetGeneratorInput(c *fiber.Ctx) (GeneratorInput, bool) {
+	input := GeneratorInput{
+		Type:      GENERATOR_TYPE_PASSWORD,
+		Length:    20,
+		Lowercase: true,
+		Uppercase: true,
+		Digits:    true,
+		WordCount: 6,
+		Separator: "-",
+	}
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return GeneratorInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return GeneratorInput{}, false
+	}
+
+	return input, true
+}
+
+func (input GeneratorInput) PasswordOptions() generator.PasswordOptions {
+	return generator.PasswordOptions{
+		Length:         input.Length,
+		Lowercase:      input.Lowercase,
+		Uppercase:      input.Uppercase,
+		Digits:         input.Digits,
+		Symbols:        input.Symbols,
+		MinLowercase:   input.MinLowercase,
+		MinUppercase:   input.MinUppercase,
+		MinDigits:      input.MinDigits,
+		MinSymbols:     input.MinSymbols,
+		AvoidAmbiguous: input.AvoidAmbiguous,
+	}
+}
+
+func (input GeneratorInput) PassphraseOptions() generator.PassphraseOptions {
+	return generator.PassphraseOptions{
+		WordCount:     input.WordCount,
+		Separator:     input.Separator,
+		Capitalize:    input.Capitalize,
+		IncludeNumber: input.IncludeNumber,
+	}
+}
@@ -0,0 +1,133 @@
+package schemas
+
+import (
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+// The key is the new group key wrapped with the creator's public key.
+type CreateGroupInput struct {
+	Name string `json:"name" validate:"required,max=64"`
+	Key  string `json:"key" validate:"required,encstring_rsa"`
+}
+
+func GetCreateGroupInput(c *fiber.Ctx) (CreateGroupInput, bool) {
+	var input CreateGroupInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return CreateGroupInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return CreateGroupInput{}, false
+	}
+
+	return input, true
+}
+
+type UpdateGroupInput struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+func GetUpdateGroupInput(c *fiber.Ctx, groupId int64) (queries.UpdateGroupParams, bool) {
+	var input UpdateGroupInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateGroupParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateGroupParams{}, false
+	}
+
+	return queries.UpdateGroupParams{
+		ID:   groupId,
+		Name: input.Name,
+	}, true
+}
+
+// The parent key is the parent group key encrypted with the group key, a nil
+// parent makes the group top-level again.
+type SetGroupParentInput struct {
+	ParentID  *int64  `json:"parentId"`
+	ParentKey *string `json:"parentKey" validate:"required_with=ParentID,omitempty,encstring"`
+}
+
+func GetSetGroupParentInput(c *fiber.Ctx, groupId int64) (queries.SetGroupParentParams, bool) {
+	var input SetGroupParentInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetGroupParentParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetGroupParentParams{}, false
+	}
+
+	if input.ParentID == nil {
+		input.ParentKey = nil
+	}
+
+	return queries.SetGroupParentParams{
+		ID:        groupId,
+		ParentID:  input.ParentID,
+		ParentKey: input.ParentKey,
+	}, true
+}
+
+// The key is the group key wrapped with the invitee's public key.
+type AddGroupUserInput struct {
+	Key string `json:"key" validate:"required,encstring_rsa"`
+}
+
+func GetAddGroupUserInput(c *fiber.Ctx, groupId int64, userId int64, inviterId int64) (queries.CreateGroupInvitationParams, bool) {
+	var input AddGroupUserInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.CreateGroupInvitationParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.CreateGroupInvitationParams{}, false
+	}
+
+	return queries.CreateGroupInvitationParams{
+		GroupID:   groupId,
+		UserID:    userId,
+		InviterID: inviterId,
+		Key:       input.Key,
+	}, true
+}
+
+// The key is the folder key encrypted with the group key.
+type SetFolderGroupInput struct {
+	Role          string `json:"role" validate:"required,oneof=viewer editor manager"`
+	HidePasswords bool   `json:"hidePasswords"`
+	Key           string `json:"key" validate:"required,encstring"`
+}
+
+func GetSetFolderGroupInput(c *fiber.Ctx, groupId int64, folderId int64) (queries.SetFolderGroupParams, bool) {
+	var input SetFolderGroupInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetFolderGroupParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetFolderGroupParams{}, false
+	}
+
+	return queries.SetFolderGroupParams{
+		GroupID:       groupId,
+		FolderID:      folderId,
+		Key:           input.Key,
+		Role:          input.Role,
+		HidePasswords: input.HidePasswords,
+	}, true
+}
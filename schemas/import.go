@@ -0,0 +1,132 @@
+package schemas
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Folders are created in order, a folder can only reference a parent listed
+// before it. Folders without a parent go under the folder of the import.
+type ImportFolderInput struct {
+	Ref       string `json:"ref" validate:"required"`
+	Name      string `json:"name" validate:"required"`
+	ParentRef string `json:"parentRef"`
+}
+
+// Entries are the parsed items once encrypted client side, they take their
+// folder from the folder ref instead of an id.
+type ImportEntryInput struct {
+	CreateEntryInput
+	Row       int    `json:"row"`
+	FolderRef string `json:"folderRef"`
+}
+
+type ImportInput struct {
+	ParentID int64               `json:"parentId" validate:"required"`
+	Folders  []ImportFolderInput `json:"folders" validate:"dive"`
+	Entries  []json.RawMessage   `json:"entries"`
+}
+
+type ImportEntryParams struct {
+	Row       int
+	FolderRef string
+	Entry     queries.CreateEntryParams
+}
+
+type ImportEntryFailure struct {
+	Row    int
+	Reason string
+}
+
+type ImportParams struct {
+	ParentID int64
+	Folders  []ImportFolderInput
+	Entries  []ImportEntryParams
+	Failures []ImportEntryFailure
+}
+
+// Invalid folders fail the whole import while invalid entries are reported
+// and left out, as are entries referencing an unknown folder.
+func GetImportInput(c *fiber.Ctx) (ImportParams, bool) {
+	var input ImportInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return ImportParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return ImportParams{}, false
+	}
+
+	folderRefs := make(map[string]bool)
+	for _, folder := range input.Folders {
+		if folderRefs[folder.Ref] {
+			status.BadRequest(c, errors.New("duplicate folder ref "+folder.Ref))
+			return ImportParams{}, false
+		}
+
+		if len(folder.ParentRef) != 0 && !folderRefs[folder.ParentRef] {
+			status.BadRequest(c, errors.New("folder "+folder.Ref+" must come after its parent"))
+			return ImportParams{}, false
+		}
+
+		folderRefs[folder.Ref] = true
+	}
+
+	result := ImportParams{
+		ParentID: input.ParentID,
+		Folders:  input.Folders,
+		Entries:  []ImportEntryParams{},
+		Failures: []ImportEntryFailure{},
+	}
+
+	for i, rawEntry := range input.Entries {
+		entry, err := getImportEntryParams(rawEntry, i+1, input.ParentID, folderRefs)
+		if err != nil {
+			result.Failures = append(result.Failures, ImportEntryFailure{
+				Row:    entry.Row,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, true
+}
+
+// Entries without a row are numbered after their position. Their folder is
+// only known once created, the folder of the import stands in for it.
+func getImportEntryParams(rawEntry json.RawMessage, row int, parentId int64, folderRefs map[string]bool) (ImportEntryParams, error) {
+	entry := ImportEntryInput{
+		CreateEntryInput: CreateEntryInput{
+			Type: ENTRY_TYPE_LOGIN,
+		},
+		Row: row,
+	}
+	if err := json.Unmarshal(rawEntry, &entry); err != nil {
+		return ImportEntryParams{Row: row}, err
+	}
+
+	if len(entry.FolderRef) != 0 && !folderRefs[entry.FolderRef] {
+		return ImportEntryParams{Row: entry.Row}, errors.New("unknown folder ref " + entry.FolderRef)
+	}
+	entry.FolderID = parentId
+
+	params, err := getCreateEntryParams(entry.CreateEntryInput)
+	if err != nil {
+		return ImportEntryParams{Row: entry.Row}, err
+	}
+
+	return ImportEntryParams{
+		Row:       entry.Row,
+		FolderRef: entry.FolderRef,
+		Entry:     params,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package schemas
+
+import (
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CreateInvitationInput struct {
+	Email         string `json:"email" validate:"required,email"`
+	Role          string `json:"role" validate:"omitempty,oneof=viewer editor manager"`
+	HidePasswords bool   `json:"hidePasswords"`
+}
+
+func GetCreateInvitationInput(c *fiber.Ctx, folderId int64, inviterId int64) (queries.CreateFolderInvitationParams, bool) {
+	var input CreateInvitationInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.CreateFolderInvitationParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.CreateFolderInvitationParams{}, false
+	}
+
+	if len(input.Role) == 0 {
+		input.Role = auth.FOLDER_ROLE_VIEWER
+	}
+
+	token, expiresAt, ok := auth.CreateInvitationToken(c)
+	if !ok {
+		return queries.CreateFolderInvitationParams{}, false
+	}
+
+	return queries.CreateFolderInvitationParams{
+		FolderID:      folderId,
+		InviterID:     inviterId,
+		InviteeEmail:  input.Email,
+		Role:          input.Role,
+		HidePasswords: input.HidePasswords,
+		Token:         token,
+		ExpiresAt:     expiresAt,
+	}, true
+}
+
+// The key is the folder key wrapped with the invitee's public key.
+type ConfirmInvitationInput struct {
+	Key string `json:"key" validate:"required,encstring_rsa"`
+}
+
+func GetConfirmInvitationInput(c *fiber.Ctx) (ConfirmInvitationInput, bool) {
+	var input ConfirmInvitationInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return ConfirmInvitationInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return ConfirmInvitationInput{}, false
+	}
+
+	return input, true
+}
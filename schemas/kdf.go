@@ -0,0 +1,40 @@
+package schemas
+
+import (
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/go-playground/validator/v10"
+)
+
+type KdfInput struct {
+	Kdf            string `json:"kdf" validate:"required,oneof=pbkdf2 argon2id"`
+	KdfIterations  int32  `json:"kdfIterations" validate:"required"`
+	KdfMemory      *int32 `json:"kdfMemory"`
+	KdfParallelism *int32 `json:"kdfParallelism"`
+}
+
+func validateKdfInput(structLevel validator.StructLevel) {
+	input := structLevel.Current().Interface().(KdfInput)
+
+	switch input.Kdf {
+	case auth.KDF_PBKDF2:
+		if input.KdfIterations < auth.PBKDF2_MIN_ITERATIONS {
+			structLevel.ReportError(input.KdfIterations, "KdfIterations", "kdfIterations", "min", "")
+		}
+		if input.KdfMemory != nil {
+			structLevel.ReportError(input.KdfMemory, "KdfMemory", "kdfMemory", "excluded_if", "")
+		}
+		if input.KdfParallelism != nil {
+			structLevel.ReportError(input.KdfParallelism, "KdfParallelism", "kdfParallelism", "excluded_if", "")
+		}
+	case auth.KDF_ARGON2ID:
+		if input.KdfIterations < auth.ARGON2ID_MIN_ITERATIONS {
+			structLevel.ReportError(input.KdfIterations, "KdfIterations", "kdfIterations", "min", "")
+		}
+		if input.KdfMemory == nil || *input.KdfMemory < auth.ARGON2ID_MIN_MEMORY || *input.KdfMemory > auth.ARGON2ID_MAX_MEMORY {
+			structLevel.ReportError(input.KdfMemory, "KdfMemory", "kdfMemory", "required_if", "")
+		}
+		if input.KdfParallelism == nil || *input.KdfParallelism < auth.ARGON2ID_MIN_PARALLELISM || *input.KdfParallelism > auth.ARGON2ID_MAX_PARALLELISM {
+			structLevel.ReportError(input.KdfParallelism, "KdfParallelism", "kdfParallelism", "required_if", "")
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package schemas
+
+import (
+	"github.com/LeonardJouve/pass-secure/auth"
+	"github.com/LeonardJouve/pass-secure/database/queries"
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrganizationInput struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+func GetOrganizationInput(c *fiber.Ctx) (OrganizationInput, bool) {
+	var input OrganizationInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return OrganizationInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return OrganizationInput{}, false
+	}
+
+	return input, true
+}
+
+type AddOrganizationUserInput struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"omitempty,oneof=member admin owner"`
+}
+
+func GetAddOrganizationUserInput(c *fiber.Ctx) (AddOrganizationUserInput, bool) {
+	var input AddOrganizationUserInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return AddOrganizationUserInput{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return AddOrganizationUserInput{}, false
+	}
+
+	if len(input.Role) == 0 {
+		input.Role = auth.ORGANIZATION_ROLE_MEMBER
+	}
+
+	return input, true
+}
+
+type UpdateOrganizationUserInput struct {
+	Role string `json:"role" validate:"required,oneof=member admin owner"`
+}
+
+func GetUpdateOrganizationUserInput(c *fiber.Ctx, organizationId int64, userId int64) (queries.UpdateOrganizationUserParams, bool) {
+	var input UpdateOrganizationUserInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateOrganizationUserParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateOrganizationUserParams{}, false
+	}
+
+	return queries.UpdateOrganizationUserParams{
+		OrganizationID: organizationId,
+		UserID:         userId,
+		Role:           input.Role,
+	}, true
+}
+
+type UpdateOrganizationPoliciesInput struct {
+	RequireTwoFactor          bool  `json:"requireTwoFactor"`
+	MinPasswordStrength       int16 `json:"minPasswordStrength" validate:"min=0,max=4"`
+	DisablePersonalExport     bool  `json:"disablePersonalExport"`
+	DisableSharedExport       bool  `json:"disableSharedExport"`
+	GeneratorMinLength        int16 `json:"generatorMinLength" validate:"min=0,max=128"`
+	GeneratorRequireUppercase bool  `json:"generatorRequireUppercase"`
+	GeneratorRequireLowercase bool  `json:"generatorRequireLowercase"`
+	GeneratorRequireDigits    bool  `json:"generatorRequireDigits"`
+	GeneratorRequireSymbols   bool  `json:"generatorRequireSymbols"`
+	GeneratorMinDigits        int16 `json:"generatorMinDigits" validate:"min=0,max=128"`
+	GeneratorMinSymbols       int16 `json:"generatorMinSymbols" validate:"min=0,max=128"`
+	GeneratorMinWordCount     int16 `json:"generatorMinWordCount" validate:"min=0,max=20"`
+	GeneratorCapitalize       bool  `json:"generatorCapitalize"`
+	GeneratorIncludeNumber    bool  `json:"generatorIncludeNumber"`
+}
+
+func GetUpdateOrganizationPoliciesInput(c *fiber.Ctx, organizationId int64) (queries.UpdateOrganizationPoliciesParams, bool) {
+	var input UpdateOrganizationPoliciesInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateOrganizationPoliciesParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.UpdateOrganizationPoliciesParams{}, false
+	}
+
+	return queries.UpdateOrganizationPoliciesParams{
+		OrganizationID:            organizationId,
+		RequireTwoFactor:          input.RequireTwoFactor,
+		MinPasswordStrength:       input.MinPasswordStrength,
+		DisablePersonalExport:     input.DisablePersonalExport,
+		DisableSharedExport:       input.DisableSharedExport,
+		GeneratorMinLength:        input.GeneratorMinLength,
+		GeneratorRequireUppercase: input.GeneratorRequireUppercase,
+		GeneratorRequireLowercase: input.GeneratorRequireLowercase,
+		GeneratorRequireDigits:    input.GeneratorRequireDigits,
+		GeneratorRequireSymbols:   input.GeneratorRequireSymbols,
+		GeneratorMinDigits:        input.GeneratorMinDigits,
+		GeneratorMinSymbols:       input.GeneratorMinSymbols,
+		GeneratorMinWordCount:     input.GeneratorMinWordCount,
+		GeneratorCapitalize:       input.GeneratorCapitalize,
+		GeneratorIncludeNumber:    input.GeneratorIncludeNumber,
+	}, true
+}
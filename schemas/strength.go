@@ -0,0 +1,80 @@
+package schemas
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Scores as reported by clients, from 0 to 4 as in zxcvbn.
+const PASSWORD_STRENGTH_MAX_SCORE = 4
+
+var minPasswordStrength int16
+
+// Returned when the reported score of a master password is below the minimum.
+type PasswordStrengthError struct {
+	Score    int16 `json:"score"`
+	MinScore int16 `json:"minScore"`
+}
+
+func (err PasswordStrengthError) Error() string {
+	return "password is too weak"
+}
+
+// The minimum score of master passwords, 0 sets no minimum. Organizations can
+// require a stronger one from their members.
+func initPasswordStrength() {
+	minStrength, err := strconv.ParseInt(os.Getenv("MASTER_PASSWORD_MIN_STRENGTH"), 10, 16)
+	if err != nil {
+		minStrength = 0
+	}
+
+	minPasswordStrength = int16(max(0, min(minStrength, PASSWORD_STRENGTH_MAX_SCORE)))
+}
+
+// Validates the score reported by the client against the minimum given as the
+// tag parameter or configured for master passwords. The server can not verify
+// the score, the minimum only stops clients reporting honestly from accepting
+// a weak master password.
+func validatePasswordStrength(field validator.FieldLevel) bool {
+	return field.Field().Int() >= int64(getMinPasswordStrength(field.Param()))
+}
+
+func getMinPasswordStrength(param string) int16 {
+	minScore, err := strconv.ParseInt(param, 10, 16)
+	if err != nil {
+		return minPasswordStrength
+	}
+
+	return int16(minScore)
+}
+
+func getPasswordStrengthError(err error) (PasswordStrengthError, bool) {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return PasswordStrengthError{}, false
+	}
+
+	for _, fieldError := range validationErrors {
+		if fieldError.Tag() != "password_strength" {
+			continue
+		}
+
+		score, _ := fieldError.Value().(int16)
+		return PasswordStrengthError{
+			Score:    score,
+			MinScore: getMinPasswordStrength(fieldError.Param()),
+		}, true
+	}
+
+	return PasswordStrengthError{}, false
+}
+
+func passwordStrengthError(c *fiber.Ctx, err PasswordStrengthError) {
+	c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"message":  err.Error(),
+		"strength": err,
+	})
+}
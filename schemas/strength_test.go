@@ -0,0 +1,22 @@
+package schemas
+
+import (
+	"testing"
+)
+
+func TestValidatePasswordStrength(t *testing.T) {
+	Init()
+
+	type passwordInput struct {
+		Score int16 `validate:"password_strength=2"`
+	}
+
+	if err := validate.Struct(passwordInput{Score: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := validate.Struct(passwordInput{Score: 1})
+	if strengthError, ok := getPasswordStrengthError(err); !ok || strengthError.Score != 1 || strengthError.MinScore != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,18 +1,32 @@
 package schemas
 
 import (
+	"errors"
+
 	"github.com/LeonardJouve/pass-secure/database/queries"
 	"github.com/LeonardJouve/pass-secure/status"
 	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UpdateMeInput struct {
-	Email    string `json:"email" validate:"required"`
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Email                     string `json:"email" validate:"required,email"`
+	Username                  string `json:"username" validate:"required"`
+	MasterPasswordHash        string `json:"masterPasswordHash" validate:"required"`
+	NewMasterPasswordHash     string `json:"newMasterPasswordHash" validate:"omitempty,base64,len=44"`
+	NewKey                    string `json:"newKey" validate:"required_with=NewMasterPasswordHash,omitempty,encstring"`
+	NewMasterPasswordStrength *int16 `json:"newMasterPasswordStrength" validate:"omitempty,min=0,max=4"`
+	*KdfInput                 `validate:"required_with=NewMasterPasswordHash,omitempty"`
 }
 
-func GetUpdateMeInput(c *fiber.Ctx, id int64) (queries.UpdateUserParams, bool) {
+// The email salts the master key derivation, so changing it also requires a
+// new master password hash and the vault key wrapped with the new master key.
+// The server never sees the master password, its strength score is estimated
+// by the client and checked against the configured minimum and the one set by
+// the organizations, which is advisory since the server can not verify it.
+// Whether it appears in a known breach is checked by the client against the
+// breach range endpoint only.
+func GetUpdateMeInput(c *fiber.Ctx, user queries.User, organizationMinPasswordStrength int16) (queries.UpdateUserParams, bool) {
 	var input UpdateMeInput
 	if err := c.BodyParser(&input); err != nil {
 		status.BadRequest(c, err)
@@ -24,10 +38,83 @@ func GetUpdateMeInput(c *fiber.Ctx, id int64) (queries.UpdateUserParams, bool) {
 		return queries.UpdateUserParams{}, false
 	}
 
-	return queries.UpdateUserParams{
-		ID:       id,
-		Email:    input.Email,
-		Username: input.Username,
-		Password: input.Password,
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.MasterPasswordHash)); err != nil {
+		status.Unauthorized(c, errors.New("invalid credentials"))
+		return queries.UpdateUserParams{}, false
+	}
+
+	result := queries.UpdateUserParams{
+		ID:             user.ID,
+		Email:          input.Email,
+		Username:       input.Username,
+		Password:       user.Password,
+		Kdf:            user.Kdf,
+		KdfIterations:  user.KdfIterations,
+		KdfMemory:      user.KdfMemory,
+		KdfParallelism: user.KdfParallelism,
+		Key:            user.Key,
+	}
+
+	if len(input.NewMasterPasswordHash) == 0 {
+		if input.Email != user.Email {
+			status.BadRequest(c, errors.New("changing email requires a new master password hash"))
+			return queries.UpdateUserParams{}, false
+		}
+
+		return result, true
+	}
+
+	minScore := max(minPasswordStrength, organizationMinPasswordStrength)
+	if minScore > 0 && (input.NewMasterPasswordStrength == nil || *input.NewMasterPasswordStrength < minScore) {
+		strengthError := PasswordStrengthError{
+			MinScore: minScore,
+		}
+		if input.NewMasterPasswordStrength != nil {
+			strengthError.Score = *input.NewMasterPasswordStrength
+		}
+
+		passwordStrengthError(c, strengthError)
+		return queries.UpdateUserParams{}, false
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewMasterPasswordHash), bcrypt.DefaultCost)
+	if err != nil {
+		status.InternalServerError(c, nil)
+		return queries.UpdateUserParams{}, false
+	}
+
+	result.Password = string(hashedPassword)
+	result.Kdf = input.Kdf
+	result.KdfIterations = input.KdfIterations
+	result.KdfMemory = input.KdfMemory
+	result.KdfParallelism = input.KdfParallelism
+	result.Key = &input.NewKey
+
+	return result, true
+}
+
+// The private key is encrypted with the vault key, the public key is used by
+// other users to wrap the folder keys they share.
+type SetKeyPairInput struct {
+	PublicKey  string `json:"publicKey" validate:"required,rsapublickey"`
+	PrivateKey string `json:"privateKey" validate:"required,encstring"`
+}
+
+func GetSetKeyPairInput(c *fiber.Ctx, userId int64) (queries.SetUserKeyPairParams, bool) {
+	var input SetKeyPairInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetUserKeyPairParams{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return queries.SetUserKeyPairParams{}, false
+	}
+
+	return queries.SetUserKeyPairParams{
+		ID:         userId,
+		PublicKey:  &input.PublicKey,
+		PrivateKey: &input.PrivateKey,
 	}, true
 }
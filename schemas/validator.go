@@ -1,19 +1,58 @@
 package schemas
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"regexp"
 
+	"github.com/LeonardJouve/pass-secure/encstring"
 	"github.com/go-playground/validator/v10"
 )
 
-const EMAIL_REGEX = `^.+@.+\..+$`
+const (
+	EMAIL_REGEX      = `^.+@.+\..+$`
+	RSA_MIN_KEY_SIZE = 2048
+)
 
 var validate *validator.Validate = validator.New()
 
 func Init() {
+	initPasswordStrength()
+
 	validate.RegisterValidation("email", validateEmail)
+	validate.RegisterValidation("encstring", validateEncString)
+	validate.RegisterValidation("encstring_rsa", validateAsymmetricEncString)
+	validate.RegisterValidation("rsapublickey", validateRSAPublicKey)
+	validate.RegisterValidation("password_strength", validatePasswordStrength)
+	validate.RegisterStructValidation(validateKdfInput, KdfInput{})
 }
 
 func validateEmail(field validator.FieldLevel) bool {
 	return regexp.MustCompile(EMAIL_REGEX).MatchString(field.Field().String())
 }
+
+func validateEncString(field validator.FieldLevel) bool {
+	return encstring.IsValid(field.Field().String())
+}
+
+func validateAsymmetricEncString(field validator.FieldLevel) bool {
+	return encstring.IsValidAsymmetric(field.Field().String())
+}
+
+// Public keys are base64 encoded DER SubjectPublicKeyInfo, as exported by WebCrypto "spki".
+func validateRSAPublicKey(field validator.FieldLevel) bool {
+	der, err := base64.StdEncoding.DecodeString(field.Field().String())
+	if err != nil {
+		return false
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return false
+	}
+
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+
+	return ok && rsaPublicKey.N.BitLen() >= RSA_MIN_KEY_SIZE
+}
@@ -0,0 +1,93 @@
+package schemas
+
+import (
+	"encoding/base64"
+
+	"github.com/LeonardJouve/pass-secure/status"
+	"github.com/LeonardJouve/pass-secure/webauthn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebAuthnRegistrationInput struct {
+	Name              string `json:"name" validate:"required,max=128"`
+	ClientDataJSON    string `json:"clientDataJSON" validate:"required,base64rawurl"`
+	AttestationObject string `json:"attestationObject" validate:"required,base64rawurl"`
+}
+
+func GetWebAuthnRegistrationInput(c *fiber.Ctx) (string, webauthn.AttestationResponse, bool) {
+	var input WebAuthnRegistrationInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return "", webauthn.AttestationResponse{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return "", webauthn.AttestationResponse{}, false
+	}
+
+	clientDataJSON, _ := base64.RawURLEncoding.DecodeString(input.ClientDataJSON)
+	attestationObject, _ := base64.RawURLEncoding.DecodeString(input.AttestationObject)
+
+	return input.Name, webauthn.AttestationResponse{
+		ClientDataJSON:    clientDataJSON,
+		AttestationObject: attestationObject,
+	}, true
+}
+
+type WebAuthnLoginOptionsInput struct {
+	MFAToken string `json:"mfa_token" validate:"omitempty"`
+}
+
+func GetWebAuthnLoginOptionsInput(c *fiber.Ctx) (WebAuthnLoginOptionsInput, bool) {
+	var input WebAuthnLoginOptionsInput
+	if len(c.Body()) != 0 {
+		if err := c.BodyParser(&input); err != nil {
+			status.BadRequest(c, err)
+			return WebAuthnLoginOptionsInput{}, false
+		}
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return WebAuthnLoginOptionsInput{}, false
+	}
+
+	return input, true
+}
+
+type WebAuthnLoginInput struct {
+	MFAToken          string `json:"mfa_token" validate:"omitempty"`
+	CredentialID      string `json:"credentialId" validate:"required,base64rawurl"`
+	ClientDataJSON    string `json:"clientDataJSON" validate:"required,base64rawurl"`
+	AuthenticatorData string `json:"authenticatorData" validate:"required,base64rawurl"`
+	Signature         string `json:"signature" validate:"required,base64rawurl"`
+	UserHandle        string `json:"userHandle" validate:"omitempty,base64rawurl"`
+}
+
+func GetWebAuthnLoginInput(c *fiber.Ctx) (string, webauthn.AssertionResponse, bool) {
+	var input WebAuthnLoginInput
+	if err := c.BodyParser(&input); err != nil {
+		status.BadRequest(c, err)
+		return "", webauthn.AssertionResponse{}, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		status.BadRequest(c, err)
+		return "", webauthn.AssertionResponse{}, false
+	}
+
+	credentialId, _ := base64.RawURLEncoding.DecodeString(input.CredentialID)
+	clientDataJSON, _ := base64.RawURLEncoding.DecodeString(input.ClientDataJSON)
+	authenticatorData, _ := base64.RawURLEncoding.DecodeString(input.AuthenticatorData)
+	signature, _ := base64.RawURLEncoding.DecodeString(input.Signature)
+	userHandle, _ := base64.RawURLEncoding.DecodeString(input.UserHandle)
+
+	return input.MFAToken, webauthn.AssertionResponse{
+		CredentialID:      credentialId,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authenticatorData,
+		Signature:         signature,
+		UserHandle:        userHandle,
+	}, true
+}
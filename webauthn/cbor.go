@@ -0,0 +1,153 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	CBOR_UNSIGNED  = 0
+	CBOR_NEGATIVE  = 1
+	CBOR_BYTES     = 2
+	CBOR_TEXT      = 3
+	CBOR_ARRAY     = 4
+	CBOR_MAP       = 5
+	CBOR_TAG       = 6
+	CBOR_SIMPLE    = 7
+	CBOR_MAX_DEPTH = 16
+)
+
+var errInvalidCBOR = errors.New("invalid cbor")
+
+// Decodes the single CBOR item at the start of data and returns the remaining bytes.
+// Only the definite length subset used by CTAP2 is supported, integers are
+// decoded as int64, maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > CBOR_MAX_DEPTH || len(data) == 0 {
+		return nil, nil, errInvalidCBOR
+	}
+
+	majorType := data[0] >> 5
+	additionalInformation := data[0] & 0x1f
+
+	if majorType == CBOR_SIMPLE {
+		return decodeCBORSimple(data, additionalInformation)
+	}
+
+	argument, data, err := decodeCBORArgument(data[1:], additionalInformation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch majorType {
+	case CBOR_UNSIGNED:
+		if argument > math.MaxInt64 {
+			return nil, nil, errInvalidCBOR
+		}
+		return int64(argument), data, nil
+	case CBOR_NEGATIVE:
+		if argument > math.MaxInt64 {
+			return nil, nil, errInvalidCBOR
+		}
+		return -1 - int64(argument), data, nil
+	case CBOR_BYTES, CBOR_TEXT:
+		if argument > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		value := make([]byte, argument)
+		copy(value, data[:argument])
+		if majorType == CBOR_TEXT {
+			return string(value), data[argument:], nil
+		}
+		return value, data[argument:], nil
+	case CBOR_ARRAY:
+		if argument > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		array := make([]interface{}, argument)
+		for i := range array {
+			array[i], data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return array, data, nil
+	case CBOR_MAP:
+		if argument > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		object := make(map[interface{}]interface{}, argument)
+		for range argument {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errInvalidCBOR
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			object[key] = value
+		}
+		return object, data, nil
+	case CBOR_TAG:
+		return decodeCBORItem(data, depth+1)
+	}
+
+	return nil, nil, errInvalidCBOR
+}
+
+func decodeCBORArgument(data []byte, additionalInformation byte) (uint64, []byte, error) {
+	switch {
+	case additionalInformation < 24:
+		return uint64(additionalInformation), data, nil
+	case additionalInformation == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case additionalInformation == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case additionalInformation == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case additionalInformation == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+
+	return 0, nil, errInvalidCBOR
+}
+
+func decodeCBORSimple(data []byte, additionalInformation byte) (interface{}, []byte, error) {
+	switch additionalInformation {
+	case 20:
+		return false, data[1:], nil
+	case 21:
+		return true, data[1:], nil
+	case 22, 23:
+		return nil, data[1:], nil
+	case 25:
+		if len(data) < 3 {
+			return nil, nil, errInvalidCBOR
+		}
+		return nil, data[3:], nil
+	case 26:
+		if len(data) < 5 {
+			return nil, nil, errInvalidCBOR
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:]))), data[5:], nil
+	case 27:
+		if len(data) < 9 {
+			return nil, nil, errInvalidCBOR
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:])), data[9:], nil
+	}
+
+	return nil, nil, errInvalidCBOR
+}
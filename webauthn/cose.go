@@ -0,0 +1,129 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const (
+	COSE_KEY_TYPE      = 1
+	COSE_ALGORITHM     = 3
+	COSE_EC2_CURVE     = -1
+	COSE_EC2_X         = -2
+	COSE_EC2_Y         = -3
+	COSE_RSA_N         = -1
+	COSE_RSA_E         = -2
+	COSE_OKP_CURVE     = -1
+	COSE_OKP_X         = -2
+	COSE_KEY_TYPE_OKP  = 1
+	COSE_KEY_TYPE_EC2  = 2
+	COSE_KEY_TYPE_RSA  = 3
+	COSE_CURVE_P256    = 1
+	COSE_CURVE_ED25519 = 6
+	COSE_ES256         = -7
+	COSE_EDDSA         = -8
+	COSE_RS256         = -257
+)
+
+var (
+	errUnsupportedKey   = errors.New("unsupported credential public key")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+var SupportedAlgorithms = []int64{COSE_ES256, COSE_EDDSA, COSE_RS256}
+
+type PublicKey struct {
+	Algorithm int64
+	key       interface{}
+}
+
+func ParsePublicKey(coseKey []byte) (PublicKey, error) {
+	decoded, rest, err := decodeCBOR(coseKey)
+	if err != nil || len(rest) != 0 {
+		return PublicKey{}, errUnsupportedKey
+	}
+
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return PublicKey{}, errUnsupportedKey
+	}
+
+	keyType, _ := object[int64(COSE_KEY_TYPE)].(int64)
+	algorithm, _ := object[int64(COSE_ALGORITHM)].(int64)
+
+	switch {
+	case keyType == COSE_KEY_TYPE_EC2 && algorithm == COSE_ES256:
+		curve, _ := object[int64(COSE_EC2_CURVE)].(int64)
+		x, _ := object[int64(COSE_EC2_X)].([]byte)
+		y, _ := object[int64(COSE_EC2_Y)].([]byte)
+		if curve != COSE_CURVE_P256 || len(x) != 32 || len(y) != 32 {
+			return PublicKey{}, errUnsupportedKey
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return PublicKey{}, errUnsupportedKey
+		}
+
+		return PublicKey{Algorithm: algorithm, key: key}, nil
+	case keyType == COSE_KEY_TYPE_OKP && algorithm == COSE_EDDSA:
+		curve, _ := object[int64(COSE_OKP_CURVE)].(int64)
+		x, _ := object[int64(COSE_OKP_X)].([]byte)
+		if curve != COSE_CURVE_ED25519 || len(x) != ed25519.PublicKeySize {
+			return PublicKey{}, errUnsupportedKey
+		}
+
+		return PublicKey{Algorithm: algorithm, key: ed25519.PublicKey(x)}, nil
+	case keyType == COSE_KEY_TYPE_RSA && algorithm == COSE_RS256:
+		n, _ := object[int64(COSE_RSA_N)].([]byte)
+		e, _ := object[int64(COSE_RSA_E)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return PublicKey{}, errUnsupportedKey
+		}
+
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+
+		return PublicKey{Algorithm: algorithm, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: exponent,
+		}}, nil
+	}
+
+	return PublicKey{}, errUnsupportedKey
+}
+
+func (p PublicKey) Verify(data []byte, signature []byte) error {
+	switch key := p.key.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, hash[:], signature) {
+			return errInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, signature) {
+			return errInvalidSignature
+		}
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+			return errInvalidSignature
+		}
+	default:
+		return errUnsupportedKey
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	CHALLENGE_SIZE                = 32
+	TIMEOUT                       = time.Minute
+	CEREMONY_CREATE               = "webauthn.create"
+	CEREMONY_GET                  = "webauthn.get"
+	CREDENTIAL_TYPE               = "public-key"
+	FLAG_USER_PRESENT             = 0x01
+	FLAG_USER_VERIFIED            = 0x04
+	FLAG_ATTESTED_DATA            = 0x40
+	AUTHENTICATOR_DATA_MIN_LENGTH = 37
+	AAGUID_LENGTH                 = 16
+	USER_VERIFICATION_REQUIRED    = "required"
+	USER_VERIFICATION_PREFERRED   = "preferred"
+)
+
+var (
+	errInvalidClientData        = errors.New("invalid client data")
+	errInvalidAuthenticatorData = errors.New("invalid authenticator data")
+	errInvalidAttestation       = errors.New("invalid attestation object")
+	errUserNotPresent           = errors.New("user not present")
+	errUserNotVerified          = errors.New("user not verified")
+	errSignCount                = errors.New("sign count did not increase, the authenticator may be cloned")
+)
+
+var encoding = base64.RawURLEncoding
+
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+type ClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type AuthenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+type AssertionResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RelyingPartyID   string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, CHALLENGE_SIZE)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func EncodeChallenge(challenge []byte) string {
+	return encoding.EncodeToString(challenge)
+}
+
+func (r RelyingParty) NewCreationOptions(challenge []byte, userHandle []byte, name string, displayName string, excludeCredentialIds [][]byte) CreationOptions {
+	pubKeyCredParams := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, algorithm := range SupportedAlgorithms {
+		pubKeyCredParams[i] = CredentialParameter{
+			Type:      CREDENTIAL_TYPE,
+			Algorithm: algorithm,
+		}
+	}
+
+	return CreationOptions{
+		Challenge: EncodeChallenge(challenge),
+		RelyingParty: RelyingPartyEntity{
+			ID:   r.ID,
+			Name: r.Name,
+		},
+		User: UserEntity{
+			ID:          encoding.EncodeToString(userHandle),
+			Name:        name,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams:   pubKeyCredParams,
+		Timeout:            TIMEOUT.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: toCredentialDescriptors(excludeCredentialIds),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: USER_VERIFICATION_PREFERRED,
+		},
+	}
+}
+
+func (r RelyingParty) NewRequestOptions(challenge []byte, allowCredentialIds [][]byte, userVerification string) RequestOptions {
+	return RequestOptions{
+		Challenge:        EncodeChallenge(challenge),
+		Timeout:          TIMEOUT.Milliseconds(),
+		RelyingPartyID:   r.ID,
+		AllowCredentials: toCredentialDescriptors(allowCredentialIds),
+		UserVerification: userVerification,
+	}
+}
+
+func ParseClientData(clientDataJSON []byte) (ClientData, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return ClientData{}, errInvalidClientData
+	}
+
+	return clientData, nil
+}
+
+func ParseAuthenticatorData(data []byte) (AuthenticatorData, error) {
+	if len(data) < AUTHENTICATOR_DATA_MIN_LENGTH {
+		return AuthenticatorData{}, errInvalidAuthenticatorData
+	}
+
+	authenticatorData := AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if authenticatorData.Flags&FLAG_ATTESTED_DATA == 0 {
+		return authenticatorData, nil
+	}
+
+	rest := data[AUTHENTICATOR_DATA_MIN_LENGTH:]
+	if len(rest) < AAGUID_LENGTH+2 {
+		return AuthenticatorData{}, errInvalidAuthenticatorData
+	}
+
+	authenticatorData.AAGUID = rest[:AAGUID_LENGTH]
+	credentialIdLength := int(binary.BigEndian.Uint16(rest[AAGUID_LENGTH:]))
+	rest = rest[AAGUID_LENGTH+2:]
+	if len(rest) < credentialIdLength {
+		return AuthenticatorData{}, errInvalidAuthenticatorData
+	}
+
+	authenticatorData.CredentialID = rest[:credentialIdLength]
+	rest = rest[credentialIdLength:]
+
+	_, extensions, err := decodeCBOR(rest)
+	if err != nil {
+		return AuthenticatorData{}, errInvalidAuthenticatorData
+	}
+	authenticatorData.CredentialPublicKey = rest[:len(rest)-len(extensions)]
+
+	return authenticatorData, nil
+}
+
+// Attestation statements are not verified: credentials are requested with the
+// "none" conveyance so the relying party does not rely on authenticator provenance.
+func (r RelyingParty) VerifyRegistration(challenge []byte, response AttestationResponse, requireUserVerification bool) (Credential, error) {
+	if err := r.verifyClientData(response.ClientDataJSON, CEREMONY_CREATE, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	decoded, rest, err := decodeCBOR(response.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return Credential{}, errInvalidAttestation
+	}
+
+	attestationObject, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return Credential{}, errInvalidAttestation
+	}
+
+	rawAuthenticatorData, ok := attestationObject["authData"].([]byte)
+	if !ok {
+		return Credential{}, errInvalidAttestation
+	}
+
+	authenticatorData, err := ParseAuthenticatorData(rawAuthenticatorData)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if err := r.verifyAuthenticatorData(authenticatorData, requireUserVerification); err != nil {
+		return Credential{}, err
+	}
+
+	if authenticatorData.Flags&FLAG_ATTESTED_DATA == 0 {
+		return Credential{}, errInvalidAttestation
+	}
+
+	if _, err := ParsePublicKey(authenticatorData.CredentialPublicKey); err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:        authenticatorData.CredentialID,
+		PublicKey: authenticatorData.CredentialPublicKey,
+		SignCount: authenticatorData.SignCount,
+	}, nil
+}
+
+// Returns the new sign count to persist for the credential.
+func (r RelyingParty) VerifyAssertion(challenge []byte, credential Credential, response AssertionResponse, requireUserVerification bool) (uint32, error) {
+	if err := r.verifyClientData(response.ClientDataJSON, CEREMONY_GET, challenge); err != nil {
+		return 0, err
+	}
+
+	authenticatorData, err := ParseAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.verifyAuthenticatorData(authenticatorData, requireUserVerification); err != nil {
+		return 0, err
+	}
+
+	publicKey, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(response.ClientDataJSON)
+	signedData := append(slices.Clone(response.AuthenticatorData), clientDataHash[:]...)
+	if err := publicKey.Verify(signedData, response.Signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators without a counter always report zero
+	if (authenticatorData.SignCount != 0 || credential.SignCount != 0) && authenticatorData.SignCount <= credential.SignCount {
+		return 0, errSignCount
+	}
+
+	return authenticatorData.SignCount, nil
+}
+
+func (r RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) error {
+	clientData, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+
+	if clientData.Type != ceremony {
+		return errInvalidClientData
+	}
+
+	receivedChallenge, err := encoding.DecodeString(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(receivedChallenge, challenge) != 1 {
+		return errInvalidClientData
+	}
+
+	if !slices.Contains(r.Origins, clientData.Origin) {
+		return errInvalidClientData
+	}
+
+	return nil
+}
+
+func (r RelyingParty) verifyAuthenticatorData(authenticatorData AuthenticatorData, requireUserVerification bool) error {
+	rpIdHash := sha256.Sum256([]byte(r.ID))
+	if !bytes.Equal(authenticatorData.RPIDHash, rpIdHash[:]) {
+		return errInvalidAuthenticatorData
+	}
+
+	if authenticatorData.Flags&FLAG_USER_PRESENT == 0 {
+		return errUserNotPresent
+	}
+
+	if requireUserVerification && authenticatorData.Flags&FLAG_USER_VERIFIED == 0 {
+		return errUserNotVerified
+	}
+
+	return nil
+}
+
+func toCredentialDescriptors(credentialIds [][]byte) []CredentialDescriptor {
+	credentialDescriptors := make([]CredentialDescriptor, len(credentialIds))
+	for i, credentialId := range credentialIds {
+		credentialDescriptors[i] = CredentialDescriptor{
+			Type: CREDENTIAL_TYPE,
+			ID:   encoding.EncodeToString(credentialId),
+		}
+	}
+
+	return credentialDescriptors
+}
@@ -0,0 +1,255 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+const (
+	TEST_RP_ID  = "localhost"
+	TEST_ORIGIN = "http://localhost:3000"
+)
+
+var testRelyingParty = RelyingParty{
+	ID:      TEST_RP_ID,
+	Name:    "pass-secure",
+	Origins: []string{TEST_ORIGIN},
+}
+
+// Software authenticator holding a single ES256 credential
+type authenticator struct {
+	credentialId []byte
+	privateKey   *ecdsa.PrivateKey
+	signCount    uint32
+	flags        byte
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialId := make([]byte, 16)
+	rand.Read(credentialId)
+
+	return &authenticator{
+		credentialId: credentialId,
+		privateKey:   privateKey,
+		flags:        FLAG_USER_PRESENT | FLAG_USER_VERIFIED,
+	}
+}
+
+func (a *authenticator) coseKey() []byte {
+	x := a.privateKey.PublicKey.X.FillBytes(make([]byte, 32))
+	y := a.privateKey.PublicKey.Y.FillBytes(make([]byte, 32))
+
+	return encodeTestCBOR([]interface{}{
+		int64(COSE_KEY_TYPE), int64(COSE_KEY_TYPE_EC2),
+		int64(COSE_ALGORITHM), int64(COSE_ES256),
+		int64(COSE_EC2_CURVE), int64(COSE_CURVE_P256),
+		int64(COSE_EC2_X), x,
+		int64(COSE_EC2_Y), y,
+	})
+}
+
+func (a *authenticator) authenticatorData(rpId string, attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(rpId))
+	data := append([]byte{}, rpIdHash[:]...)
+
+	flags := a.flags
+	if attested {
+		flags |= FLAG_ATTESTED_DATA
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, AAGUID_LENGTH)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialId)))
+		data = append(data, a.credentialId...)
+		data = append(data, a.coseKey()...)
+	}
+
+	return data
+}
+
+func (a *authenticator) create(t *testing.T, challenge []byte, origin string) AttestationResponse {
+	clientDataJSON := clientData(t, CEREMONY_CREATE, challenge, origin)
+
+	return AttestationResponse{
+		ClientDataJSON: clientDataJSON,
+		AttestationObject: encodeTestCBOR(map[string]interface{}{
+			"fmt":      "none",
+			"attStmt":  map[string]interface{}{},
+			"authData": a.authenticatorData(TEST_RP_ID, true),
+		}),
+	}
+}
+
+func (a *authenticator) get(t *testing.T, challenge []byte, origin string) AssertionResponse {
+	a.signCount++
+
+	clientDataJSON := clientData(t, CEREMONY_GET, challenge, origin)
+	authenticatorData := a.authenticatorData(TEST_RP_ID, false)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hash := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.privateKey, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return AssertionResponse{
+		CredentialID:      a.credentialId,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authenticatorData,
+		Signature:         signature,
+	}
+}
+
+func clientData(t *testing.T, ceremony string, challenge []byte, origin string) []byte {
+	clientDataJSON, err := json.Marshal(ClientData{
+		Type:      ceremony,
+		Challenge: EncodeChallenge(challenge),
+		Origin:    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return clientDataJSON
+}
+
+// Encodes maps given as alternating key/value slices to keep integer keys ordered
+func encodeTestCBOR(value interface{}) []byte {
+	header := func(majorType byte, length int) []byte {
+		switch {
+		case length < 24:
+			return []byte{majorType<<5 | byte(length)}
+		case length < 256:
+			return []byte{majorType<<5 | 24, byte(length)}
+		default:
+			return binary.BigEndian.AppendUint16([]byte{majorType<<5 | 25}, uint16(length))
+		}
+	}
+
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return header(CBOR_NEGATIVE, int(-1-v))
+		}
+		return header(CBOR_UNSIGNED, int(v))
+	case []byte:
+		return append(header(CBOR_BYTES, len(v)), v...)
+	case string:
+		return append(header(CBOR_TEXT, len(v)), v...)
+	case []interface{}:
+		data := header(CBOR_MAP, len(v)/2)
+		for _, item := range v {
+			data = append(data, encodeTestCBOR(item)...)
+		}
+		return data
+	case map[string]interface{}:
+		data := header(CBOR_MAP, len(v))
+		for _, key := range []string{"fmt", "attStmt", "authData"} {
+			if item, ok := v[key]; ok {
+				data = append(data, encodeTestCBOR(key)...)
+				data = append(data, encodeTestCBOR(item)...)
+			}
+		}
+		return data
+	}
+
+	return nil
+}
+
+func register(t *testing.T, a *authenticator) Credential {
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credential, err := testRelyingParty.VerifyRegistration(challenge, a.create(t, challenge, TEST_ORIGIN), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credential
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	a := newAuthenticator(t)
+	credential := register(t, a)
+
+	if string(credential.ID) != string(a.credentialId) {
+		t.Fatal("unexpected credential id")
+	}
+
+	for range 2 {
+		challenge, _ := NewChallenge()
+		signCount, err := testRelyingParty.VerifyAssertion(challenge, credential, a.get(t, challenge, TEST_ORIGIN), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if signCount != a.signCount {
+			t.Fatalf("expected sign count %d, got %d", a.signCount, signCount)
+		}
+		credential.SignCount = signCount
+	}
+}
+
+func TestRegistrationRejectsInvalidClientData(t *testing.T) {
+	a := newAuthenticator(t)
+	challenge, _ := NewChallenge()
+	otherChallenge, _ := NewChallenge()
+
+	if _, err := testRelyingParty.VerifyRegistration(otherChallenge, a.create(t, challenge, TEST_ORIGIN), true); err == nil {
+		t.Fatal("expected challenge mismatch to be rejected")
+	}
+
+	if _, err := testRelyingParty.VerifyRegistration(challenge, a.create(t, challenge, "https://evil.example"), true); err == nil {
+		t.Fatal("expected foreign origin to be rejected")
+	}
+}
+
+func TestAssertionRejectsTampering(t *testing.T) {
+	a := newAuthenticator(t)
+	credential := register(t, a)
+
+	challenge, _ := NewChallenge()
+	response := a.get(t, challenge, TEST_ORIGIN)
+	response.Signature[len(response.Signature)-1] ^= 0xff
+	if _, err := testRelyingParty.VerifyAssertion(challenge, credential, response, true); err == nil {
+		t.Fatal("expected invalid signature to be rejected")
+	}
+
+	challenge, _ = NewChallenge()
+	response = a.get(t, challenge, TEST_ORIGIN)
+	credential.SignCount = a.signCount
+	if _, err := testRelyingParty.VerifyAssertion(challenge, credential, response, true); err == nil {
+		t.Fatal("expected replayed sign count to be rejected")
+	}
+}
+
+func TestAssertionRequiresUserVerification(t *testing.T) {
+	a := newAuthenticator(t)
+	credential := register(t, a)
+	a.flags = FLAG_USER_PRESENT
+
+	challenge, _ := NewChallenge()
+	if _, err := testRelyingParty.VerifyAssertion(challenge, credential, a.get(t, challenge, TEST_ORIGIN), true); err == nil {
+		t.Fatal("expected missing user verification to be rejected")
+	}
+
+	challenge, _ = NewChallenge()
+	if _, err := testRelyingParty.VerifyAssertion(challenge, credential, a.get(t, challenge, TEST_ORIGIN), false); err != nil {
+		t.Fatal(err)
+	}
+}